@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dennisklein/kdev/internal/tool"
+	"github.com/dennisklein/kdev/internal/util"
+)
+
+func newToolsBundleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Export and import offline tool bundles",
+		Long:  `Pack cached tool versions into a single tar file and unpack it on machines without network access.`,
+	}
+
+	cmd.AddCommand(newToolsBundleExportCmd())
+	cmd.AddCommand(newToolsBundleImportCmd())
+
+	return cmd
+}
+
+func newToolsBundleExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export <file.tar> [tool[@version]...]",
+		Short: "Pack tool versions into a bundle",
+		Long:  `Pack tool versions with their sha256 checksums into a tar file. Without a version, all cached versions of a tool are packed; if no tools are specified, all cached versions of all tools. Binaries for platforms other than the host are downloaded.`,
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  runToolsBundleExport,
+	}
+
+	cmd.Flags().StringSlice("platform", []string{tool.Platform(runtime.GOOS, runtime.GOARCH)}, "Platforms (os/arch) to pack binaries for")
+
+	return cmd
+}
+
+func newToolsBundleImportCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "import <file.tar>",
+		Short: "Unpack a bundle into the tool cache",
+		Long: `Verify the checksums of the binaries for the host platform in a bundle and add them to the tool cache. ` +
+			`Binaries are also compared with the checksums in ` + tool.LockFileName + ` if it has them for the host. ` +
+			`Fails if a binary listed in the bundle manifest is missing from the bundle.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runToolsBundleImport,
+	}
+}
+
+func runToolsBundleExport(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+	registry := getRegistry(cmd, out)
+
+	path := args[0]
+
+	platforms, err := cmd.Flags().GetStringSlice("platform")
+	if err != nil {
+		return fmt.Errorf("failed to get --platform flag: %w", err)
+	}
+
+	items, err := bundleItems(registry, args[1:])
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that a failed export leaves no truncated bundle behind.
+	tmpPath := path + ".tmp"
+
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+
+	manifest, err := tool.ExportBundle(ctx, file, items, platforms)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write bundle: %w", closeErr)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmpPath) //nolint:errcheck // cleanup on error path
+
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath) //nolint:errcheck // cleanup on error path
+
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	var size int64
+
+	for _, artifact := range manifest.Artifacts {
+		size += artifact.Size
+
+		if err := printBundleArtifact(out, artifact, "packed"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintf(out, "Wrote %s (%d binaries, %s)\n", path, len(manifest.Artifacts), util.FormatBytes(size)); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+// bundleItems selects the tools and versions to export from tool[@version]
+// arguments. Without arguments, all cached versions of all tools are selected.
+func bundleItems(registry *tool.Registry, args []string) ([]tool.BundleItem, error) {
+	if len(args) == 0 {
+		tools := registry.AllTools()
+		items := make([]tool.BundleItem, 0, len(tools))
+
+		for _, t := range tools {
+			items = append(items, tool.BundleItem{Tool: t})
+		}
+
+		return items, nil
+	}
+
+	items := make([]tool.BundleItem, 0, len(args))
+
+	for _, arg := range args {
+		name, spec, _ := strings.Cut(arg, "@")
+
+		t := registry.Get(name)
+		if t == nil {
+			return nil, fmt.Errorf("unknown tool: %s", name)
+		}
+
+		items = append(items, tool.BundleItem{Tool: t, Spec: spec})
+	}
+
+	return items, nil
+}
+
+func runToolsBundleImport(cmd *cobra.Command, args []string) (err error) {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+	registry := getRegistry(cmd, out)
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	result, err := tool.ImportBundle(ctx, file, registry)
+	if err != nil {
+		return err
+	}
+
+	for _, artifact := range result.Imported {
+		if err := printBundleArtifact(out, artifact, "imported"); err != nil {
+			return err
+		}
+	}
+
+	for _, artifact := range result.Cached {
+		if err := printBundleArtifact(out, artifact, "already cached"); err != nil {
+			return err
+		}
+	}
+
+	if len(result.Skipped) > 0 {
+		if _, err := fmt.Fprintf(out, "Skipped %d binaries for other platforms\n", len(result.Skipped)); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// printBundleArtifact prints one artifact of a bundle with a status message.
+func printBundleArtifact(out io.Writer, artifact tool.BundleArtifact, message string) error {
+	toolName := toolNameStyle.Render(artifact.Tool)
+	version := latestStyle.Render(artifact.Version)
+	platform := specStyle.Render(artifact.Platform())
+
+	if _, err := fmt.Fprintf(out, "%s %s %s %s\n", toolName, version, platform, infoStyle.Render(message)); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunToolsBundle(t *testing.T) {
+	t.Run("exports and imports cached versions", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+		binPath := createCachedTool(t, tmpHome, "kind", "v0.20.0", 1024)
+		createCachedTool(t, tmpHome, "kubectl", "v1.30.0", 2048)
+
+		bundlePath := filepath.Join(t.TempDir(), "tools.tar")
+
+		cmd := newToolsBundleCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"export", bundlePath, "kind"})
+		cmd.SetContext(context.Background())
+
+		require.NoError(t, cmd.Execute())
+		assert.Contains(t, buf.String(), "packed")
+		assert.Contains(t, buf.String(), "Wrote "+bundlePath+" (1 binaries, 1.0 KiB)")
+		requireFileExists(t, bundlePath)
+		requireFileNotExists(t, bundlePath+".tmp")
+
+		// Import on a fresh machine.
+		newHome := setupTestCacheDir(t)
+
+		cmd = newToolsBundleCmd()
+		buf.Reset()
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"import", bundlePath})
+		cmd.SetContext(context.Background())
+
+		require.NoError(t, cmd.Execute())
+		assert.Contains(t, buf.String(), "imported")
+
+		imported := filepath.Join(newHome, ".kdev", "kdev", "kind", "v0.20.0", "kind")
+		want, err := os.ReadFile(binPath)
+		require.NoError(t, err)
+
+		got, err := os.ReadFile(imported)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+
+		// A second import finds the version cached.
+		buf.Reset()
+		cmd.SetArgs([]string{"import", bundlePath})
+		require.NoError(t, cmd.Execute())
+		assert.Contains(t, buf.String(), "already cached")
+	})
+
+	t.Run("fails for unknown tool", func(t *testing.T) {
+		setupTestCacheDir(t)
+
+		cmd := newToolsBundleCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs([]string{"export", filepath.Join(t.TempDir(), "tools.tar"), "nonexistent"})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown tool: nonexistent")
+	})
+
+	t.Run("fails for missing bundle", func(t *testing.T) {
+		setupTestCacheDir(t)
+
+		cmd := newToolsBundleCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs([]string{"import", filepath.Join(t.TempDir(), "missing.tar")})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to open bundle")
+	})
+}
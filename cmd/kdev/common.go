@@ -1,24 +1,77 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/dennisklein/kdev/internal/tool"
 )
 
+const (
+	// offlineFlag is the name of the global flag that enables offline mode.
+	offlineFlag = "offline"
+
+	// toolCmdAnnotation marks commands executing a tool, holding the tool name.
+	toolCmdAnnotation = "kdev/tool"
+)
+
+// registryKey is the context key of the tool registry shared by all commands.
+type registryKey struct{}
+
+// withRegistry returns a context carrying the tool registry used by commands.
+func withRegistry(ctx context.Context, registry *tool.Registry) context.Context {
+	return context.WithValue(ctx, registryKey{}, registry)
+}
+
+// loadRegistry creates the tool registry. An invalid tools.yaml is reported to
+// errOut as a warning, so that the built-in tools keep working.
+func loadRegistry(errOut io.Writer) *tool.Registry {
+	registry, err := tool.NewRegistry(nil)
+	if err != nil {
+		_, _ = fmt.Fprintf(errOut, "Warning: ignoring user-defined tools: %v\n", err) //nolint:errcheck // best-effort warning
+	}
+
+	return registry
+}
+
+// getRegistry returns the tool registry of the context of cmd, or loads one if
+// there is none, configured with progress and the global flags of cmd.
+func getRegistry(cmd *cobra.Command, progress io.Writer) *tool.Registry {
+	registry, ok := cmd.Context().Value(registryKey{}).(*tool.Registry)
+	if !ok {
+		registry = loadRegistry(cmd.ErrOrStderr())
+	}
+
+	registry.SetProgressWriter(progress)
+
+	// Look up via cmd.Flag so persistent flags of parent commands are found
+	// even when flag parsing is disabled for cmd itself.
+	if flag := cmd.Flag(offlineFlag); flag != nil && flag.Value.String() == "true" {
+		registry.SetOffline(true)
+	}
+
+	return registry
+}
+
 // newToolCmd creates a generic command for tools that can be auto-downloaded and executed.
 func newToolCmd(toolName, shortDesc string) *cobra.Command {
 	return &cobra.Command{
 		Use:                toolName,
 		Short:              shortDesc,
-		Long:               fmt.Sprintf("Lazily downloads and executes %s, passing through all arguments.", toolName),
+		Long:               fmt.Sprintf("Lazily downloads and executes %s, passing through all arguments. Run a specific version with %[1]s@<version> or %s=<version>.", toolName, tool.VersionEnvVar(toolName)),
 		DisableFlagParsing: true,
+		Annotations:        map[string]string{toolCmdAnnotation: toolName},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
-			registry := tool.NewRegistry(os.Stdout)
+			registry := getRegistry(cmd, os.Stdout)
+
 			t := registry.Get(toolName)
 			if t == nil {
 				return fmt.Errorf("unknown tool: %s", toolName)
@@ -28,3 +81,59 @@ func newToolCmd(toolName, shortDesc string) *cobra.Command {
 		},
 	}
 }
+
+// addToolCmds adds a command to root for each tool of registry that has none
+// yet, i.e. the user-defined tools. Tools shadowing other commands are skipped
+// and reported in the returned error.
+func addToolCmds(root *cobra.Command, registry *tool.Registry) error {
+	root.InitDefaultHelpCmd()
+	root.InitDefaultCompletionCmd()
+
+	existing := make(map[string]*cobra.Command)
+	for _, cmd := range root.Commands() {
+		existing[cmd.Name()] = cmd
+	}
+
+	var errs []error
+
+	for _, name := range registry.All() {
+		cmd, ok := existing[name]
+
+		switch {
+		case !ok:
+			root.AddCommand(newToolCmd(name, fmt.Sprintf("Execute %s (auto-downloads if needed)", name)))
+		case cmd.Annotations[toolCmdAnnotation] != name:
+			errs = append(errs, fmt.Errorf("tool %s conflicts with the kdev %s command", name, cmd.Name()))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// applyToolVersionArg handles the "tool@version" shorthand, e.g. "kdev kubectl@v1.29.3 get nodes".
+// The first non-flag argument naming a tool with a version suffix is replaced by
+// the tool name, and the version is requested from the tool of registry. Unlike
+// the tool's version environment variable, it does not leak into the tool's
+// child processes.
+func applyToolVersionArg(registry *tool.Registry, args []string) []string {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+
+		name, spec, ok := strings.Cut(arg, "@")
+
+		t := registry.Get(name)
+		if !ok || t == nil {
+			return args
+		}
+
+		rewritten := slices.Clone(args)
+		rewritten[i] = name
+		t.RequestedVersion = spec
+
+		return rewritten
+	}
+
+	return args
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dennisklein/kdev/internal/tool"
+)
+
+func TestGetRegistry(t *testing.T) {
+	t.Run("uses the registry of the context", func(t *testing.T) {
+		registry, err := tool.NewRegistry(nil)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+
+		cmd := &cobra.Command{Use: "standalone"}
+		cmd.SetContext(withRegistry(context.Background(), registry))
+
+		assert.Same(t, registry, getRegistry(cmd, &buf))
+		assert.Equal(t, &buf, registry.Get("kubectl").ProgressWriter)
+	})
+
+	t.Run("warns about invalid user-defined tools", func(t *testing.T) {
+		writeToolsConfig(t, "tools: [")
+
+		var errOut bytes.Buffer
+
+		cmd := &cobra.Command{Use: "standalone"}
+		cmd.SetContext(context.Background())
+		cmd.SetErr(&errOut)
+
+		registry := getRegistry(cmd, nil)
+		assert.Equal(t, []string{"cilium", "kind", "kubectl"}, registry.All())
+		assert.Contains(t, errOut.String(), "Warning: ignoring user-defined tools")
+		assert.Contains(t, errOut.String(), "failed to parse")
+	})
+
+	t.Run("enables offline mode from parent persistent flag", func(t *testing.T) {
+		root := &cobra.Command{Use: "kdev", TraverseChildren: true}
+		root.PersistentFlags().Bool(offlineFlag, false, "")
+
+		child := &cobra.Command{Use: "kubectl", DisableFlagParsing: true}
+		root.AddCommand(child)
+
+		require.NoError(t, root.PersistentFlags().Set(offlineFlag, "true"))
+
+		child.SetContext(context.Background())
+		registry := getRegistry(child, nil)
+
+		for _, tl := range registry.AllTools() {
+			assert.True(t, tl.Offline, "%s should be offline", tl.Name)
+		}
+	})
+
+	t.Run("leaves offline mode disabled without flag", func(t *testing.T) {
+		cmd := &cobra.Command{Use: "standalone"}
+		cmd.SetContext(context.Background())
+
+		registry := getRegistry(cmd, nil)
+
+		for _, tl := range registry.AllTools() {
+			assert.False(t, tl.Offline, "%s should be online", tl.Name)
+		}
+	})
+}
+
+// writeToolsConfig declares user-defined tools in a file named by KDEV_TOOLS_CONFIG.
+func writeToolsConfig(t *testing.T, content string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "tools.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	t.Setenv(tool.ToolsConfigEnvVar, path)
+}
+
+// testToolDefinition declares a user-defined tool called name.
+func testToolDefinition(name string) string {
+	return `tools:
+  - name: ` + name + `
+    versionURL: https://example.com/stable.txt
+    download: https://example.com/{{.Version}}/tool-{{.OS}}-{{.Arch}}
+    checksum: https://example.com/{{.Version}}/tool-{{.OS}}-{{.Arch}}.sha256
+`
+}
+
+func TestAddToolCmds(t *testing.T) {
+	newRoot := func() *cobra.Command {
+		root := &cobra.Command{Use: "kdev"}
+		root.AddCommand(newKindCmd(), newKubectlCmd(), newCiliumCmd(), newToolsCmd(), newVersionCmd())
+
+		return root
+	}
+
+	t.Run("adds commands for user-defined tools", func(t *testing.T) {
+		writeToolsConfig(t, testToolDefinition("helm"))
+
+		registry, err := tool.NewRegistry(nil)
+		require.NoError(t, err)
+
+		root := newRoot()
+		require.NoError(t, addToolCmds(root, registry))
+
+		cmd, _, err := root.Find([]string{"helm", "version"})
+		require.NoError(t, err)
+		assert.Equal(t, "helm", cmd.Name())
+		assert.True(t, cmd.DisableFlagParsing)
+		assert.Contains(t, cmd.Long, tool.VersionEnvVar("helm"))
+
+		kubectl := 0
+
+		for _, cmd := range root.Commands() {
+			if cmd.Name() == "kubectl" {
+				kubectl++
+			}
+		}
+
+		assert.Equal(t, 1, kubectl, "built-in tools keep their command")
+	})
+
+	t.Run("rejects tools shadowing other commands", func(t *testing.T) {
+		writeToolsConfig(t, testToolDefinition("version"))
+
+		registry, err := tool.NewRegistry(nil)
+		require.NoError(t, err)
+
+		root := newRoot()
+
+		err = addToolCmds(root, registry)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "tool version conflicts with the kdev version command")
+
+		cmd, _, err := root.Find([]string{"version"})
+		require.NoError(t, err)
+		assert.Empty(t, cmd.Annotations[toolCmdAnnotation], "the kdev command is kept")
+	})
+
+	t.Run("rejects tools shadowing the help command", func(t *testing.T) {
+		writeToolsConfig(t, testToolDefinition("help"))
+
+		registry, err := tool.NewRegistry(nil)
+		require.NoError(t, err)
+
+		err = addToolCmds(newRoot(), registry)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "conflicts with the kdev help command")
+	})
+}
+
+func TestApplyToolVersionArgUserDefinedTool(t *testing.T) {
+	writeToolsConfig(t, testToolDefinition("helm"))
+
+	registry, err := tool.NewRegistry(nil)
+	require.NoError(t, err)
+
+	args := applyToolVersionArg(registry, []string{"helm@v3.15.0", "list"})
+	assert.Equal(t, []string{"helm", "list"}, args)
+	assert.Equal(t, "v3.15.0", registry.Get("helm").RequestedVersion)
+}
+
+func TestRootCmdOfflineFlag(t *testing.T) {
+	t.Run("registers offline as persistent flag", func(t *testing.T) {
+		flag := rootCmd.PersistentFlags().Lookup(offlineFlag)
+		require.NotNil(t, flag)
+		assert.Equal(t, "false", flag.DefValue)
+	})
+
+	t.Run("traverses children to parse global flags before tool commands", func(t *testing.T) {
+		assert.True(t, rootCmd.TraverseChildren)
+	})
+}
+
+func TestApplyToolVersionArg(t *testing.T) {
+	t.Run("splits version from tool command", func(t *testing.T) {
+		t.Setenv(tool.VersionEnvVar("kubectl"), "")
+
+		registry, err := tool.NewRegistry(nil)
+		require.NoError(t, err)
+
+		args := applyToolVersionArg(registry, []string{"--offline", "kubectl@v1.29.3", "get", "nodes"})
+		assert.Equal(t, []string{"--offline", "kubectl", "get", "nodes"}, args)
+		assert.Equal(t, "v1.29.3", registry.Get("kubectl").RequestedVersion)
+		assert.Empty(t, os.Getenv(tool.VersionEnvVar("kubectl")), "the version must not leak into child processes")
+	})
+
+	t.Run("leaves tool arguments untouched", func(t *testing.T) {
+		registry, err := tool.NewRegistry(nil)
+		require.NoError(t, err)
+
+		input := []string{"kubectl", "get", "pods@v1"}
+
+		args := applyToolVersionArg(registry, input)
+		assert.Equal(t, input, args)
+		assert.Empty(t, registry.Get("kubectl").RequestedVersion)
+	})
+
+	t.Run("ignores unknown commands", func(t *testing.T) {
+		registry, err := tool.NewRegistry(nil)
+		require.NoError(t, err)
+
+		input := []string{"tools", "install", "kind@v0.22.0"}
+
+		args := applyToolVersionArg(registry, input)
+		assert.Equal(t, input, args)
+		assert.Empty(t, registry.Get("kind").RequestedVersion)
+	})
+}
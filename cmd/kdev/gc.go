@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/dennisklein/kdev/internal/tool"
+	"github.com/dennisklein/kdev/internal/util"
+)
+
+func newToolsGCCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gc [tool...]",
+		Short: "Remove unused cached tool versions",
+		Long: `Remove cached tool versions selected by a garbage collection policy: versions beyond the newest --keep of each tool, versions not executed for --unused-for, ` +
+			`and the least recently used versions while the cache is larger than --max-size. ` +
+			`Flags override the gc settings in the user's tools.yaml (see ` + tool.ToolsConfigEnvVar + `), where "auto: true" also collects garbage after each download. ` +
+			`Versions in use by other kdev processes are skipped. If no tool names are specified, collects garbage of all tools.`,
+		RunE: runToolsGC,
+	}
+
+	cmd.Flags().String("max-size", "", `Maximum total size of the cached versions, e.g. "500MiB"`)
+	cmd.Flags().Int("keep", 0, "Number of versions to keep per tool, newest first")
+	cmd.Flags().String("unused-for", "", `Remove versions not used for this long, e.g. "30d"`)
+	cmd.Flags().Bool("dry-run", false, "Only list what would be removed and reclaimed")
+
+	return cmd
+}
+
+// errNoGCPolicy is returned when gc runs without any rule selecting versions.
+var errNoGCPolicy = errors.New("no garbage collection policy: set --max-size, --keep or --unused-for, or gc in tools.yaml")
+
+func runToolsGC(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+	registry := getRegistry(cmd, out)
+
+	tools := resolveTools(registry, args)
+
+	policy, err := gcPolicy(cmd)
+	if err != nil {
+		return err
+	}
+
+	if policy.IsZero() {
+		return errNoGCPolicy
+	}
+
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return fmt.Errorf("failed to get --dry-run flag: %w", err)
+	}
+
+	candidates, err := tool.PlanGC(tools, policy, time.Now())
+	if err != nil {
+		return err
+	}
+
+	removed := candidates
+
+	if !dryRun {
+		removed, err = tool.CollectGarbage(candidates)
+		if err != nil {
+			return err
+		}
+	}
+
+	return printGCResult(out, candidates, removed, dryRun)
+}
+
+// gcPolicy returns the policy from the gc settings of the user configuration,
+// overridden by the flags that are set.
+func gcPolicy(cmd *cobra.Command) (tool.GCPolicy, error) {
+	path, err := tool.ToolsConfigPath()
+	if err != nil {
+		return tool.GCPolicy{}, err
+	}
+
+	user, err := tool.LoadToolsConfig(afero.NewOsFs(), path)
+	if err != nil {
+		return tool.GCPolicy{}, err
+	}
+
+	settings := user.GC
+	flags := cmd.Flags()
+
+	if flags.Changed("max-size") {
+		if settings.MaxSize, err = flags.GetString("max-size"); err != nil {
+			return tool.GCPolicy{}, fmt.Errorf("failed to get --max-size flag: %w", err)
+		}
+	}
+
+	if flags.Changed("keep") {
+		if settings.Keep, err = flags.GetInt("keep"); err != nil {
+			return tool.GCPolicy{}, fmt.Errorf("failed to get --keep flag: %w", err)
+		}
+	}
+
+	if flags.Changed("unused-for") {
+		if settings.UnusedFor, err = flags.GetString("unused-for"); err != nil {
+			return tool.GCPolicy{}, fmt.Errorf("failed to get --unused-for flag: %w", err)
+		}
+	}
+
+	return settings.Policy()
+}
+
+func printGCResult(out io.Writer, candidates, removed []tool.GCCandidate, dryRun bool) error {
+	if len(candidates) == 0 {
+		if _, err := fmt.Fprintln(out, "Nothing to remove"); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+
+		return nil
+	}
+
+	isRemoved := make(map[string]bool, len(removed))
+	removedVersions := make([]tool.CachedVersion, 0, len(removed))
+
+	for _, c := range removed {
+		isRemoved[c.Version.Path] = true
+		removedVersions = append(removedVersions, c.Version)
+	}
+
+	for _, c := range candidates {
+		status := infoStyle.Render(c.Reason)
+		if !isRemoved[c.Version.Path] {
+			status = notCachedStyle.Render("in use, skipped")
+		}
+
+		if _, err := fmt.Fprintf(out, "%s %s %s  %s\n", toolNameStyle.Render(c.Tool.Name), versionStyle.Render(c.Version.Version),
+			sizeStyle.Render(util.FormatBytes(c.Version.Size)), status); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+
+	message := "Reclaimed"
+	if dryRun {
+		message = "Would reclaim"
+	}
+
+	reclaimed, err := tool.ReclaimedSize(afero.NewOsFs(), removedVersions)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(out, "%s %s\n", message, successStyle.Bold(true).Render(util.FormatBytes(reclaimed))); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
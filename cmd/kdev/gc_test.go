@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dennisklein/kdev/internal/testutil"
+)
+
+// createUsedCachedTool creates a fake cached tool binary that was last used age ago.
+func createUsedCachedTool(t *testing.T, home, toolName, version string, size int64, age time.Duration) string {
+	t.Helper()
+
+	binPath := createCachedTool(t, home, toolName, version, size)
+	used := time.Now().Add(-age)
+	require.NoError(t, os.Chtimes(binPath, used, used))
+
+	return binPath
+}
+
+func runGCCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	cmd := newToolsGCCmd()
+
+	var buf bytes.Buffer
+	cmd.SetOut(&buf)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs(args)
+	cmd.SetContext(context.Background())
+
+	err := cmd.Execute()
+
+	return buf.String(), err
+}
+
+func TestRunToolsGC(t *testing.T) {
+	t.Run("previews removals with --dry-run", func(t *testing.T) {
+		writeToolsConfig(t, "")
+		tmpHome := setupTestCacheDir(t)
+		createUsedCachedTool(t, tmpHome, "kind", "v0.20.0", 1024, time.Hour)
+		oldPath := createUsedCachedTool(t, tmpHome, "kind", "v0.19.0", 2048, 48*time.Hour)
+
+		output, err := runGCCommand(t, "--keep", "1", "--dry-run")
+		require.NoError(t, err)
+
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		require.Len(t, lines, 2)
+		assert.Contains(t, lines[0], "v0.19.0")
+		assert.Contains(t, lines[0], "2.0 KiB")
+		assert.Contains(t, lines[0], "more than 1 versions cached")
+		assert.Equal(t, "Would reclaim 2.0 KiB", lines[1])
+		requireFileExists(t, oldPath)
+	})
+
+	t.Run("does not count blobs of kept versions", func(t *testing.T) {
+		writeToolsConfig(t, "")
+		tmpHome := setupTestCacheDir(t)
+		sharedPath := createUsedCachedTool(t, tmpHome, "kind", "v0.19.0", 2048, 48*time.Hour)
+		blobPath := shareCachedToolBlob(t, tmpHome, sharedPath, "kind", "v0.20.0")
+		createUsedCachedTool(t, tmpHome, "kind", "v0.18.0", 1024, 72*time.Hour)
+
+		output, err := runGCCommand(t, "--keep", "1", "--dry-run")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Would reclaim 1.0 KiB")
+
+		output, err = runGCCommand(t, "--keep", "1")
+		require.NoError(t, err)
+		assert.Contains(t, output, "Reclaimed 1.0 KiB")
+		requireFileExists(t, blobPath)
+	})
+
+	t.Run("removes versions selected by the flags", func(t *testing.T) {
+		writeToolsConfig(t, "")
+		tmpHome := setupTestCacheDir(t)
+		newPath := createUsedCachedTool(t, tmpHome, "kind", "v0.20.0", 1024, time.Hour)
+		oldPath := createUsedCachedTool(t, tmpHome, "kind", "v0.19.0", 2048, 40*24*time.Hour)
+		kubectlPath := createUsedCachedTool(t, tmpHome, "kubectl", "v1.30.0", 1024, 31*24*time.Hour)
+
+		output, err := runGCCommand(t, "--unused-for", "30d", "kind")
+		require.NoError(t, err)
+		assert.Contains(t, output, "unused since")
+		assert.Contains(t, output, "Reclaimed 2.0 KiB")
+
+		requireFileExists(t, newPath)
+		requireFileNotExists(t, oldPath)
+		requireFileExists(t, kubectlPath)
+	})
+
+	t.Run("uses the user configuration", func(t *testing.T) {
+		writeToolsConfig(t, "gc:\n  maxSize: 2KiB\n")
+		tmpHome := setupTestCacheDir(t)
+		newPath := createUsedCachedTool(t, tmpHome, "kind", "v0.20.0", 1024, time.Hour)
+		oldPath := createUsedCachedTool(t, tmpHome, "kubectl", "v1.30.0", 2048, 2*time.Hour)
+
+		output, err := runGCCommand(t)
+		require.NoError(t, err)
+		assert.Contains(t, output, "cache larger than 2.0 KiB")
+
+		requireFileExists(t, newPath)
+		requireFileNotExists(t, oldPath)
+	})
+
+	t.Run("flags override the user configuration", func(t *testing.T) {
+		writeToolsConfig(t, "gc:\n  maxSize: 1KiB\n")
+		tmpHome := setupTestCacheDir(t)
+		binPath := createUsedCachedTool(t, tmpHome, "kind", "v0.20.0", 2048, time.Hour)
+
+		output, err := runGCCommand(t, "--max-size", "1GiB")
+		require.NoError(t, err)
+		assert.Equal(t, "Nothing to remove\n", output)
+		requireFileExists(t, binPath)
+	})
+
+	t.Run("requires a policy", func(t *testing.T) {
+		writeToolsConfig(t, "")
+		setupTestCacheDir(t)
+
+		_, err := runGCCommand(t)
+		require.ErrorIs(t, err, errNoGCPolicy)
+	})
+
+	t.Run("rejects invalid sizes", func(t *testing.T) {
+		writeToolsConfig(t, "")
+		setupTestCacheDir(t)
+
+		_, err := runGCCommand(t, "--max-size", "huge")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid size "huge"`)
+	})
+
+	t.Run("handles write errors", func(t *testing.T) {
+		writeToolsConfig(t, "")
+		tmpHome := setupTestCacheDir(t)
+		createUsedCachedTool(t, tmpHome, "kind", "v0.20.0", 1024, time.Hour)
+
+		cmd := newToolsGCCmd()
+		cmd.SetOut(testutil.NewErrorWriter(fmt.Errorf("write error")))
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs([]string{"--keep", "1"})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to write output")
+	})
+}
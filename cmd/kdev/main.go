@@ -1,25 +1,43 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/dennisklein/kdev/internal/tool"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "kdev",
 	Short: "Manage opinionated local kind-based Kubernetes dev clusters",
 	Long:  `kdev is a tool for managing opinionated, local, kind-based Kubernetes development clusters.`,
+	// Parse global flags placed before a subcommand, so "kdev --offline kubectl"
+	// works even though tool commands pass all of their own arguments through.
+	TraverseChildren: true,
 }
 
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
+	// The registry is loaded once and shared with all commands through the context.
+	registry := loadRegistry(os.Stderr)
+
+	if err := addToolCmds(rootCmd, registry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	rootCmd.SetArgs(applyToolVersionArg(registry, os.Args[1:]))
+
+	if err := rootCmd.ExecuteContext(withRegistry(context.Background(), registry)); err != nil {
 		os.Exit(1)
 	}
 }
 
 func init() {
+	rootCmd.PersistentFlags().Bool(offlineFlag, false,
+		"Do not look up upstream versions, use the newest cached version (also via "+tool.OfflineEnvVar+")")
+
 	rootCmd.AddCommand(newVersionCmd())
 	rootCmd.AddCommand(newCiliumCmd())
 	rootCmd.AddCommand(newKindCmd())
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+
+	"github.com/dennisklein/kdev/internal/util"
+)
+
+const (
+	// maxParallelUpdates bounds the number of tools updated concurrently.
+	maxParallelUpdates = 4
+
+	// updateRefreshInterval is how often the live progress display is redrawn.
+	updateRefreshInterval = 100 * time.Millisecond
+)
+
+var (
+	pendingStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	failedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+)
+
+// updateStatus is the state of a single tool update.
+type updateStatus int
+
+const (
+	updatePending updateStatus = iota
+	updateResolving
+	updateDownloading
+	updateCached
+	updateDownloaded
+	updateFailed
+)
+
+// updateLine tracks the update of a single tool. It doubles as the tool's
+// ProgressWriter so that messages are routed through the display.
+type updateLine struct {
+	display *updateDisplay
+	name    string
+	version string
+	status  updateStatus
+	current int64
+	total   int64
+	err     error
+}
+
+// Write prints a progress message of the tool through the display.
+func (l *updateLine) Write(p []byte) (int, error) {
+	if err := l.display.print(string(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// setVersion records the version the tool is updated to.
+func (l *updateLine) setVersion(version string) {
+	l.display.mu.Lock()
+	defer l.display.mu.Unlock()
+
+	l.version = version
+}
+
+// setStatus moves the tool to a new state. Without a live display, tools that
+// are already cached are reported with a line of their own; downloads and
+// failures are reported by the tool and the returned error respectively.
+func (l *updateLine) setStatus(status updateStatus, err error) error {
+	l.display.mu.Lock()
+	l.status, l.err = status, err
+	line := l.render()
+	l.display.mu.Unlock()
+
+	if l.display.program != nil || status != updateCached {
+		return nil
+	}
+
+	return l.display.print(line + "\n")
+}
+
+// setProgress records the download progress of the tool.
+func (l *updateLine) setProgress(current, total int64) {
+	l.display.mu.Lock()
+	defer l.display.mu.Unlock()
+
+	l.current, l.total = current, total
+}
+
+// render formats the line for display. The caller must hold the display lock.
+func (l *updateLine) render() string {
+	toolName := toolNameStyle.Render(l.name)
+	version := latestStyle.Render(l.version)
+
+	switch l.status {
+	case updatePending:
+		return toolName + " " + pendingStyle.Render("waiting")
+	case updateResolving:
+		return toolName + " " + pendingStyle.Render("checking version...")
+	case updateDownloading:
+		if l.total <= 0 {
+			return fmt.Sprintf("%s %s %s", toolName, version, infoStyle.Render(util.FormatBytes(l.current)))
+		}
+
+		percent := float64(l.current) / float64(l.total)
+		info := successStyle.Render(fmt.Sprintf(" %3.0f%% (%s / %s)", percent*100, util.FormatBytes(l.current), util.FormatBytes(l.total)))
+
+		return fmt.Sprintf("%s %s %s%s", toolName, version, l.display.bar.ViewAs(percent), info)
+	case updateCached:
+		return fmt.Sprintf("%s %s %s", toolName, version, infoStyle.Render("already cached"))
+	case updateDownloaded:
+		return fmt.Sprintf("%s %s %s", toolName, version, successStyle.Render("downloaded "+util.FormatBytes(l.current)))
+	case updateFailed:
+		return toolName + " " + failedStyle.Render(l.err.Error())
+	}
+
+	return toolName
+}
+
+// updateDisplay shows the progress of concurrent tool updates. On a terminal
+// it renders one live progress line per tool; otherwise it prints plain lines
+// as tools finish.
+type updateDisplay struct {
+	mu      sync.Mutex
+	out     io.Writer
+	lines   []*updateLine
+	bar     progress.Model
+	program *tea.Program
+	done    chan error
+}
+
+// newUpdateDisplay creates a display with one line per tool name.
+func newUpdateDisplay(out io.Writer, names []string) *updateDisplay {
+	d := &updateDisplay{
+		out: out,
+		bar: progress.New(
+			progress.WithDefaultGradient(),
+			progress.WithWidth(30),
+			progress.WithoutPercentage(),
+		),
+	}
+
+	for _, name := range names {
+		d.lines = append(d.lines, &updateLine{display: d, name: name})
+	}
+
+	if isTerminal(out) {
+		d.program = tea.NewProgram(updateModel{display: d},
+			tea.WithOutput(out),
+			tea.WithInput(nil),
+			tea.WithoutSignalHandler(),
+		)
+	}
+
+	return d
+}
+
+// start begins rendering the live display, if any.
+func (d *updateDisplay) start() {
+	if d.program == nil {
+		return
+	}
+
+	d.done = make(chan error, 1)
+
+	go func() {
+		_, err := d.program.Run()
+		d.done <- err
+	}()
+}
+
+// stop renders the final state and shuts down the live display, if any.
+func (d *updateDisplay) stop() error {
+	if d.program == nil {
+		return nil
+	}
+
+	d.program.Quit()
+
+	if err := <-d.done; err != nil {
+		return fmt.Errorf("failed to render progress: %w", err)
+	}
+
+	return nil
+}
+
+// print writes a message above the live display, or directly to the output.
+func (d *updateDisplay) print(message string) error {
+	if d.program != nil {
+		d.program.Send(tea.Println(strings.TrimSuffix(message, "\n"))())
+
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, err := io.WriteString(d.out, message)
+
+	return err
+}
+
+// view renders all lines of the display.
+func (d *updateDisplay) view() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	rendered := make([]string, 0, len(d.lines))
+	for _, line := range d.lines {
+		rendered = append(rendered, line.render())
+	}
+
+	return strings.Join(rendered, "\n") + "\n"
+}
+
+// summary returns the combined result of all tool updates.
+func (d *updateDisplay) summary() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var downloaded, cached, failed int
+
+	var totalBytes int64
+
+	for _, line := range d.lines {
+		switch line.status {
+		case updateDownloaded:
+			downloaded++
+			totalBytes += line.current
+		case updateCached:
+			cached++
+		case updateFailed:
+			failed++
+		case updatePending, updateResolving, updateDownloading:
+		}
+	}
+
+	summary := fmt.Sprintf("Checked %d tools: %d downloaded (%s), %d already cached", len(d.lines), downloaded, util.FormatBytes(totalBytes), cached)
+	if failed > 0 {
+		return summary + ", " + failedStyle.Render(fmt.Sprintf("%d failed", failed))
+	}
+
+	return summary
+}
+
+// tickMsg triggers a redraw of the live display.
+type tickMsg struct{}
+
+// updateModel is the bubbletea model of the live display. Tool state is kept
+// in the display and read on every tick.
+type updateModel struct {
+	display *updateDisplay
+}
+
+// Init implements tea.Model.
+func (m updateModel) Init() tea.Cmd {
+	return tick()
+}
+
+// Update implements tea.Model.
+func (m updateModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if _, ok := msg.(tickMsg); ok {
+		return m, tick()
+	}
+
+	return m, nil
+}
+
+// View implements tea.Model.
+func (m updateModel) View() string {
+	return m.display.view()
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(updateRefreshInterval, func(time.Time) tea.Msg {
+		return tickMsg{}
+	})
+}
+
+// isTerminal reports whether stream, an input or output, is an interactive terminal.
+func isTerminal(stream any) bool {
+	f, ok := stream.(*os.File)
+
+	return ok && term.IsTerminal(f.Fd())
+}
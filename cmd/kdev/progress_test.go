@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dennisklein/kdev/internal/testutil"
+)
+
+func TestUpdateDisplay(t *testing.T) {
+	t.Run("uses plain output when not a terminal", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		display := newUpdateDisplay(&buf, []string{"kind"})
+		assert.Nil(t, display.program)
+	})
+
+	t.Run("prints cached tools and tool messages", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		display := newUpdateDisplay(&buf, []string{"kind", "kubectl"})
+		kind, kubectl := display.lines[0], display.lines[1]
+
+		_, err := fmt.Fprintf(kubectl, "Downloading kubectl v1.31.0...\n")
+		require.NoError(t, err)
+
+		kind.setVersion("v0.22.0")
+		require.NoError(t, kind.setStatus(updateCached, nil))
+		require.NoError(t, kubectl.setStatus(updateDownloading, nil))
+
+		output := buf.String()
+		assert.Contains(t, output, "Downloading kubectl v1.31.0...\n")
+		assert.Contains(t, output, "v0.22.0")
+		assert.Contains(t, output, "already cached")
+	})
+
+	t.Run("renders download progress", func(t *testing.T) {
+		display := newUpdateDisplay(&bytes.Buffer{}, []string{"kind"})
+		line := display.lines[0]
+
+		line.setVersion("v0.22.0")
+		require.NoError(t, line.setStatus(updateDownloading, nil))
+		line.setProgress(512, 1024)
+
+		assert.Contains(t, display.view(), " 50% (512 B / 1.0 KiB)")
+
+		line.setProgress(2048, 0)
+		assert.Contains(t, display.view(), "2.0 KiB")
+	})
+
+	t.Run("summarizes results", func(t *testing.T) {
+		display := newUpdateDisplay(&bytes.Buffer{}, []string{"cilium", "kind", "kubectl"})
+
+		display.lines[0].setProgress(2048, 2048)
+		require.NoError(t, display.lines[0].setStatus(updateDownloaded, nil))
+		require.NoError(t, display.lines[1].setStatus(updateCached, nil))
+		require.NoError(t, display.lines[2].setStatus(updateFailed, fmt.Errorf("network down")))
+
+		assert.Contains(t, display.view(), "network down")
+
+		summary := display.summary()
+		assert.Contains(t, summary, "Checked 3 tools: 1 downloaded (2.0 KiB), 1 already cached")
+		assert.Contains(t, summary, "1 failed")
+	})
+
+	t.Run("handles write error", func(t *testing.T) {
+		errWriter := testutil.NewErrorWriter(fmt.Errorf("write error"))
+
+		display := newUpdateDisplay(errWriter, []string{"kind"})
+
+		err := display.lines[0].setStatus(updateCached, nil)
+		require.Error(t, err)
+
+		_, err = display.lines[0].Write([]byte("message\n"))
+		require.Error(t, err)
+	})
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
 	"github.com/dennisklein/kdev/internal/tool"
@@ -15,6 +22,7 @@ const (
 	toolNameWidth = 15
 	versionWidth  = 10
 	sizeWidth     = 10
+	specWidth     = 14
 )
 
 var (
@@ -27,18 +35,31 @@ var (
 	totalSizeStyle  = sizeStyle.Bold(true)
 	successStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
 	infoStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("14"))
+	specStyle       = lipgloss.NewStyle().Width(specWidth).Align(lipgloss.Left)
+	headerStyle     = lipgloss.NewStyle().Bold(true).Width(specWidth).Align(lipgloss.Left)
+	behindStyle     = specStyle.Foreground(lipgloss.Color("3"))
+	upstreamStyle   = specStyle.Foreground(lipgloss.Color("10"))
 )
 
 func newToolsCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "tools",
 		Short: "Manage cached tools",
-		Long:  `Manage cached CLI tools (clean, info, update).`,
+		Long: `Manage cached CLI tools (bundle, clean, gc, info, install, lock, outdated, update, verify). ` +
+			`Additional tools are declared in ~/.config/kdev/tools.yaml or the file named by ` + tool.ToolsConfigEnvVar + `, ` +
+			`with a version source (github: owner/repo or versionURL), download and checksum URL templates using ` +
+			`{{.Version}}, {{.OS}} and {{.Arch}}, and optionally the archiveMember holding the binary.`,
 	}
 
+	cmd.AddCommand(newToolsBundleCmd())
 	cmd.AddCommand(newToolsCleanCmd())
+	cmd.AddCommand(newToolsGCCmd())
 	cmd.AddCommand(newToolsInfoCmd())
+	cmd.AddCommand(newToolsInstallCmd())
+	cmd.AddCommand(newToolsLockCmd())
+	cmd.AddCommand(newToolsOutdatedCmd())
 	cmd.AddCommand(newToolsUpdateCmd())
+	cmd.AddCommand(newToolsVerifyCmd())
 
 	return cmd
 }
@@ -57,26 +78,67 @@ func newToolsCleanCmd() *cobra.Command {
 }
 
 func newToolsInfoCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "info [tool...]",
 		Short: "Show cached tool information",
-		Long:  `Show version, path, and size information for cached tools. If no tool names are specified, shows all tools.`,
+		Long:  `Show version, path, and size information for cached tools. If no tool names are specified, shows all tools. With --verbose, also show where each version was downloaded from, its sha256, when it was downloaded and by which kdev version, and when it was last used.`,
 		RunE:  runToolsInfo,
 	}
+
+	cmd.Flags().BoolP("verbose", "v", false, "Show the download metadata of each cached version")
+
+	return cmd
+}
+
+func newToolsInstallCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "install tool[@version]...",
+		Short: "Install specific tool versions",
+		Long:  `Download and verify specific tool versions, e.g. "kind@v0.22.0" or "kubectl@~1.30". Without a version, installs the version that would be executed.`,
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  runToolsInstall,
+	}
+}
+
+func newToolsLockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock [tool...]",
+		Short: "Record exact tool versions and checksums",
+		Long:  `Resolve tool versions and record them with download URLs and sha256 checksums per platform in ` + tool.LockFileName + `. If no tool names are specified, locks all tools.`,
+		RunE:  runToolsLock,
+	}
+
+	cmd.Flags().StringSlice("platform", tool.DefaultLockPlatforms, "Platforms (os/arch) to record checksums for")
+
+	return cmd
+}
+
+func newToolsOutdatedCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "outdated [tool...]",
+		Short: "Show tools with newer versions available",
+		Long:  `Compare cached, pinned and latest upstream versions of tools. Exits with a non-zero code if a cached version or a pin lags behind. In offline mode, the latest versions are not looked up and shown as unknown. If no tool names are specified, checks all tools.`,
+		RunE:  runToolsOutdated,
+		// A report of outdated tools is not a usage error.
+		SilenceUsage: true,
+	}
 }
 
 func newToolsUpdateCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "update [tool...]",
 		Short: "Update tools to latest version",
-		Long:  `Check for and download the latest version of tools. If no tool names are specified, updates all tools.`,
-		RunE:  runToolsUpdate,
+		Long:  `Check for and download the latest version of tools, honouring versions pinned in .kdev.yaml. Tools are updated in parallel. If no tool names are specified, updates all tools.`,
+		// Failed updates are reported in the summary, not as usage errors.
+		SilenceUsage: true,
+		RunE:         runToolsUpdate,
 	}
 }
 
 func runToolsClean(cmd *cobra.Command, args []string) error {
 	out := cmd.OutOrStdout()
-	registry := tool.NewRegistry(out)
+	registry := getRegistry(cmd, out)
+
 	tools := resolveTools(registry, args)
 
 	cleanOld, err := cmd.Flags().GetBool("old")
@@ -84,7 +146,7 @@ func runToolsClean(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get --old flag: %w", err)
 	}
 
-	var totalReclaimed int64
+	var removed []tool.CachedVersion
 
 	for _, t := range tools {
 		versions, err := t.CachedVersions()
@@ -96,23 +158,27 @@ func runToolsClean(cmd *cobra.Command, args []string) error {
 			// Clean only old versions (keep most recent)
 			versionsToClean := versions[1:] // Skip the newest
 			for _, v := range versionsToClean {
-				totalReclaimed += v.Size
 				if err := t.CleanVersion(v.Version); err != nil {
 					return fmt.Errorf("failed to clean %s version %s: %w", t.Name, v.Version, err)
 				}
+
+				removed = append(removed, v)
 			}
 		} else if !cleanOld {
 			// Clean all versions
-			for _, v := range versions {
-				totalReclaimed += v.Size
-			}
-
 			if err := t.CleanAll(); err != nil {
 				return fmt.Errorf("failed to clean %s: %w", t.Name, err)
 			}
+
+			removed = append(removed, versions...)
 		}
 	}
 
+	totalReclaimed, err := tool.ReclaimedSize(afero.NewOsFs(), removed)
+	if err != nil {
+		return err
+	}
+
 	if totalReclaimed > 0 {
 		reclaimedStr := successStyle.Bold(true).Render(util.FormatBytes(totalReclaimed))
 		message := "Reclaimed"
@@ -127,20 +193,29 @@ func runToolsClean(cmd *cobra.Command, args []string) error {
 
 func runToolsInfo(cmd *cobra.Command, args []string) error {
 	out := cmd.OutOrStdout()
-	registry := tool.NewRegistry(nil)
+	registry := getRegistry(cmd, nil)
+
 	tools := resolveTools(registry, args)
 
-	var totalSize int64
+	verbose, err := cmd.Flags().GetBool("verbose")
+	if err != nil {
+		return fmt.Errorf("failed to get --verbose flag: %w", err)
+	}
+
+	var cached []tool.CachedVersion
 
 	for _, t := range tools {
-		size, err := printToolInfo(out, t)
+		versions, err := printToolInfo(out, t, verbose)
 		if err != nil {
 			return err
 		}
 
-		totalSize += size
+		cached = append(cached, versions...)
 	}
 
+	// Identical binaries share storage, so count them once.
+	totalSize := tool.DiskUsage(cached)
+
 	// Print total if more than one tool
 	if len(tools) > 1 && totalSize > 0 {
 		totalName := toolNameStyle.Render("cache size")
@@ -155,10 +230,10 @@ func runToolsInfo(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func printToolInfo(out io.Writer, t *tool.Tool) (int64, error) {
+func printToolInfo(out io.Writer, t *tool.Tool, verbose bool) ([]tool.CachedVersion, error) {
 	versions, err := t.CachedVersions()
 	if err != nil {
-		return 0, fmt.Errorf("failed to get cached versions for %s: %w", t.Name, err)
+		return nil, fmt.Errorf("failed to get cached versions for %s: %w", t.Name, err)
 	}
 
 	if len(versions) == 0 {
@@ -166,19 +241,15 @@ func printToolInfo(out io.Writer, t *tool.Tool) (int64, error) {
 		notCached := notCachedStyle.Render("(not cached)")
 
 		if _, writeErr := fmt.Fprintf(out, "%s  %s\n", toolName, notCached); writeErr != nil {
-			return 0, fmt.Errorf("failed to write output: %w", writeErr)
+			return nil, fmt.Errorf("failed to write output: %w", writeErr)
 		}
 
-		return 0, nil
+		return nil, nil
 	}
 
 	// Print each cached version on one line: toolname version size path
 	// Highlight the newest cached version (first in list) in green
-	var totalSize int64
-
 	for i, v := range versions {
-		totalSize += v.Size
-
 		toolName := toolNameStyle.Render(t.Name)
 
 		style := oldVersionStyle
@@ -191,58 +262,317 @@ func printToolInfo(out io.Writer, t *tool.Tool) (int64, error) {
 		styledSize := sizeStyle.Render(util.FormatBytes(v.Size))
 
 		if _, err := fmt.Fprintf(out, "%s  %s  %s  %s\n", toolName, styledVersion, styledSize, v.Path); err != nil {
-			return 0, fmt.Errorf("failed to write output: %w", err)
+			return nil, fmt.Errorf("failed to write output: %w", err)
+		}
+
+		if verbose {
+			if err := printVersionMetadata(out, v.Metadata); err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	return totalSize, nil
+	return versions, nil
 }
 
-func runToolsUpdate(cmd *cobra.Command, args []string) error {
+// printVersionMetadata prints the download metadata of a cached version below its info line.
+func printVersionMetadata(out io.Writer, metadata *tool.VersionMetadata) error {
+	if metadata == nil {
+		metadata = &tool.VersionMetadata{}
+	}
+
+	source := metadata.SourceURL
+	if metadata.Imported {
+		source = "imported from bundle"
+	}
+
+	downloaded := formatMetadataTime(metadata.DownloadedAt)
+	if downloaded != "" && metadata.KdevVersion != "" {
+		downloaded += " by kdev " + metadata.KdevVersion
+	}
+
+	fields := []struct{ name, value string }{
+		{"source", source},
+		{"sha256", metadata.SHA256},
+		{"downloaded", downloaded},
+		{"last used", formatMetadataTime(metadata.LastUsed)},
+	}
+
+	for _, field := range fields {
+		value := field.value
+		if value == "" {
+			value = notCachedStyle.Render("unknown")
+		}
+
+		if _, err := fmt.Fprintf(out, "  %-11s %s\n", field.name+":", value); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// formatMetadataTime formats a metadata timestamp in local time, or returns
+// an empty string if it was not recorded.
+func formatMetadataTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Local().Format(time.DateTime)
+}
+
+func runToolsInstall(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+	registry := getRegistry(cmd, out)
+
+	for _, arg := range args {
+		name, spec, _ := strings.Cut(arg, "@")
+
+		t := registry.Get(name)
+		if t == nil {
+			return fmt.Errorf("unknown tool: %s", name)
+		}
+
+		version, err := t.Install(ctx, spec)
+		if err != nil {
+			return fmt.Errorf("failed to install %s: %w", arg, err)
+		}
+
+		toolName := toolNameStyle.Render(t.Name)
+		styledVersion := latestStyle.Render(version)
+
+		if _, err := fmt.Fprintf(out, "%s %s %s\n", toolName, styledVersion, infoStyle.Render("installed")); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func runToolsLock(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 	out := cmd.OutOrStdout()
-	registry := tool.NewRegistry(out)
+	fs := afero.NewOsFs()
+
+	platforms, err := cmd.Flags().GetStringSlice("platform")
+	if err != nil {
+		return fmt.Errorf("failed to get --platform flag: %w", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to determine working directory: %w", err)
+	}
+
+	lock, err := tool.LoadLockFile(fs, wd)
+	if err != nil {
+		return err
+	}
+
+	registry := getRegistry(cmd, nil)
+
+	for _, t := range resolveTools(registry, args) {
+		locked, err := t.Lock(ctx, platforms)
+		if err != nil {
+			return fmt.Errorf("failed to lock %s: %w", t.Name, err)
+		}
+
+		lock.Set(t.Name, locked)
+
+		toolName := toolNameStyle.Render(t.Name)
+		version := latestStyle.Render(locked.Version)
+
+		if _, err := fmt.Fprintf(out, "%s %s %s\n", toolName, version, infoStyle.Render("locked")); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+
+	path := tool.LockFilePath(fs, wd)
+
+	if err := lock.Save(fs, path); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(out, "Wrote %s\n", path); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
+
+func runToolsOutdated(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+	registry := getRegistry(cmd, nil)
+
 	tools := resolveTools(registry, args)
 
+	header := toolNameStyle.Render("TOOL") + "  " + headerStyle.Render("CACHED") + "  " + headerStyle.Render("PINNED") + "  " +
+		headerStyle.Render("WANTED") + "  " + headerStyle.Render("LATEST")
+	if _, err := fmt.Fprintln(out, header); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	outdated := 0
+
 	for _, t := range tools {
-		latest, err := t.LatestVersion(ctx)
+		status, err := t.Status(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to get latest version for %s: %w", t.Name, err)
+			return fmt.Errorf("failed to check %s: %w", t.Name, err)
 		}
 
-		versions, err := t.CachedVersions()
-		if err != nil {
-			return fmt.Errorf("failed to get cached versions for %s: %w", t.Name, err)
+		if status.Outdated() {
+			outdated++
 		}
 
-		alreadyCached := false
+		if err := printToolStatus(out, t.Name, status); err != nil {
+			return err
+		}
+	}
 
-		for _, v := range versions {
-			if v.Version == latest {
-				alreadyCached = true
+	if outdated > 0 {
+		return fmt.Errorf("%d of %d tools outdated", outdated, len(tools))
+	}
 
-				break
-			}
+	return nil
+}
+
+func printToolStatus(out io.Writer, name string, status tool.VersionStatus) error {
+	orNone := func(s string) string {
+		if s == "" {
+			return "-"
 		}
 
-		toolName := toolNameStyle.Render(t.Name)
-		version := latestStyle.Render(latest)
+		return s
+	}
+
+	cachedStyle := specStyle
+	if status.CacheBehind() {
+		cachedStyle = behindStyle
+	}
+
+	wantedStyle := specStyle
+	if status.PinBehind() {
+		wantedStyle = behindStyle
+	}
+
+	latest := status.Latest
+	if latest == "" {
+		latest = "unknown"
+	}
+
+	line := toolNameStyle.Render(name) + "  " +
+		cachedStyle.Render(orNone(status.Cached)) + "  " +
+		specStyle.Render(orNone(status.Pinned)) + "  " +
+		wantedStyle.Render(status.Wanted) + "  " +
+		upstreamStyle.Render(latest)
+
+	if _, err := fmt.Fprintln(out, line); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
 
-		if alreadyCached {
-			message := infoStyle.Render("already cached")
-			if _, err := fmt.Fprintf(out, "%s %s %s\n", toolName, version, message); err != nil {
+func runToolsUpdate(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+	registry := getRegistry(cmd, out)
+
+	tools := resolveTools(registry, args)
+
+	if len(tools) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(tools))
+	for _, t := range tools {
+		names = append(names, t.Name)
+	}
+
+	display := newUpdateDisplay(out, names)
+	display.start()
+
+	errs := make([]error, len(tools))
+	slots := make(chan struct{}, maxParallelUpdates)
+
+	var wg sync.WaitGroup
+
+	for i, t := range tools {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			errs[i] = updateTool(ctx, t, display.lines[i])
+		}()
+	}
+
+	wg.Wait()
+
+	if err := display.stop(); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintln(out, display.summary()); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// updateTool downloads the version t should be updated to unless it is
+// already cached, reporting its progress to line.
+func updateTool(ctx context.Context, t *tool.Tool, line *updateLine) error {
+	t.ProgressWriter = line
+	t.OnProgress = line.setProgress
+
+	if err := line.setStatus(updateResolving, nil); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	fail := func(err error) error {
+		_ = line.setStatus(updateFailed, err) //nolint:errcheck // err is reported instead
+
+		return err
+	}
+
+	target, err := t.RefreshVersion(ctx)
+	if err != nil {
+		return fail(fmt.Errorf("failed to get version for %s: %w", t.Name, err))
+	}
+
+	line.setVersion(target)
+
+	versions, err := t.CachedVersions()
+	if err != nil {
+		return fail(fmt.Errorf("failed to get cached versions for %s: %w", t.Name, err))
+	}
+
+	for _, v := range versions {
+		if v.Version == target {
+			if err := line.setStatus(updateCached, nil); err != nil {
 				return fmt.Errorf("failed to write output: %w", err)
 			}
 
-			continue
+			return nil
 		}
+	}
 
-		if err := t.Download(ctx); err != nil {
-			return fmt.Errorf("failed to download %s: %w", t.Name, err)
-		}
+	if err := line.setStatus(updateDownloading, nil); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
 	}
 
-	return nil
+	if err := t.Download(ctx); err != nil {
+		return fail(fmt.Errorf("failed to download %s: %w", t.Name, err))
+	}
+
+	return line.setStatus(updateDownloaded, nil)
 }
 
 func resolveTools(registry *tool.Registry, names []string) []*tool.Tool {
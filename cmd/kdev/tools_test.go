@@ -3,8 +3,11 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -28,6 +31,14 @@ func TestNewToolsCmd(t *testing.T) {
 		assert.True(t, cmd.HasSubCommands())
 	})
 
+	t.Run("has bundle subcommand", func(t *testing.T) {
+		cmd := newToolsCmd()
+
+		bundleCmd, _, err := cmd.Find([]string{"bundle", "export"})
+		require.NoError(t, err)
+		assert.Equal(t, "export", bundleCmd.Name())
+	})
+
 	t.Run("has clean subcommand", func(t *testing.T) {
 		cmd := newToolsCmd()
 
@@ -36,6 +47,14 @@ func TestNewToolsCmd(t *testing.T) {
 		assert.Equal(t, "clean", cleanCmd.Name())
 	})
 
+	t.Run("has gc subcommand", func(t *testing.T) {
+		cmd := newToolsCmd()
+
+		gcCmd, _, err := cmd.Find([]string{"gc"})
+		require.NoError(t, err)
+		assert.Equal(t, "gc", gcCmd.Name())
+	})
+
 	t.Run("has info subcommand", func(t *testing.T) {
 		cmd := newToolsCmd()
 
@@ -44,6 +63,30 @@ func TestNewToolsCmd(t *testing.T) {
 		assert.Equal(t, "info", infoCmd.Name())
 	})
 
+	t.Run("has install subcommand", func(t *testing.T) {
+		cmd := newToolsCmd()
+
+		installCmd, _, err := cmd.Find([]string{"install"})
+		require.NoError(t, err)
+		assert.Equal(t, "install", installCmd.Name())
+	})
+
+	t.Run("has lock subcommand", func(t *testing.T) {
+		cmd := newToolsCmd()
+
+		lockCmd, _, err := cmd.Find([]string{"lock"})
+		require.NoError(t, err)
+		assert.Equal(t, "lock", lockCmd.Name())
+	})
+
+	t.Run("has outdated subcommand", func(t *testing.T) {
+		cmd := newToolsCmd()
+
+		outdatedCmd, _, err := cmd.Find([]string{"outdated"})
+		require.NoError(t, err)
+		assert.Equal(t, "outdated", outdatedCmd.Name())
+	})
+
 	t.Run("has update subcommand", func(t *testing.T) {
 		cmd := newToolsCmd()
 
@@ -51,6 +94,14 @@ func TestNewToolsCmd(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, "update", updateCmd.Name())
 	})
+
+	t.Run("has verify subcommand", func(t *testing.T) {
+		cmd := newToolsCmd()
+
+		verifyCmd, _, err := cmd.Find([]string{"verify"})
+		require.NoError(t, err)
+		assert.Equal(t, "verify", verifyCmd.Name())
+	})
 }
 
 func TestNewToolsCleanCmd(t *testing.T) {
@@ -173,6 +224,59 @@ func TestRunToolsClean(t *testing.T) { //nolint:maintidx // test function comple
 		assert.Contains(t, output, expectedSize)
 	})
 
+	t.Run("counts binaries sharing a blob once", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+
+		v29Path := createCachedTool(t, tmpHome, "kubectl", "v1.29.0", 1024*100)
+		blobPath := shareCachedToolBlob(t, tmpHome, v29Path, "kubectl", "v1.30.0")
+
+		cmd := newToolsCleanCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"kubectl"})
+
+		require.NoError(t, cmd.Execute())
+		assert.Contains(t, buf.String(), "Reclaimed "+util.FormatBytes(1024*100))
+		requireFileNotExists(t, blobPath)
+	})
+
+	t.Run("--old flag does not count blobs of kept versions", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+
+		createCachedTool(t, tmpHome, "kubectl", "v1.28.0", 1024*50)
+		v29Path := createCachedTool(t, tmpHome, "kubectl", "v1.29.0", 1024*100)
+		blobPath := shareCachedToolBlob(t, tmpHome, v29Path, "kubectl", "v1.30.0")
+
+		cmd := newToolsCleanCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"--old", "kubectl"})
+
+		require.NoError(t, cmd.Execute())
+
+		// Only v1.28.0 frees space, v1.29.0 shares its blob with the kept v1.30.0.
+		assert.Contains(t, buf.String(), "Reclaimed "+util.FormatBytes(1024*50))
+		requireFileExists(t, blobPath)
+	})
+
+	t.Run("does not count blobs shared with other tools", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+
+		kubectlPath := createCachedTool(t, tmpHome, "kubectl", "v1.30.0", 1024*100)
+		shareCachedToolBlob(t, tmpHome, kubectlPath, "kind", "v0.20.0")
+
+		cmd := newToolsCleanCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"kubectl"})
+
+		require.NoError(t, cmd.Execute())
+		assert.Empty(t, buf.String())
+	})
+
 	t.Run("--old flag keeps newest version", func(t *testing.T) {
 		tmpHome := setupTestCacheDir(t)
 
@@ -387,15 +491,14 @@ func TestRunToolsClean(t *testing.T) { //nolint:maintidx // test function comple
 		// Get the directory paths
 		versionDir := binPath[:len(binPath)-len("/kubectl")]
 		toolDir := versionDir[:len(versionDir)-len("/v1.30.0")]
-		kdevDir := toolDir[:len(toolDir)-len("/kubectl")]
 
-		// Make kubectl tool directory undeletable by removing write permission from parent
-		err := os.Chmod(kdevDir, 0o555)
+		// Make the version directory undeletable by removing write permission from the tool directory
+		err := os.Chmod(toolDir, 0o555)
 		require.NoError(t, err)
 
 		// Restore permissions after test
 		defer func() {
-			_ = os.Chmod(kdevDir, 0o755) //nolint:errcheck // cleanup in test
+			_ = os.Chmod(toolDir, 0o755) //nolint:errcheck // cleanup in test
 		}()
 
 		cmd := newToolsCleanCmd()
@@ -406,7 +509,7 @@ func TestRunToolsClean(t *testing.T) { //nolint:maintidx // test function comple
 
 		err = cmd.Execute()
 
-		// Should get an error removing the tool directory
+		// Should get an error removing the version directory
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to clean kubectl")
 	})
@@ -478,6 +581,40 @@ func TestRunToolsInfo(t *testing.T) {
 		}
 	})
 
+	t.Run("shows user-defined tools", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+		writeToolsConfig(t, testToolDefinition("helm"))
+		createCachedTool(t, tmpHome, "helm", "v3.15.0", 1024)
+
+		cmd := newToolsInfoCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"helm"})
+		cmd.SetContext(context.Background())
+
+		require.NoError(t, cmd.Execute())
+		assert.Contains(t, buf.String(), "helm")
+		assert.Contains(t, buf.String(), "v3.15.0")
+	})
+
+	t.Run("warns about invalid user-defined tools", func(t *testing.T) {
+		setupTestCacheDir(t)
+		writeToolsConfig(t, "tools: [")
+
+		cmd := newToolsInfoCmd()
+
+		var buf, errOut bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetErr(&errOut)
+		cmd.SetArgs([]string{})
+		cmd.SetContext(context.Background())
+
+		require.NoError(t, cmd.Execute())
+		assert.Contains(t, errOut.String(), "failed to parse")
+		assert.Contains(t, buf.String(), "kubectl", "built-in tools keep working")
+	})
+
 	t.Run("handles unknown tool", func(t *testing.T) {
 		cmd := newToolsInfoCmd()
 
@@ -518,37 +655,285 @@ func TestPrintToolInfo(t *testing.T) {
 	t.Run("shows not cached message when no versions", func(t *testing.T) {
 		var buf bytes.Buffer
 
-		registry := newTestRegistry(&buf)
+		registry := newTestRegistry(t, &buf)
 		kubectl := registry.Get("kubectl")
 
 		// Ensure no cached versions exist
-		size, err := printToolInfo(&buf, kubectl)
+		versions, err := printToolInfo(&buf, kubectl, false)
 		require.NoError(t, err)
-		assert.Equal(t, int64(0), size)
+		assert.Empty(t, versions)
 
 		output := buf.String()
 		assert.Contains(t, output, "kubectl")
 		assert.Contains(t, output, "not cached")
 	})
 
+	t.Run("shows download metadata when verbose", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+		binPath := createCachedTool(t, tmpHome, "kubectl", "v1.30.0", 1024)
+		createCachedTool(t, tmpHome, "kubectl", "v1.29.0", 1024)
+
+		metadata := `{"sourceURL": "https://dl.k8s.io/release/v1.30.0/bin/linux/amd64/kubectl", "sha256": "abc123",
+			"downloadedAt": "2025-01-02T03:04:05Z", "kdevVersion": "v0.1.0"}`
+		require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(binPath), "metadata.json"), []byte(metadata), 0o644))
+
+		var buf bytes.Buffer
+
+		_, err := printToolInfo(&buf, newTestRegistry(t, &bytes.Buffer{}).Get("kubectl"), true)
+		require.NoError(t, err)
+
+		output := buf.String()
+		assert.Contains(t, output, "source:     https://dl.k8s.io/release/v1.30.0/bin/linux/amd64/kubectl")
+		assert.Contains(t, output, "sha256:     abc123")
+		assert.Contains(t, output, "by kdev v0.1.0")
+		assert.Contains(t, output, "last used:  unknown")
+		// v1.29.0 has no metadata
+		assert.Equal(t, 5, strings.Count(output, "unknown"))
+	})
+
 	t.Run("handles write error for cached versions", func(t *testing.T) {
 		tmpHome := setupTestCacheDir(t)
 
 		// Create a cached tool
 		createCachedTool(t, tmpHome, "kubectl", "v1.30.0", 1024*200)
 
-		registry := newTestRegistry(&bytes.Buffer{})
+		registry := newTestRegistry(t, &bytes.Buffer{})
 		kubectl := registry.Get("kubectl")
 
 		// Use error writer
 		errWriter := testutil.NewErrorWriter(fmt.Errorf("write error"))
 
-		_, err := printToolInfo(errWriter, kubectl)
+		_, err := printToolInfo(errWriter, kubectl, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to write output")
+	})
+}
+
+func TestNewToolsInstallCmd(t *testing.T) {
+	t.Run("creates install command", func(t *testing.T) {
+		cmd := newToolsInstallCmd()
+
+		require.NotNil(t, cmd)
+		assert.Equal(t, "install tool[@version]...", cmd.Use)
+		assert.NotEmpty(t, cmd.Short)
+		assert.NotNil(t, cmd.RunE)
+	})
+}
+
+func TestRunToolsInstall(t *testing.T) {
+	t.Run("requires at least one tool", func(t *testing.T) {
+		cmd := newToolsInstallCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetErr(&buf)
+		cmd.SetArgs([]string{})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.Error(t, err)
+	})
+
+	t.Run("fails for unknown tool", func(t *testing.T) {
+		cmd := newToolsInstallCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"nonexistent@v1.0.0"})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown tool: nonexistent")
+	})
+
+	t.Run("reports already cached version as installed", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+
+		createCachedTool(t, tmpHome, "kind", "v0.22.0", 1024)
+
+		cmd := newToolsInstallCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"kind@v0.22.0"})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+
+		output := buf.String()
+		assert.Contains(t, output, "v0.22.0")
+		assert.Contains(t, output, "installed")
+		assert.NotContains(t, output, "Downloading")
+	})
+
+	t.Run("resolves constraint against cached versions in offline mode", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+		t.Setenv(tool.OfflineEnvVar, "1")
+
+		createCachedTool(t, tmpHome, "kind", "v0.21.0", 1024)
+		createCachedTool(t, tmpHome, "kind", "v0.22.0", 1024)
+
+		cmd := newToolsInstallCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"kind@~0.21"})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "Offline mode: using cached kind v0.21.0")
+	})
+}
+
+func TestNewToolsLockCmd(t *testing.T) {
+	t.Run("creates lock command", func(t *testing.T) {
+		cmd := newToolsLockCmd()
+
+		require.NotNil(t, cmd)
+		assert.Equal(t, "lock [tool...]", cmd.Use)
+		assert.NotEmpty(t, cmd.Short)
+		assert.NotNil(t, cmd.RunE)
+	})
+
+	t.Run("has --platform flag", func(t *testing.T) {
+		cmd := newToolsLockCmd()
+
+		platforms, err := cmd.Flags().GetStringSlice("platform")
+		require.NoError(t, err)
+		assert.Equal(t, tool.DefaultLockPlatforms, platforms)
+	})
+}
+
+func TestRunToolsLock(t *testing.T) {
+	t.Run("writes lockfile next to project config", func(t *testing.T) {
+		setupTestCacheDir(t)
+		writeProjectConfig(t, "versions: {}\n")
+
+		cmd := newToolsLockCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"nonexistent"})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+		requireFileExists(t, filepath.Join(wd, tool.LockFileName))
+		assert.Contains(t, buf.String(), "Wrote")
+	})
+
+	t.Run("rejects invalid platform", func(t *testing.T) {
+		setupTestCacheDir(t)
+		writeProjectConfig(t, "versions:\n  kind: v0.20.0\n")
+
+		cmd := newToolsLockCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"kind", "--platform", "linux"})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid platform")
+
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+		requireFileNotExists(t, filepath.Join(wd, tool.LockFileName))
+	})
+}
+
+func TestNewToolsOutdatedCmd(t *testing.T) {
+	t.Run("creates outdated command", func(t *testing.T) {
+		cmd := newToolsOutdatedCmd()
+
+		require.NotNil(t, cmd)
+		assert.Equal(t, "outdated [tool...]", cmd.Use)
+		assert.NotEmpty(t, cmd.Short)
+		assert.NotNil(t, cmd.RunE)
+		assert.True(t, cmd.SilenceUsage)
+	})
+}
+
+func TestRunToolsOutdated(t *testing.T) {
+	t.Run("prints header only for unknown tool", func(t *testing.T) {
+		cmd := newToolsOutdatedCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"nonexistent"})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+
+		output := buf.String()
+		assert.Contains(t, output, "CACHED")
+		assert.Contains(t, output, "LATEST")
+	})
+
+	t.Run("handles header write error", func(t *testing.T) {
+		cmd := newToolsOutdatedCmd()
+
+		errWriter := testutil.NewErrorWriter(fmt.Errorf("write error"))
+		cmd.SetOut(errWriter)
+		cmd.SetArgs([]string{"nonexistent"})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to write output")
 	})
 }
 
+func TestPrintToolStatus(t *testing.T) {
+	t.Run("prints placeholders for missing versions", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := printToolStatus(&buf, "kind", tool.VersionStatus{Wanted: "v0.22.0", Latest: "v0.22.0"})
+		require.NoError(t, err)
+
+		output := buf.String()
+		assert.Contains(t, output, "kind")
+		assert.Contains(t, output, "-")
+		assert.Contains(t, output, "v0.22.0")
+	})
+
+	t.Run("prints all versions", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		status := tool.VersionStatus{Cached: "v1.30.2", Pinned: "~1.30", Wanted: "v1.30.4", Latest: "v1.31.0"}
+		err := printToolStatus(&buf, "kubectl", status)
+		require.NoError(t, err)
+
+		output := buf.String()
+		for _, want := range []string{"v1.30.2", "~1.30", "v1.30.4", "v1.31.0"} {
+			assert.Contains(t, output, want)
+		}
+	})
+
+	t.Run("prints an unknown latest version", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := printToolStatus(&buf, "kind", tool.VersionStatus{Cached: "v0.22.0", Wanted: "v0.22.0"})
+		require.NoError(t, err)
+		assert.True(t, strings.HasSuffix(strings.TrimSpace(buf.String()), "unknown"))
+	})
+
+	t.Run("handles write error", func(t *testing.T) {
+		errWriter := testutil.NewErrorWriter(fmt.Errorf("write error"))
+
+		err := printToolStatus(errWriter, "kind", tool.VersionStatus{})
+		require.Error(t, err)
+	})
+}
+
 func TestNewToolsUpdateCmd(t *testing.T) {
 	t.Run("creates update command", func(t *testing.T) {
 		cmd := newToolsUpdateCmd()
@@ -633,6 +1018,114 @@ func TestRunToolsUpdate(t *testing.T) {
 		_ = err
 	})
 
+	t.Run("uses pinned version from project config", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+
+		createCachedTool(t, tmpHome, "kubectl", "v1.29.3", 1024)
+		writeProjectConfig(t, "versions:\n  kubectl: v1.29.3\n")
+
+		cmd := newToolsUpdateCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"kubectl"})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+
+		output := buf.String()
+		assert.Contains(t, output, "v1.29.3")
+		assert.Contains(t, output, "already cached")
+	})
+
+	t.Run("uses locked version", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+
+		createCachedTool(t, tmpHome, "kind", "v0.20.0", 1024)
+		writeProjectConfig(t, "versions: {}\n")
+
+		err := os.WriteFile(tool.LockFileName, []byte("tools:\n  kind:\n    version: v0.20.0\n"), 0o644)
+		require.NoError(t, err)
+
+		cmd := newToolsUpdateCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"kind"})
+		cmd.SetContext(context.Background())
+
+		err = cmd.Execute()
+		require.NoError(t, err)
+
+		output := buf.String()
+		assert.Contains(t, output, "v0.20.0")
+		assert.Contains(t, output, "already cached")
+	})
+
+	t.Run("uses newest cached version in offline mode", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+		t.Setenv(tool.OfflineEnvVar, "1")
+
+		createCachedTool(t, tmpHome, "kind", "v0.21.0", 1024)
+		createCachedTool(t, tmpHome, "kind", "v0.22.0", 1024)
+
+		cmd := newToolsUpdateCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"kind"})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+
+		output := buf.String()
+		assert.Contains(t, output, "Offline mode: using cached kind v0.22.0")
+		assert.Contains(t, output, "already cached")
+	})
+
+	t.Run("updates multiple tools and prints summary", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+		t.Setenv(tool.OfflineEnvVar, "1")
+
+		createCachedTool(t, tmpHome, "kind", "v0.22.0", 1024)
+		createCachedTool(t, tmpHome, "kubectl", "v1.31.0", 1024)
+
+		cmd := newToolsUpdateCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"kind", "kubectl"})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.NoError(t, err)
+
+		output := buf.String()
+		assert.Contains(t, output, "v0.22.0")
+		assert.Contains(t, output, "v1.31.0")
+		assert.Contains(t, output, "Checked 2 tools: 0 downloaded (0 B), 2 already cached")
+	})
+
+	t.Run("reports all failures", func(t *testing.T) {
+		setupTestCacheDir(t)
+		t.Setenv(tool.OfflineEnvVar, "1")
+
+		cmd := newToolsUpdateCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"kind", "kubectl"})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to get version for kind")
+		assert.Contains(t, err.Error(), "failed to get version for kubectl")
+		assert.Contains(t, buf.String(), "2 failed")
+	})
+
 	t.Run("handles LatestVersion error", func(t *testing.T) {
 		// This test verifies error handling for LatestVersion failure
 		// In the real world, this would happen if the network is down
@@ -660,7 +1153,7 @@ func TestResolveTools(t *testing.T) {
 	t.Run("returns all tools when no names provided", func(t *testing.T) {
 		var buf bytes.Buffer
 
-		registry := newTestRegistry(&buf)
+		registry := newTestRegistry(t, &buf)
 
 		tools := resolveTools(registry, nil)
 
@@ -675,7 +1168,7 @@ func TestResolveTools(t *testing.T) {
 	t.Run("returns all tools when empty slice provided", func(t *testing.T) {
 		var buf bytes.Buffer
 
-		registry := newTestRegistry(&buf)
+		registry := newTestRegistry(t, &buf)
 
 		tools := resolveTools(registry, []string{})
 
@@ -685,7 +1178,7 @@ func TestResolveTools(t *testing.T) {
 	t.Run("returns specific tool when name provided", func(t *testing.T) {
 		var buf bytes.Buffer
 
-		registry := newTestRegistry(&buf)
+		registry := newTestRegistry(t, &buf)
 
 		tools := resolveTools(registry, []string{"kubectl"})
 
@@ -696,7 +1189,7 @@ func TestResolveTools(t *testing.T) {
 	t.Run("returns empty slice for unknown tool", func(t *testing.T) {
 		var buf bytes.Buffer
 
-		registry := newTestRegistry(&buf)
+		registry := newTestRegistry(t, &buf)
 
 		tools := resolveTools(registry, []string{"nonexistent"})
 
@@ -705,8 +1198,13 @@ func TestResolveTools(t *testing.T) {
 }
 
 // newTestRegistry creates a registry for testing.
-func newTestRegistry(buf *bytes.Buffer) *tool.Registry {
-	return tool.NewRegistry(buf)
+func newTestRegistry(t *testing.T, buf *bytes.Buffer) *tool.Registry {
+	t.Helper()
+
+	registry, err := tool.NewRegistry(buf)
+	require.NoError(t, err)
+
+	return registry
 }
 
 // setupTestCacheDir creates a temporary home directory for testing
@@ -744,6 +1242,45 @@ func createCachedTool(t *testing.T, home, toolName, version string, size int64)
 	return binPath
 }
 
+// shareCachedToolBlob moves the cached binary at binPath into the blob store
+// and links version of toolName to the same blob, as kdev does for identical
+// binaries. Returns the path of the blob.
+func shareCachedToolBlob(t *testing.T, home, binPath, toolName, version string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(binPath)
+	require.NoError(t, err)
+
+	checksum := fmt.Sprintf("%x", sha256.Sum256(data))
+	blobPath := filepath.Join(home, ".kdev", "kdev", ".blobs", "sha256", checksum)
+	sharedPath := filepath.Join(home, ".kdev", "kdev", toolName, version, toolName)
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(blobPath), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Dir(sharedPath), 0o755))
+	require.NoError(t, os.Link(binPath, blobPath))
+	require.NoError(t, os.Link(binPath, sharedPath))
+
+	for _, path := range []string{binPath, sharedPath} {
+		metadata := fmt.Sprintf(`{"sha256": "%s"}`, checksum)
+		require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(path), "metadata.json"), []byte(metadata), 0o644))
+	}
+
+	return blobPath
+}
+
+// writeProjectConfig writes a .kdev.yaml with the given content into a fresh
+// temporary directory and makes it the working directory.
+func writeProjectConfig(t *testing.T, content string) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(dir, tool.ProjectConfigFile), []byte(content), 0o644)
+	require.NoError(t, err)
+
+	t.Chdir(dir)
+}
+
 // requireFileExists fails the test if the file doesn't exist.
 func requireFileExists(t *testing.T, path string) {
 	t.Helper()
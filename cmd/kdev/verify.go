@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dennisklein/kdev/internal/tool"
+)
+
+func newToolsVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [tool...]",
+		Short: "Check cached tools for corruption",
+		Long: `Re-hash cached tool binaries, compare them with their checksum and check that they are executables for the host OS and architecture. ` +
+			`Binaries are compared with the checksum in ` + tool.LockFileName + ` if it has one for the host, and otherwise with the sha256 recorded when they were downloaded. ` +
+			`The recorded sha256 comes from the cache itself, so it only detects corruption, not tampering. ` +
+			`With --upstream, binaries without a checksum in ` + tool.LockFileName + ` are compared with the upstream checksum instead; if it cannot be fetched, the recorded sha256 is used. ` +
+			`Checksums of archives do not apply to the binaries extracted from them, so these binaries are always compared with the recorded sha256. ` +
+			`Broken versions are downloaded again with --repair, or after confirmation when running on a terminal. Exits with a non-zero code if a broken version remains. ` +
+			`If no tool names are specified, verifies all tools. Set ` + tool.VerifyEnvVar + `=true or "verify: true" in .kdev.yaml to verify binaries before each execution.`,
+		RunE: runToolsVerify,
+		// A report of broken versions is not a usage error.
+		SilenceUsage: true,
+	}
+
+	cmd.Flags().Bool("repair", false, "Download broken versions again without asking")
+	cmd.Flags().Bool("upstream", false, "Compare binaries without a checksum in "+tool.LockFileName+" with the upstream checksum")
+
+	return cmd
+}
+
+func runToolsVerify(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	out := cmd.OutOrStdout()
+	registry := getRegistry(cmd, out)
+
+	tools := resolveTools(registry, args)
+
+	repair, err := cmd.Flags().GetBool("repair")
+	if err != nil {
+		return fmt.Errorf("failed to get --repair flag: %w", err)
+	}
+
+	upstream, err := cmd.Flags().GetBool("upstream")
+	if err != nil {
+		return fmt.Errorf("failed to get --upstream flag: %w", err)
+	}
+
+	// Only ask when someone can answer.
+	ask := !repair && isTerminal(cmd.InOrStdin()) && isTerminal(out)
+	in := bufio.NewReader(cmd.InOrStdin())
+
+	verified, broken := 0, 0
+
+	for _, t := range tools {
+		results, err := t.Verify(ctx, upstream)
+		if err != nil {
+			return fmt.Errorf("failed to verify %s: %w", t.Name, err)
+		}
+
+		for _, result := range results {
+			verified++
+
+			if err := printVerifyResult(out, t.Name, result); err != nil {
+				return err
+			}
+
+			if result.OK() {
+				continue
+			}
+
+			fix := repair
+			if ask {
+				if fix, err = confirm(in, out, fmt.Sprintf("Download %s %s again?", t.Name, result.Version)); err != nil {
+					return err
+				}
+			}
+
+			if !fix {
+				broken++
+
+				continue
+			}
+
+			if err := t.Repair(ctx, result.Version); err != nil {
+				broken++
+
+				if _, err := fmt.Fprintf(out, "%s %s %s\n", toolNameStyle.Render(t.Name), versionStyle.Render(result.Version),
+					failedStyle.Render("repair failed: "+err.Error())); err != nil {
+					return fmt.Errorf("failed to write output: %w", err)
+				}
+
+				continue
+			}
+
+			if _, err := fmt.Fprintf(out, "%s %s %s\n", toolNameStyle.Render(t.Name), versionStyle.Render(result.Version), successStyle.Render("repaired")); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+		}
+	}
+
+	if broken > 0 {
+		return fmt.Errorf("%d of %d cached versions failed verification", broken, verified)
+	}
+
+	return nil
+}
+
+func printVerifyResult(out io.Writer, name string, result tool.VerifyResult) error {
+	var status string
+
+	switch {
+	case !result.OK():
+		status = failedStyle.Render("failed: " + result.Err.Error())
+	case result.Trusted != "":
+		status = successStyle.Render("ok (matches " + result.Trusted + ")")
+	case result.Checksummed:
+		status = successStyle.Render("ok")
+	default:
+		status = notCachedStyle.Render("ok (no recorded checksum)")
+	}
+
+	if _, err := fmt.Fprintf(out, "%s %s %s\n", toolNameStyle.Render(name), versionStyle.Render(result.Version), status); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	if result.UpstreamErr != nil {
+		if _, err := fmt.Fprintf(out, "Warning: upstream checksum of %s %s unavailable: %v\n", name, result.Version, result.UpstreamErr); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// confirm asks a yes/no question, defaulting to no.
+func confirm(in *bufio.Reader, out io.Writer, question string) (bool, error) {
+	if _, err := fmt.Fprintf(out, "%s [y/N] ", question); err != nil {
+		return false, fmt.Errorf("failed to write output: %w", err)
+	}
+
+	answer, err := in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
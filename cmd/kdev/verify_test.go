@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dennisklein/kdev/internal/testutil"
+	"github.com/dennisklein/kdev/internal/tool"
+)
+
+// createExecutableCachedTool caches a copy of the running test binary, which
+// is an executable for the host, and records its checksum if checksummed is set.
+func createExecutableCachedTool(t *testing.T, home, toolName, version string, checksummed bool) string {
+	t.Helper()
+
+	self, err := os.Executable()
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(self)
+	require.NoError(t, err)
+
+	binPath := createCachedTool(t, home, toolName, version, 0)
+	require.NoError(t, os.WriteFile(binPath, data, 0o755))
+
+	if checksummed {
+		metadata := fmt.Sprintf(`{"sha256": "%x"}`, sha256.Sum256(data))
+		require.NoError(t, os.WriteFile(filepath.Join(filepath.Dir(binPath), "metadata.json"), []byte(metadata), 0o644))
+	}
+
+	return binPath
+}
+
+func TestRunToolsVerify(t *testing.T) {
+	t.Run("reports intact versions", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+		createExecutableCachedTool(t, tmpHome, "kind", "v0.20.0", true)
+		createExecutableCachedTool(t, tmpHome, "kind", "v0.19.0", false)
+
+		cmd := newToolsVerifyCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"kind"})
+		cmd.SetContext(context.Background())
+
+		require.NoError(t, cmd.Execute())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.Contains(t, lines[0], "v0.20.0")
+		assert.True(t, strings.HasSuffix(lines[0], "ok"))
+		assert.Contains(t, lines[1], "v0.19.0")
+		assert.Contains(t, lines[1], "ok (no recorded checksum)")
+	})
+
+	t.Run("warns if the upstream checksum is unavailable", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+		t.Setenv(tool.OfflineEnvVar, "true")
+		createExecutableCachedTool(t, tmpHome, "kind", "v0.20.0", true)
+
+		cmd := newToolsVerifyCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetArgs([]string{"--upstream", "kind"})
+		cmd.SetContext(context.Background())
+
+		require.NoError(t, cmd.Execute())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.True(t, strings.HasSuffix(lines[0], "ok"))
+		assert.Contains(t, lines[1], "Warning: upstream checksum of kind v0.20.0 unavailable")
+	})
+
+	t.Run("fails for broken versions", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+		binPath := createExecutableCachedTool(t, tmpHome, "kind", "v0.20.0", true)
+		createCachedTool(t, tmpHome, "kubectl", "v1.30.0", 1024)
+
+		file, err := os.OpenFile(binPath, os.O_APPEND|os.O_WRONLY, 0o755)
+		require.NoError(t, err)
+		_, err = file.WriteString("tampered")
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		cmd := newToolsVerifyCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs([]string{"kind", "kubectl"})
+		cmd.SetContext(context.Background())
+
+		err = cmd.Execute()
+		require.Error(t, err)
+		assert.Equal(t, "2 of 2 cached versions failed verification", err.Error())
+
+		output := buf.String()
+		assert.Contains(t, output, "failed: corrupt binary: sha256")
+		assert.Contains(t, output, "failed: corrupt binary: not a")
+		assert.NotContains(t, output, "[y/N]", "should not ask without a terminal")
+		requireFileExists(t, binPath)
+	})
+
+	t.Run("succeeds without cached versions", func(t *testing.T) {
+		setupTestCacheDir(t)
+
+		cmd := newToolsVerifyCmd()
+
+		var buf bytes.Buffer
+		cmd.SetOut(&buf)
+		cmd.SetContext(context.Background())
+
+		require.NoError(t, cmd.Execute())
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("handles write errors", func(t *testing.T) {
+		tmpHome := setupTestCacheDir(t)
+		createCachedTool(t, tmpHome, "kind", "v0.20.0", 1024)
+
+		cmd := newToolsVerifyCmd()
+		cmd.SetOut(testutil.NewErrorWriter(fmt.Errorf("write error")))
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs([]string{"kind"})
+		cmd.SetContext(context.Background())
+
+		err := cmd.Execute()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to write output")
+	})
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   bool
+	}{
+		{answer: "y\n", want: true},
+		{answer: "Yes\n", want: true},
+		{answer: "n\n", want: false},
+		{answer: "\n", want: false},
+		{answer: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.answer), func(t *testing.T) {
+			var out bytes.Buffer
+
+			got, err := confirm(bufio.NewReader(strings.NewReader(tt.answer)), &out, "Download kind v0.20.0 again?")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, "Download kind v0.20.0 again? [y/N] ", out.String())
+		})
+	}
+}
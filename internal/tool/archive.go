@@ -0,0 +1,340 @@
+package tool
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"math"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/afero"
+	"github.com/ulikunitz/xz"
+
+	"github.com/dennisklein/kdev/internal/util"
+)
+
+// defaultMaxExtractSize is the maximum uncompressed size of a binary extracted
+// from an archive unless the tool sets MaxExtractSize.
+const defaultMaxExtractSize int64 = 512 << 20
+
+// errUnsafeArchive is wrapped by the errors of archives that are rejected for
+// entries escaping the archive, links or oversized binaries.
+var errUnsafeArchive = errors.New("unsafe archive")
+
+// ArchiveFormat is the packaging of a downloaded tool artifact.
+type ArchiveFormat string
+
+const (
+	// ArchiveAuto detects the format from the extension of the download URL.
+	ArchiveAuto ArchiveFormat = ""
+	// ArchivePlain is an uncompressed binary.
+	ArchivePlain ArchiveFormat = "plain"
+	// ArchiveTarGz is a gzip-compressed tar archive.
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+	// ArchiveTarXz is an xz-compressed tar archive.
+	ArchiveTarXz ArchiveFormat = "tar.xz"
+	// ArchiveZip is a zip archive.
+	ArchiveZip ArchiveFormat = "zip"
+)
+
+// detectArchiveFormat returns the archive format matching the extension of
+// url, or ArchivePlain for anything else.
+func detectArchiveFormat(url string) ArchiveFormat {
+	switch {
+	case strings.HasSuffix(url, ".tar.gz"), strings.HasSuffix(url, ".tgz"):
+		return ArchiveTarGz
+	case strings.HasSuffix(url, ".tar.xz"), strings.HasSuffix(url, ".txz"):
+		return ArchiveTarXz
+	case strings.HasSuffix(url, ".zip"):
+		return ArchiveZip
+	default:
+		return ArchivePlain
+	}
+}
+
+// matchArchiveMember reports whether the cleaned archive entry name matches pattern.
+// Patterns use path.Match syntax. A pattern without a slash matches the base
+// name of entries in any directory, e.g. "helm"; a pattern with a slash matches
+// the whole entry path, e.g. "linux-amd64/helm" or "*/bin/helm".
+func matchArchiveMember(pattern, name string) (bool, error) {
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	}
+
+	return path.Match(pattern, name)
+}
+
+// cleanArchivePath returns the cleaned path of an archive entry, rejecting
+// absolute paths and paths escaping the archive root via "..".
+func cleanArchivePath(name string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("%w: entry %q escapes the archive", errUnsafeArchive, name)
+	}
+
+	return cleaned, nil
+}
+
+// archiveEntry is an entry of an archive.
+type archiveEntry struct {
+	name    string
+	mode    iofs.FileMode // Type and permission bits; links are never regular
+	size    int64         // Uncompressed size as declared by the archive
+	content io.Reader
+}
+
+// archiveEntries iterates over the entries of an archive.
+type archiveEntries interface {
+	// next returns the next entry, or io.EOF after the last one.
+	next() (*archiveEntry, error)
+	io.Closer
+}
+
+// tarEntries iterates over a tar archive read from a decompressor.
+type tarEntries struct {
+	reader *tar.Reader
+	closer io.Closer // Closes the decompressor, if it needs closing
+}
+
+func (e *tarEntries) next() (*archiveEntry, error) {
+	header, err := e.reader.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	mode := header.FileInfo().Mode()
+	if header.Typeflag == tar.TypeLink {
+		// Hard links carry the mode of a regular file.
+		mode |= iofs.ModeIrregular
+	}
+
+	return &archiveEntry{name: header.Name, mode: mode, size: header.Size, content: e.reader}, nil
+}
+
+func (e *tarEntries) Close() error {
+	if e.closer == nil {
+		return nil
+	}
+
+	return e.closer.Close()
+}
+
+// zipEntries iterates over a zip archive, keeping the current entry open
+// until the next one is requested.
+type zipEntries struct {
+	files   []*zip.File
+	current io.ReadCloser
+}
+
+func (e *zipEntries) next() (*archiveEntry, error) {
+	if err := e.Close(); err != nil {
+		return nil, err
+	}
+
+	if len(e.files) == 0 {
+		return nil, io.EOF
+	}
+
+	file := e.files[0]
+	e.files = e.files[1:]
+
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	e.current = reader
+
+	size := int64(min(file.UncompressedSize64, math.MaxInt64)) //nolint:gosec // clamped to the int64 range
+
+	return &archiveEntry{name: file.Name, mode: file.Mode(), size: size, content: reader}, nil
+}
+
+func (e *zipEntries) Close() error {
+	if e.current == nil {
+		return nil
+	}
+
+	err := e.current.Close()
+	e.current = nil
+
+	return err
+}
+
+// openArchive returns an iterator over the entries of file in the given format.
+func openArchive(file afero.File, format ArchiveFormat) (archiveEntries, error) {
+	switch format {
+	case ArchiveTarGz:
+		gzr, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+
+		return &tarEntries{reader: tar.NewReader(gzr), closer: gzr}, nil
+	case ArchiveTarXz:
+		xzr, err := xz.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create xz reader: %w", err)
+		}
+
+		return &tarEntries{reader: tar.NewReader(xzr)}, nil
+	case ArchiveZip:
+		info, err := file.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat archive: %w", err)
+		}
+
+		zr, err := zip.NewReader(file, info.Size())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zip reader: %w", err)
+		}
+
+		return &zipEntries{files: zr.File}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %q", format)
+	}
+}
+
+// extractArchive extracts the first regular file of the archive at archivePath
+// that matches the member pattern to destPath, preserving its permissions.
+// Archives with entries escaping the archive root are rejected, as are matching
+// links and binaries larger than maxSize.
+func extractArchive(fs afero.Fs, format ArchiveFormat, archivePath, destPath, member string, maxSize int64) (err error) {
+	if _, err := path.Match(member, ""); err != nil {
+		return fmt.Errorf("invalid archive member pattern %q: %w", member, err)
+	}
+
+	archiveFile, err := fs.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	defer func() {
+		closeErr := archiveFile.Close()
+		if err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to close archive file: %w", closeErr)
+		}
+	}()
+
+	entries, err := openArchive(archiveFile, format)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		closeErr := entries.Close()
+		if err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to close %s archive: %w", format, closeErr)
+		}
+	}()
+
+	for {
+		entry, err := entries.next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read %s archive: %w", format, err)
+		}
+
+		name, err := cleanArchivePath(entry.name)
+		if err != nil {
+			return err
+		}
+
+		matched, err := matchArchiveMember(member, name)
+		if err != nil || !matched || entry.mode.IsDir() {
+			continue
+		}
+
+		if !entry.mode.IsRegular() {
+			return fmt.Errorf("%w: %s is not a regular file (%s)", errUnsafeArchive, name, entry.mode.Type())
+		}
+
+		return writeArchiveMember(fs, destPath, entry, maxSize)
+	}
+
+	return fmt.Errorf("binary %s not found in archive", member)
+}
+
+// writeArchiveMember copies the content of an archive entry to destPath,
+// failing once more than maxSize bytes have been written.
+func writeArchiveMember(fs afero.Fs, destPath string, entry *archiveEntry, maxSize int64) error {
+	if entry.size > maxSize {
+		return errArchiveMemberSize(entry.name, maxSize)
+	}
+
+	perm := entry.mode.Perm()
+	if perm == 0 {
+		perm = 0o644
+	}
+
+	out, err := fs.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	// Archives may declare a smaller size than their content, so read one byte
+	// past the limit to detect oversized entries.
+	written, err := io.Copy(out, io.LimitReader(entry.content, maxSize+1))
+	if err == nil && written > maxSize {
+		err = errArchiveMemberSize(entry.name, maxSize)
+	}
+
+	if err != nil {
+		_ = out.Close()         //nolint:errcheck // close on error path
+		_ = fs.Remove(destPath) //nolint:errcheck // cleanup on error path
+
+		return fmt.Errorf("failed to extract binary: %w", err)
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	// The umask may have masked the permissions on creation.
+	return fs.Chmod(destPath, perm)
+}
+
+// errArchiveMemberSize returns the error for an archive entry exceeding maxSize.
+func errArchiveMemberSize(name string, maxSize int64) error {
+	return fmt.Errorf("%w: %s exceeds the maximum size of %s", errUnsafeArchive, name, util.FormatBytes(maxSize))
+}
+
+// archiveFormat returns the configured archive format of the tool, detecting
+// it from the download URL by default.
+func (t *Tool) archiveFormat(url string) ArchiveFormat {
+	if t.ArchiveFormat != ArchiveAuto {
+		return t.ArchiveFormat
+	}
+
+	return detectArchiveFormat(url)
+}
+
+// archiveMember returns the pattern matching the binary inside archives of a
+// version for goos/goarch, defaulting to the tool name.
+func (t *Tool) archiveMember(version, goos, goarch string) string {
+	if t.ArchiveMember != nil {
+		if member := t.ArchiveMember(version, goos, goarch); member != "" {
+			return member
+		}
+	}
+
+	return t.Name
+}
+
+// maxExtractSize returns the maximum size of binaries extracted from archives.
+func (t *Tool) maxExtractSize() int64 {
+	if t.MaxExtractSize > 0 {
+		return t.MaxExtractSize
+	}
+
+	return defaultMaxExtractSize
+}
@@ -0,0 +1,419 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+const (
+	testArchivePath = "/tmp/archive"
+	testExtractPath = "/tmp/helm"
+)
+
+// testArchiveFiles are the entries of the test archives in order.
+var testArchiveFiles = []struct{ name, content string }{
+	{"linux-amd64/README.md", "readme"},
+	{"linux-amd64/helm", "helm binary"},
+	{"darwin-arm64/helm", "darwin binary"},
+}
+
+// writeTestTar writes testArchiveFiles as a tar archive to w.
+func writeTestTar(t *testing.T, w io.Writer) {
+	t.Helper()
+
+	tw := tar.NewWriter(w)
+
+	for _, file := range testArchiveFiles {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0o755, Size: int64(len(file.content))}))
+		_, err := tw.Write([]byte(file.content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+}
+
+// createTestTarGz returns a tar.gz archive of the given entries, whose content is their name.
+func createTestTarGz(t *testing.T, headers ...tar.Header) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	for _, header := range headers {
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(header.Name))
+		}
+
+		require.NoError(t, tw.WriteHeader(&header))
+
+		if header.Typeflag == tar.TypeReg {
+			_, err := tw.Write([]byte(header.Name))
+			require.NoError(t, err)
+		}
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+
+	return buf.Bytes()
+}
+
+// createTestArchive returns testArchiveFiles packed in the given format.
+func createTestArchive(t *testing.T, format ArchiveFormat) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	switch format {
+	case ArchiveTarGz:
+		gzw := gzip.NewWriter(&buf)
+		writeTestTar(t, gzw)
+		require.NoError(t, gzw.Close())
+	case ArchiveTarXz:
+		xzw, err := xz.NewWriter(&buf)
+		require.NoError(t, err)
+		writeTestTar(t, xzw)
+		require.NoError(t, xzw.Close())
+	case ArchiveZip:
+		zw := zip.NewWriter(&buf)
+
+		for _, file := range testArchiveFiles {
+			w, err := zw.Create(file.name)
+			require.NoError(t, err)
+			_, err = w.Write([]byte(file.content))
+			require.NoError(t, err)
+		}
+
+		require.NoError(t, zw.Close())
+	default:
+		t.Fatalf("unsupported test archive format %q", format)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDetectArchiveFormat(t *testing.T) {
+	tests := []struct {
+		url  string
+		want ArchiveFormat
+	}{
+		{"https://example.com/cilium-linux-amd64.tar.gz", ArchiveTarGz},
+		{"https://example.com/tool.tgz", ArchiveTarGz},
+		{"https://example.com/tool.tar.xz", ArchiveTarXz},
+		{"https://example.com/tool.txz", ArchiveTarXz},
+		{"https://example.com/tool-windows.zip", ArchiveZip},
+		{"https://dl.k8s.io/release/v1.31.0/bin/linux/amd64/kubectl", ArchivePlain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			assert.Equal(t, tt.want, detectArchiveFormat(tt.url))
+		})
+	}
+}
+
+func TestMatchArchiveMember(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"helm", "helm", true},
+		{"helm", "linux-amd64/helm", true},
+		{"helm", "helm.sig", false},
+		{"linux-amd64/helm", "linux-amd64/helm", true},
+		{"linux-amd64/helm", "darwin-arm64/helm", false},
+		{"*/helm", "linux-amd64/helm", true},
+		{"*/helm", "helm", false},
+		{"helm-*", "helm-v3.16.0-linux-amd64", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			matched, err := matchArchiveMember(tt.pattern, tt.name)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, matched)
+		})
+	}
+}
+
+func TestCleanArchivePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "helm", want: "helm"},
+		{name: "./linux-amd64/helm", want: "linux-amd64/helm"},
+		{name: "bin/../helm", want: "helm"},
+		{name: `linux-amd64\helm`, want: "linux-amd64/helm"},
+		{name: "../helm", wantErr: true},
+		{name: "bin/../../helm", wantErr: true},
+		{name: "/usr/bin/helm", wantErr: true},
+		{name: "..", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleaned, err := cleanArchivePath(tt.name)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errUnsafeArchive)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cleaned)
+		})
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	for _, format := range []ArchiveFormat{ArchiveTarGz, ArchiveTarXz, ArchiveZip} {
+		t.Run(string(format), func(t *testing.T) {
+			archive := createTestArchive(t, format)
+
+			tests := []struct {
+				member string
+				want   string
+			}{
+				{"helm", "helm binary"},
+				{"darwin-arm64/helm", "darwin binary"},
+				{"*/README.md", "readme"},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.member, func(t *testing.T) {
+					fs := afero.NewMemMapFs()
+					require.NoError(t, afero.WriteFile(fs, testArchivePath, archive, 0o644))
+
+					require.NoError(t, extractArchive(fs, format, testArchivePath, testExtractPath, tt.member, defaultMaxExtractSize))
+
+					data, err := afero.ReadFile(fs, testExtractPath)
+					require.NoError(t, err)
+					assert.Equal(t, tt.want, string(data))
+				})
+			}
+		})
+	}
+
+	t.Run("preserves permissions", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, testArchivePath, createTestTarGz(t, tar.Header{Name: "helm", Typeflag: tar.TypeReg, Mode: 0o750}), 0o644))
+
+		require.NoError(t, extractArchive(fs, ArchiveTarGz, testArchivePath, testExtractPath, "helm", defaultMaxExtractSize))
+
+		info, err := fs.Stat(testExtractPath)
+		require.NoError(t, err)
+		assert.Equal(t, 0o750, int(info.Mode().Perm()))
+	})
+
+	t.Run("skips directories matching the member", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		archive := createTestTarGz(t,
+			tar.Header{Name: "helm/", Typeflag: tar.TypeDir, Mode: 0o755},
+			tar.Header{Name: "helm/helm", Typeflag: tar.TypeReg, Mode: 0o755})
+		require.NoError(t, afero.WriteFile(fs, testArchivePath, archive, 0o644))
+
+		require.NoError(t, extractArchive(fs, ArchiveTarGz, testArchivePath, testExtractPath, "helm", defaultMaxExtractSize))
+
+		data, err := afero.ReadFile(fs, testExtractPath)
+		require.NoError(t, err)
+		assert.Equal(t, "helm/helm", string(data))
+	})
+
+	t.Run("rejects unsafe archives", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			headers []tar.Header
+			maxSize int64
+			wantErr string
+		}{
+			{
+				name:    "symlink",
+				headers: []tar.Header{{Name: "helm", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}},
+				wantErr: "helm is not a regular file",
+			},
+			{
+				name:    "hard link",
+				headers: []tar.Header{{Name: "README.md", Typeflag: tar.TypeReg}, {Name: "helm", Typeflag: tar.TypeLink, Linkname: "README.md"}},
+				wantErr: "helm is not a regular file",
+			},
+			{
+				name:    "parent directory",
+				headers: []tar.Header{{Name: "../../bin/helm", Typeflag: tar.TypeReg}},
+				wantErr: `entry "../../bin/helm" escapes the archive`,
+			},
+			{
+				name:    "absolute path",
+				headers: []tar.Header{{Name: "/usr/bin/other", Typeflag: tar.TypeReg}, {Name: "helm", Typeflag: tar.TypeReg}},
+				wantErr: `entry "/usr/bin/other" escapes the archive`,
+			},
+			{
+				name:    "oversized binary",
+				headers: []tar.Header{{Name: "helm", Typeflag: tar.TypeReg}},
+				maxSize: 2,
+				wantErr: "helm exceeds the maximum size of 2 B",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				fs := afero.NewMemMapFs()
+				require.NoError(t, afero.WriteFile(fs, testArchivePath, createTestTarGz(t, tt.headers...), 0o644))
+
+				maxSize := tt.maxSize
+				if maxSize == 0 {
+					maxSize = defaultMaxExtractSize
+				}
+
+				err := extractArchive(fs, ArchiveTarGz, testArchivePath, testExtractPath, "helm", maxSize)
+				require.ErrorIs(t, err, errUnsafeArchive)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				exists, err := afero.Exists(fs, testExtractPath)
+				require.NoError(t, err)
+				assert.False(t, exists)
+			})
+		}
+	})
+
+	t.Run("rejects content exceeding the declared size", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		entry := &archiveEntry{name: "helm", mode: 0o755, content: strings.NewReader("more than declared")}
+
+		err := writeArchiveMember(fs, testExtractPath, entry, 4)
+		require.ErrorIs(t, err, errUnsafeArchive)
+
+		exists, err := afero.Exists(fs, testExtractPath)
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("fails if the member is missing", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, testArchivePath, createTestArchive(t, ArchiveZip), 0o644))
+
+		err := extractArchive(fs, ArchiveZip, testArchivePath, testExtractPath, "kubectl", defaultMaxExtractSize)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "binary kubectl not found in archive")
+
+		exists, err := afero.Exists(fs, testExtractPath)
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("fails on invalid patterns", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, testArchivePath, createTestArchive(t, ArchiveZip), 0o644))
+
+		err := extractArchive(fs, ArchiveZip, testArchivePath, testExtractPath, "[", defaultMaxExtractSize)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid archive member pattern")
+	})
+
+	t.Run("fails on corrupt archives", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, testArchivePath, []byte("not an archive"), 0o644))
+
+		for _, format := range []ArchiveFormat{ArchiveTarGz, ArchiveTarXz, ArchiveZip} {
+			require.Error(t, extractArchive(fs, format, testArchivePath, testExtractPath, "helm", defaultMaxExtractSize), format)
+		}
+	})
+
+	t.Run("fails on unsupported formats", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, testArchivePath, []byte("data"), 0o644))
+
+		err := extractArchive(fs, "rar", testArchivePath, testExtractPath, "helm", defaultMaxExtractSize)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unsupported archive format: "rar"`)
+	})
+}
+
+func TestDownloadArchive(t *testing.T) {
+	archive := createTestArchive(t, ArchiveZip)
+	checksum := fmt.Sprintf("%x", sha256.Sum256(archive))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/checksum" {
+			_, _ = w.Write([]byte(checksum)) //nolint:errcheck // test helper
+
+			return
+		}
+
+		_, _ = w.Write(archive) //nolint:errcheck // test helper
+	}))
+	defer server.Close()
+
+	newTool := func(fs afero.Fs, format ArchiveFormat, downloadPath string) *Tool {
+		return &Tool{
+			Name: "helm",
+			Fs:   fs,
+			DownloadURL: func(version, goos, goarch string) string {
+				return server.URL + downloadPath
+			},
+			ChecksumURL: func(version, goos, goarch string) string {
+				return server.URL + "/checksum"
+			},
+			ArchiveFormat: format,
+			ArchiveMember: func(version, goos, goarch string) string {
+				return goos + "-" + goarch + "/helm"
+			},
+			Project: &ProjectConfig{},
+		}
+	}
+
+	t.Run("extracts the member for the platform", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		require.NoError(t, newTool(fs, ArchiveAuto, "/helm.zip").downloadPlatform(context.Background(), testExtractPath, testVersion, "darwin", "arm64"))
+
+		data, err := afero.ReadFile(fs, testExtractPath)
+		require.NoError(t, err)
+		assert.Equal(t, "darwin binary", string(data))
+
+		exists, err := afero.Exists(fs, testExtractPath+partialSuffix)
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("uses the configured format", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		require.NoError(t, newTool(fs, ArchiveZip, "/download").downloadPlatform(context.Background(), testExtractPath, testVersion, "linux", "amd64"))
+
+		data, err := afero.ReadFile(fs, testExtractPath)
+		require.NoError(t, err)
+		assert.Equal(t, "helm binary", string(data))
+	})
+
+	t.Run("surfaces unsafe archives", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		tool := newTool(fs, ArchiveAuto, "/helm.zip")
+		tool.MaxExtractSize = 4
+
+		err := tool.downloadPlatform(context.Background(), testExtractPath, testVersion, "linux", "amd64")
+		require.ErrorIs(t, err, errUnsafeArchive)
+		assert.Contains(t, err.Error(), "failed to extract archive")
+		assert.Contains(t, err.Error(), "linux-amd64/helm exceeds the maximum size of 4 B")
+	})
+}
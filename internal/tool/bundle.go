@@ -0,0 +1,506 @@
+package tool
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// bundleManifestName is the name of the manifest entry, the first entry of a bundle.
+	bundleManifestName = "manifest.json"
+
+	// bundleFormatVersion is the version of the bundle layout written by ExportBundle.
+	bundleFormatVersion = 1
+)
+
+// BundleManifest describes the artifacts contained in a tool bundle.
+type BundleManifest struct {
+	Version   int              `json:"version"`
+	Artifacts []BundleArtifact `json:"artifacts"`
+}
+
+// BundleArtifact is a tool binary for one platform contained in a bundle.
+type BundleArtifact struct {
+	Tool    string `json:"tool"`
+	Version string `json:"version"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	SHA256  string `json:"sha256"`
+	Size    int64  `json:"size"`
+}
+
+// Platform returns the os/arch pair of the artifact.
+func (a BundleArtifact) Platform() string {
+	return Platform(a.OS, a.Arch)
+}
+
+// path returns the location of the artifact inside the bundle.
+func (a BundleArtifact) path() string {
+	return path.Join(a.Tool, a.Version, a.OS+"-"+a.Arch, a.Tool)
+}
+
+// BundleItem selects versions of a tool to export. An empty Spec selects all
+// cached versions, anything else is resolved like a version in .kdev.yaml.
+type BundleItem struct {
+	Tool *Tool
+	Spec string
+}
+
+// BundleImport reports the outcome of importing a bundle.
+type BundleImport struct {
+	// Imported lists artifacts that were added to the cache.
+	Imported []BundleArtifact
+	// Cached lists artifacts whose version was already cached.
+	Cached []BundleArtifact
+	// Skipped lists artifacts for other platforms than the host.
+	Skipped []BundleArtifact
+}
+
+// bundleFile is an artifact staged for export.
+type bundleFile struct {
+	artifact BundleArtifact
+	fs       afero.Fs
+	path     string
+}
+
+// ExportBundle writes the selected tool versions for each platform to w as a
+// tar archive. Binaries for the host platform are taken from the cache, all
+// others are downloaded and verified first.
+func ExportBundle(ctx context.Context, w io.Writer, items []BundleItem, platforms []string) (*BundleManifest, error) {
+	var (
+		files    []bundleFile
+		cleanups []func()
+	)
+
+	// Downloaded binaries are kept until the bundle is written.
+	defer func() {
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+	}()
+
+	for _, item := range items {
+		versions, err := item.Tool.bundleVersions(ctx, item.Spec)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, version := range versions {
+			for _, platform := range platforms {
+				goos, goarch, err := splitPlatform(platform)
+				if err != nil {
+					return nil, err
+				}
+
+				file, cleanup, err := item.Tool.stageBundleFile(ctx, version, goos, goarch)
+				if cleanup != nil {
+					cleanups = append(cleanups, cleanup)
+				}
+
+				if err != nil {
+					return nil, fmt.Errorf("failed to prepare %s %s for %s: %w", item.Tool.Name, version, platform, err)
+				}
+
+				files = append(files, file)
+			}
+		}
+	}
+
+	manifest := &BundleManifest{Version: bundleFormatVersion, Artifacts: make([]BundleArtifact, 0, len(files))}
+	for _, file := range files {
+		manifest.Artifacts = append(manifest.Artifacts, file.artifact)
+	}
+
+	if err := writeBundle(w, manifest, files); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// bundleVersions returns the versions selected by spec for export.
+func (t *Tool) bundleVersions(ctx context.Context, spec string) ([]string, error) {
+	if spec != "" {
+		version, err := t.resolveRequestedSpec(ctx, spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get version for %s: %w", t.Name, err)
+		}
+
+		return []string{version}, nil
+	}
+
+	cached, err := t.CachedVersions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached versions for %s: %w", t.Name, err)
+	}
+
+	versions := make([]string, 0, len(cached))
+	for _, v := range cached {
+		versions = append(versions, v.Version)
+	}
+
+	return versions, nil
+}
+
+// stageBundleFile locates the binary of version for goos/goarch, downloading
+// it to a temporary directory unless it is cached. The returned cleanup
+// function, if any, removes the temporary directory.
+func (t *Tool) stageBundleFile(ctx context.Context, version, goos, goarch string) (bundleFile, func(), error) {
+	fs := t.getFs()
+
+	dataDir, err := DataDir(fs)
+	if err != nil {
+		return bundleFile{}, nil, fmt.Errorf("failed to determine data directory: %w", err)
+	}
+
+	binPath := filepath.Join(dataDir, "kdev", t.Name, version, t.Name)
+
+	var cleanup func()
+
+	if goos != runtime.GOOS || goarch != runtime.GOARCH || !t.getFSHelper().Exists(binPath) {
+		tmpDir, err := afero.TempDir(fs, "", "kdev-bundle-")
+		if err != nil {
+			return bundleFile{}, nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+
+		cleanup = func() {
+			_ = fs.RemoveAll(tmpDir) //nolint:errcheck // best effort cleanup
+		}
+
+		binPath = filepath.Join(tmpDir, t.Name)
+
+		if err := t.writeProgress("Downloading %s %s for %s...\n", t.Name, version, Platform(goos, goarch)); err != nil {
+			return bundleFile{}, cleanup, fmt.Errorf("failed to write progress: %w", err)
+		}
+
+		if err := t.downloadPlatform(ctx, binPath, version, goos, goarch); err != nil {
+			return bundleFile{}, cleanup, fmt.Errorf("failed to download: %w", err)
+		}
+	}
+
+	checksum, size, err := fileSHA256(fs, binPath)
+	if err != nil {
+		return bundleFile{}, cleanup, err
+	}
+
+	artifact := BundleArtifact{Tool: t.Name, Version: version, OS: goos, Arch: goarch, SHA256: checksum, Size: size}
+
+	return bundleFile{artifact: artifact, fs: fs, path: binPath}, cleanup, nil
+}
+
+// writeBundle writes the manifest followed by all files as a tar archive.
+func writeBundle(w io.Writer, manifest *BundleManifest, files []bundleFile) error {
+	tw := tar.NewWriter(w)
+	modTime := time.Now()
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle manifest: %w", err)
+	}
+
+	header := &tar.Header{Name: bundleManifestName, Mode: 0o644, Size: int64(len(data)), ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	for _, file := range files {
+		if err := writeBundleFile(tw, file, modTime); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	return nil
+}
+
+// writeBundleFile adds a staged artifact to the bundle.
+func writeBundleFile(tw *tar.Writer, file bundleFile, modTime time.Time) (err error) {
+	in, err := file.fs.Open(file.path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file.path, err)
+	}
+
+	defer func() {
+		if closeErr := in.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	header := &tar.Header{Name: file.artifact.path(), Mode: 0o755, Size: file.artifact.Size, ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	if _, err := io.Copy(tw, in); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	return nil
+}
+
+// ImportBundle reads a bundle written by ExportBundle from r and adds the
+// artifacts for the host platform to the cache of the registered tools. Each
+// artifact is verified against the checksum recorded in the manifest and, if
+// kdev.lock has one for the host, against the locked checksum. Every artifact
+// listed in the manifest must be contained in the bundle.
+func ImportBundle(ctx context.Context, r io.Reader, registry *Registry) (*BundleImport, error) {
+	tr := tar.NewReader(r)
+
+	manifest, err := readBundleManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts := make(map[string]BundleArtifact, len(manifest.Artifacts))
+
+	for _, artifact := range manifest.Artifacts {
+		if err := validateBundleArtifact(artifact, registry); err != nil {
+			return nil, err
+		}
+
+		artifacts[artifact.path()] = artifact
+	}
+
+	result := &BundleImport{}
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return result, missingBundleArtifacts(manifest, artifacts)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		artifact, ok := artifacts[header.Name]
+		if !ok || header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("unexpected entry %q in bundle", header.Name)
+		}
+
+		// Entries are expected once, so a duplicate is unexpected as well.
+		delete(artifacts, header.Name)
+
+		if artifact.OS != runtime.GOOS || artifact.Arch != runtime.GOARCH {
+			result.Skipped = append(result.Skipped, artifact)
+
+			continue
+		}
+
+		imported, err := registry.Get(artifact.Tool).importArtifact(ctx, tr, artifact)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import %s %s: %w", artifact.Tool, artifact.Version, err)
+		}
+
+		if imported {
+			result.Imported = append(result.Imported, artifact)
+		} else {
+			result.Cached = append(result.Cached, artifact)
+		}
+	}
+}
+
+// missingBundleArtifacts returns an error listing the artifacts of the manifest
+// that are left in remaining, i.e. were not found in the bundle.
+func missingBundleArtifacts(manifest *BundleManifest, remaining map[string]BundleArtifact) error {
+	var errs []error
+
+	for _, artifact := range manifest.Artifacts {
+		if _, ok := remaining[artifact.path()]; ok {
+			errs = append(errs, fmt.Errorf("%s %s for %s is missing from bundle", artifact.Tool, artifact.Version, artifact.Platform()))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// readBundleManifest reads the manifest, which must be the first bundle entry.
+func readBundleManifest(tr *tar.Reader) (*BundleManifest, error) {
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	if header.Name != bundleManifestName {
+		return nil, fmt.Errorf("invalid bundle: expected %s as first entry, got %q", bundleManifestName, header.Name)
+	}
+
+	var manifest BundleManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle manifest: %w", err)
+	}
+
+	if manifest.Version != bundleFormatVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", manifest.Version)
+	}
+
+	return &manifest, nil
+}
+
+// validateBundleArtifact checks that an artifact refers to a registered tool
+// and that its version can safely be used as a cache directory name.
+func validateBundleArtifact(artifact BundleArtifact, registry *Registry) error {
+	if registry.Get(artifact.Tool) == nil {
+		return fmt.Errorf("unknown tool in bundle: %s", artifact.Tool)
+	}
+
+	if err := ValidateVersion(artifact.Version); err != nil {
+		return fmt.Errorf("invalid %s in bundle: %w", artifact.Tool, err)
+	}
+
+	return nil
+}
+
+// importArtifact copies the binary read from r into the cache unless the
+// version is already cached. It reports whether the binary was imported.
+func (t *Tool) importArtifact(ctx context.Context, r io.Reader, artifact BundleArtifact) (bool, error) {
+	fs := t.getFs()
+
+	dataDir, err := DataDir(fs)
+	if err != nil {
+		return false, fmt.Errorf("failed to determine data directory: %w", err)
+	}
+
+	binPath := filepath.Join(dataDir, "kdev", t.Name, artifact.Version, t.Name)
+
+	lock, err := t.lockCachedVersion(ctx, artifact.Version)
+	if err != nil {
+		return false, err
+	}
+
+	defer lock.Unlock() //nolint:errcheck // nothing to recover after the import
+
+	if t.getFSHelper().Exists(binPath) {
+		return false, nil
+	}
+
+	if err := fs.MkdirAll(filepath.Dir(binPath), 0o755); err != nil {
+		return false, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	partialPath := binPath + partialSuffix
+
+	checksum, err := writeHashed(fs, partialPath, r)
+	if err != nil {
+		_ = fs.Remove(partialPath) //nolint:errcheck // cleanup on error path
+
+		return false, err
+	}
+
+	if checksum != artifact.SHA256 {
+		_ = fs.Remove(partialPath) //nolint:errcheck // cleanup on error path
+
+		return false, fmt.Errorf("checksum mismatch: expected %s, got %s", artifact.SHA256, checksum)
+	}
+
+	if err := t.checkLockedChecksum(partialPath, artifact.Version); err != nil {
+		_ = fs.Remove(partialPath) //nolint:errcheck // cleanup on error path
+
+		return false, err
+	}
+
+	if err := fs.Chmod(partialPath, 0o755); err != nil {
+		return false, fmt.Errorf("failed to make executable: %w", err)
+	}
+
+	if err := fs.Rename(partialPath, binPath); err != nil {
+		_ = fs.Remove(partialPath) //nolint:errcheck // cleanup on error path
+
+		return false, err
+	}
+
+	if err := recordDownload(fs, binPath, VersionMetadata{Imported: true}); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// checkLockedChecksum checks the binary of version at binPath against the
+// checksum in kdev.lock for the host, if any. A bundle manifest is written by
+// whoever made the bundle, the lockfile is what the project agreed on.
+func (t *Tool) checkLockedChecksum(binPath, version string) error {
+	expected, err := t.lockedChecksum(version)
+	if err != nil || expected == "" {
+		return err
+	}
+
+	checksum, _, err := fileChecksum(t.getFs(), binPath, newChecksumHash(expected))
+	if err != nil {
+		return err
+	}
+
+	if checksum != expected {
+		return fmt.Errorf("checksum mismatch with %s: expected %s, got %s", LockFileName, expected, checksum)
+	}
+
+	return nil
+}
+
+// writeHashed writes r to name and returns the sha256 of the content.
+func writeHashed(fs afero.Fs, name string, r io.Reader) (string, error) {
+	out, err := fs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return "", err
+	}
+
+	hasher := sha256.New()
+
+	if _, err := io.Copy(io.MultiWriter(out, hasher), r); err != nil {
+		_ = out.Close() //nolint:errcheck // close on error path
+
+		return "", err
+	}
+
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
+
+// fileSHA256 returns the sha256 and size of the file name.
+func fileSHA256(fs afero.Fs, name string) (string, int64, error) {
+	return fileChecksum(fs, name, sha256.New())
+}
+
+// fileChecksum returns the checksum of the file computed with hasher and its size.
+func fileChecksum(fs afero.Fs, name string, hasher hash.Hash) (checksum string, size int64, err error) {
+	in, err := fs.Open(name)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+
+	defer func() {
+		if closeErr := in.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	size, err = io.Copy(hasher, in)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	return fmt.Sprintf("%x", hasher.Sum(nil)), size, nil
+}
@@ -0,0 +1,330 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDataDir = "/data"
+
+// otherPlatform returns a platform different from the host.
+func otherPlatform() (string, string) {
+	if runtime.GOOS == "darwin" {
+		return "linux", runtime.GOARCH
+	}
+
+	return "darwin", runtime.GOARCH
+}
+
+// newBundleTestTool returns a tool whose binaries for other platforms are
+// served by a test server with their checksums.
+func newBundleTestTool(t *testing.T, fs afero.Fs) *Tool {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, isChecksum := strings.CutSuffix(filepath.Base(r.URL.Path), ".sha256")
+		content := []byte("binary for " + name)
+
+		if isChecksum {
+			_, _ = fmt.Fprintf(w, "%x", sha256.Sum256(content)) //nolint:errcheck // test helper
+
+			return
+		}
+
+		_, _ = w.Write(content) //nolint:errcheck // test helper
+	}))
+	t.Cleanup(server.Close)
+
+	return &Tool{
+		Name: "testtool",
+		Fs:   fs,
+		DownloadURL: func(version, goos, goarch string) string {
+			return fmt.Sprintf("%s/%s/%s-%s", server.URL, version, goos, goarch)
+		},
+		ChecksumURL: func(version, goos, goarch string) string {
+			return fmt.Sprintf("%s/%s/%s-%s.sha256", server.URL, version, goos, goarch)
+		},
+		Project: &ProjectConfig{},
+	}
+}
+
+func cachedTestBinary(version string) string {
+	return filepath.Join(testDataDir, "kdev", "testtool", version, "testtool")
+}
+
+// exportTestBundle exports a cached version of testtool for the host and another platform.
+func exportTestBundle(t *testing.T) (*bytes.Buffer, *BundleManifest) {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, cachedTestBinary(testVersion), []byte("cached binary"), 0o755))
+
+	goos, goarch := otherPlatform()
+
+	var bundle bytes.Buffer
+
+	manifest, err := ExportBundle(context.Background(), &bundle, []BundleItem{{Tool: newBundleTestTool(t, fs)}},
+		[]string{Platform(runtime.GOOS, runtime.GOARCH), Platform(goos, goarch)})
+	require.NoError(t, err)
+
+	return &bundle, manifest
+}
+
+// readTestBundle returns the entries of a bundle keyed by name.
+func readTestBundle(t *testing.T, bundle []byte) map[string][]byte {
+	t.Helper()
+
+	entries := map[string][]byte{}
+	tr := tar.NewReader(bytes.NewReader(bundle))
+
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+
+		var data bytes.Buffer
+		_, err = data.ReadFrom(tr)
+		require.NoError(t, err)
+
+		entries[header.Name] = data.Bytes()
+	}
+
+	return entries
+}
+
+// writeTestBundle writes a bundle from a manifest and file contents keyed by artifact path.
+func writeTestBundle(t *testing.T, manifest BundleManifest, files map[string][]byte) *bytes.Buffer {
+	t.Helper()
+
+	var bundle bytes.Buffer
+
+	tw := tar.NewWriter(&bundle)
+
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: bundleManifestName, Mode: 0o644, Size: int64(len(data))}))
+	_, err = tw.Write(data)
+	require.NoError(t, err)
+
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content))}))
+		_, err = tw.Write(content)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+
+	return &bundle
+}
+
+func TestExportBundle(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+
+	t.Run("packs cached and downloaded binaries with checksums", func(t *testing.T) {
+		bundle, manifest := exportTestBundle(t)
+		goos, goarch := otherPlatform()
+
+		require.Len(t, manifest.Artifacts, 2)
+		assert.Equal(t, BundleArtifact{
+			Tool: "testtool", Version: testVersion, OS: runtime.GOOS, Arch: runtime.GOARCH,
+			SHA256: fmt.Sprintf("%x", sha256.Sum256([]byte("cached binary"))), Size: int64(len("cached binary")),
+		}, manifest.Artifacts[0])
+
+		downloaded := []byte("binary for " + goos + "-" + goarch)
+		assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256(downloaded)), manifest.Artifacts[1].SHA256)
+
+		entries := readTestBundle(t, bundle.Bytes())
+		assert.Contains(t, entries, bundleManifestName)
+		assert.Equal(t, []byte("cached binary"), entries[manifest.Artifacts[0].path()])
+		assert.Equal(t, downloaded, entries[manifest.Artifacts[1].path()])
+	})
+
+	t.Run("downloads requested versions that are not cached", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		var bundle bytes.Buffer
+
+		manifest, err := ExportBundle(context.Background(), &bundle, []BundleItem{{Tool: newBundleTestTool(t, fs), Spec: "v2.0.0"}},
+			[]string{Platform(runtime.GOOS, runtime.GOARCH)})
+		require.NoError(t, err)
+		require.Len(t, manifest.Artifacts, 1)
+		assert.Equal(t, "v2.0.0", manifest.Artifacts[0].Version)
+
+		// Temporary downloads must not end up in the cache.
+		versions, err := newBundleTestTool(t, fs).CachedVersions()
+		require.NoError(t, err)
+		assert.Empty(t, versions)
+	})
+
+	t.Run("rejects invalid platforms", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, cachedTestBinary(testVersion), []byte("cached binary"), 0o755))
+
+		_, err := ExportBundle(context.Background(), &bytes.Buffer{}, []BundleItem{{Tool: newBundleTestTool(t, fs)}}, []string{"linux"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid platform")
+	})
+}
+
+func TestImportBundle(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+
+	newRegistry := func(fs afero.Fs) *Registry {
+		return &Registry{tools: map[string]*Tool{"testtool": {Name: "testtool", Fs: fs, LockFile: &LockFile{}}}}
+	}
+
+	t.Run("imports binaries for the host platform", func(t *testing.T) {
+		bundle, _ := exportTestBundle(t)
+		fs := afero.NewMemMapFs()
+
+		result, err := ImportBundle(context.Background(), bundle, newRegistry(fs))
+		require.NoError(t, err)
+		assert.Len(t, result.Imported, 1)
+		assert.Len(t, result.Skipped, 1)
+		assert.Empty(t, result.Cached)
+
+		data, err := afero.ReadFile(fs, cachedTestBinary(testVersion))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("cached binary"), data)
+
+		info, err := fs.Stat(cachedTestBinary(testVersion))
+		require.NoError(t, err)
+		assert.Equal(t, 0o755, int(info.Mode().Perm()))
+	})
+
+	t.Run("keeps cached versions", func(t *testing.T) {
+		bundle, _ := exportTestBundle(t)
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, cachedTestBinary(testVersion), []byte("existing"), 0o755))
+
+		result, err := ImportBundle(context.Background(), bundle, newRegistry(fs))
+		require.NoError(t, err)
+		assert.Empty(t, result.Imported)
+		assert.Len(t, result.Cached, 1)
+
+		data, err := afero.ReadFile(fs, cachedTestBinary(testVersion))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("existing"), data)
+	})
+
+	t.Run("rejects tampered binaries", func(t *testing.T) {
+		artifact := BundleArtifact{Tool: "testtool", Version: testVersion, OS: runtime.GOOS, Arch: runtime.GOARCH, SHA256: "deadbeef"}
+		bundle := writeTestBundle(t, BundleManifest{Version: bundleFormatVersion, Artifacts: []BundleArtifact{artifact}},
+			map[string][]byte{artifact.path(): []byte("tampered")})
+		fs := afero.NewMemMapFs()
+
+		_, err := ImportBundle(context.Background(), bundle, newRegistry(fs))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "checksum mismatch")
+
+		exists, err := afero.Exists(fs, cachedTestBinary(testVersion))
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		exists, err = afero.Exists(fs, cachedTestBinary(testVersion)+partialSuffix)
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("rejects binaries not matching kdev.lock", func(t *testing.T) {
+		bundle, _ := exportTestBundle(t)
+		fs := afero.NewMemMapFs()
+		registry := newRegistry(fs)
+		registry.Get("testtool").LockFile = testLockFile([]byte("locked binary"))
+
+		_, err := ImportBundle(context.Background(), bundle, registry)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "checksum mismatch with "+LockFileName)
+
+		exists, err := afero.Exists(fs, cachedTestBinary(testVersion))
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("accepts binaries matching kdev.lock", func(t *testing.T) {
+		bundle, _ := exportTestBundle(t)
+		registry := newRegistry(afero.NewMemMapFs())
+		registry.Get("testtool").LockFile = testLockFile([]byte("cached binary"))
+
+		result, err := ImportBundle(context.Background(), bundle, registry)
+		require.NoError(t, err)
+		assert.Len(t, result.Imported, 1)
+	})
+
+	t.Run("rejects invalid manifests", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			manifest BundleManifest
+			files    map[string][]byte
+			wantErr  string
+		}{
+			{
+				name:     "unsupported version",
+				manifest: BundleManifest{Version: 99},
+				wantErr:  "unsupported bundle version 99",
+			},
+			{
+				name:     "unknown tool",
+				manifest: BundleManifest{Version: bundleFormatVersion, Artifacts: []BundleArtifact{{Tool: "other", Version: testVersion}}},
+				wantErr:  "unknown tool in bundle: other",
+			},
+			{
+				name:     "version escaping the cache",
+				manifest: BundleManifest{Version: bundleFormatVersion, Artifacts: []BundleArtifact{{Tool: "testtool", Version: "../../etc"}}},
+				wantErr:  "invalid version",
+			},
+			{
+				name:     "entry missing from manifest",
+				manifest: BundleManifest{Version: bundleFormatVersion},
+				files:    map[string][]byte{"testtool/v1.0.0/linux-amd64/testtool": []byte("binary")},
+				wantErr:  "unexpected entry",
+			},
+			{
+				name: "entry missing from bundle",
+				manifest: BundleManifest{Version: bundleFormatVersion, Artifacts: []BundleArtifact{
+					{Tool: "testtool", Version: testVersion, OS: "linux", Arch: "amd64"},
+				}},
+				wantErr: "testtool v1.0.0 for linux/amd64 is missing from bundle",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				bundle := writeTestBundle(t, tt.manifest, tt.files)
+
+				_, err := ImportBundle(context.Background(), bundle, newRegistry(afero.NewMemMapFs()))
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			})
+		}
+	})
+
+	t.Run("requires the manifest first", func(t *testing.T) {
+		var bundle bytes.Buffer
+
+		tw := tar.NewWriter(&bundle)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "testtool", Mode: 0o755}))
+		require.NoError(t, tw.Close())
+
+		_, err := ImportBundle(context.Background(), &bundle, newRegistry(afero.NewMemMapFs()))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid bundle")
+	})
+}
@@ -2,9 +2,12 @@ package tool
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/spf13/afero"
@@ -12,9 +15,11 @@ import (
 
 // CachedVersion represents a cached version of a tool.
 type CachedVersion struct {
-	Version string
-	Path    string
-	Size    int64
+	Version  string
+	Path     string
+	Size     int64
+	Metadata *VersionMetadata // nil for versions cached before metadata was recorded
+	Blob     string           // sha256 of the blob shared with identical binaries, empty for plain files
 }
 
 // CachedVersions returns all cached versions of this tool.
@@ -55,10 +60,14 @@ func (t *Tool) CachedVersions() ([]CachedVersion, error) {
 			continue
 		}
 
+		metadata := readMetadata(fs, binPath)
+
 		versions = append(versions, CachedVersion{
-			Version: entry.Name(),
-			Path:    binPath,
-			Size:    info.Size(),
+			Version:  entry.Name(),
+			Path:     binPath,
+			Size:     info.Size(),
+			Metadata: metadata,
+			Blob:     storedBlob(fs, binPath, metadata),
 		})
 	}
 
@@ -92,13 +101,23 @@ func compareVersions(v1, v2 string) int {
 	return ver1.Compare(ver2)
 }
 
-// LatestVersion returns the latest available version from the upstream source.
+// LatestVersion returns the latest available version in the tool's release
+// channel from the upstream source.
 func (t *Tool) LatestVersion(ctx context.Context) (string, error) {
-	return t.VersionFunc(ctx)
+	channel, err := t.channel()
+	if err != nil {
+		return "", err
+	}
+
+	return t.channelVersion(ctx, channel)
 }
 
 // CleanVersion removes a specific cached version.
 func (t *Tool) CleanVersion(version string) error {
+	if err := ValidateVersion(version); err != nil {
+		return err
+	}
+
 	fs := t.getFs()
 	helper := t.getFSHelper()
 
@@ -109,15 +128,59 @@ func (t *Tool) CleanVersion(version string) error {
 
 	versionDir := filepath.Join(dataDir, "kdev", t.Name, version)
 
-	if !helper.IsDir(versionDir) {
+	lockPath, err := t.cacheLockPath(version)
+	if err != nil {
+		return err
+	}
+
+	// A lock file without version directory is left by a failed download.
+	if !helper.IsDir(versionDir) && !helper.Exists(lockPath) {
 		return nil
 	}
 
+	// Wait for processes downloading or about to execute this version.
+	lock, err := t.lockCachedVersion(context.Background(), version)
+	if err != nil {
+		return err
+	}
+
+	defer lock.Unlock() //nolint:errcheck // nothing to recover after the removal
+
 	if err := fs.RemoveAll(versionDir); err != nil {
 		return fmt.Errorf("failed to remove version directory: %w", err)
 	}
 
-	return nil
+	if err := lock.removeFile(); err != nil {
+		return err
+	}
+
+	return pruneBlobs(fs)
+}
+
+// removeIdleVersion removes a cached version unless another kdev process is
+// using it. It reports whether the version was removed.
+func (t *Tool) removeIdleVersion(version string) (bool, error) {
+	dataDir, err := DataDir(t.getFs())
+	if err != nil {
+		return false, fmt.Errorf("failed to get data directory: %w", err)
+	}
+
+	lock, acquired, err := t.tryLockCachedVersion(version)
+	if err != nil || !acquired {
+		return false, err
+	}
+
+	defer lock.Unlock() //nolint:errcheck // nothing to recover after the removal
+
+	if err := t.getFs().RemoveAll(filepath.Join(dataDir, "kdev", t.Name, version)); err != nil {
+		return false, fmt.Errorf("failed to remove version directory: %w", err)
+	}
+
+	if err := lock.removeFile(); err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
 
 // CleanAll removes all cached versions of this tool.
@@ -136,8 +199,28 @@ func (t *Tool) CleanAll() error {
 		return nil
 	}
 
-	if err := fs.RemoveAll(toolDir); err != nil {
-		return fmt.Errorf("failed to remove tool directory: %w", err)
+	entries, err := afero.ReadDir(fs, toolDir)
+	if err != nil {
+		return fmt.Errorf("failed to read tool directory: %w", err)
+	}
+
+	// Remove versions one by one, so that versions in use are waited for. The
+	// tool directory itself stays, since other processes may be about to
+	// create lock files in it.
+	for _, entry := range entries {
+		version, isLock := strings.CutSuffix(entry.Name(), cacheLockSuffix)
+
+		switch {
+		case entry.IsDir() || isLock:
+			// CleanVersion also removes lock files left by failed downloads.
+			if err := t.CleanVersion(version); err != nil {
+				return err
+			}
+		default:
+			if err := fs.Remove(filepath.Join(toolDir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove %s: %w", entry.Name(), err)
+			}
+		}
 	}
 
 	return nil
@@ -145,39 +228,76 @@ func (t *Tool) CleanAll() error {
 
 // Download pre-downloads the tool without executing it.
 func (t *Tool) Download(ctx context.Context) error {
-	fs := t.getFs()
-	helper := t.getFSHelper()
+	version, err := t.ResolveVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get version: %w", err)
+	}
 
-	dataDir, err := DataDir(fs)
+	return t.downloadVersion(ctx, version)
+}
+
+// Install downloads the version of the tool selected by spec, which may be an
+// exact version, a semver constraint or any other spec accepted in .kdev.yaml.
+// An empty spec installs the version Download would use. Returns the version.
+func (t *Tool) Install(ctx context.Context, spec string) (string, error) {
+	version, err := t.resolveRequestedSpec(ctx, spec)
 	if err != nil {
-		return fmt.Errorf("failed to determine data directory: %w", err)
+		return "", fmt.Errorf("failed to get version: %w", err)
+	}
+
+	if err := t.downloadVersion(ctx, version); err != nil {
+		return "", err
 	}
 
-	version, err := t.VersionFunc(ctx)
+	return version, nil
+}
+
+// downloadVersion downloads and verifies a specific version unless it is already cached.
+func (t *Tool) downloadVersion(ctx context.Context, version string) error {
+	dataDir, err := DataDir(t.getFs())
 	if err != nil {
-		return fmt.Errorf("failed to get version: %w", err)
+		return fmt.Errorf("failed to determine data directory: %w", err)
 	}
 
 	binPath := filepath.Join(dataDir, "kdev", t.Name, version, t.Name)
 
-	if helper.Exists(binPath) {
-		return nil
+	lock, err := t.lockCachedVersion(ctx, version)
+	if err != nil {
+		return err
 	}
 
-	if err := t.writeProgress("Downloading %s %s...\n", t.Name, version); err != nil {
-		return fmt.Errorf("failed to write progress: %w", err)
-	}
+	defer lock.Unlock() //nolint:errcheck // nothing to recover after the download
 
-	if err := t.download(ctx, binPath, version); err != nil {
-		return fmt.Errorf("failed to download: %w", err)
-	}
+	return t.ensureExecutable(ctx, binPath, version)
+}
 
-	if err := fs.Chmod(binPath, 0o755); err != nil {
-		return fmt.Errorf("failed to make executable: %w", err)
+// ensureExecutable downloads version to binPath unless it is already cached and
+// makes it executable. After a download, garbage is collected if enabled. The
+// caller must hold the lock on the cached version.
+func (t *Tool) ensureExecutable(ctx context.Context, binPath, version string) error {
+	if !t.getFSHelper().Exists(binPath) {
+		if err := t.writeProgress("Downloading %s %s...\n", t.Name, version); err != nil {
+			return fmt.Errorf("failed to write progress: %w", err)
+		}
+
+		if err := t.download(ctx, binPath, version); err != nil {
+			return fmt.Errorf("failed to download: %w", err)
+		}
+
+		if err := t.writeProgress("%s %s downloaded successfully\n", t.Name, version); err != nil {
+			return fmt.Errorf("failed to write progress: %w", err)
+		}
+
+		// The download succeeded, so a failed cleanup is only worth a warning.
+		if err := t.autoGC(version); err != nil {
+			if err := t.writeProgress("Warning: failed to clean up the tool cache: %v\n", err); err != nil {
+				return fmt.Errorf("failed to write progress: %w", err)
+			}
+		}
 	}
 
-	if err := t.writeProgress("%s %s downloaded successfully\n", t.Name, version); err != nil {
-		return fmt.Errorf("failed to write progress: %w", err)
+	if err := t.getFs().Chmod(binPath, 0o755); err != nil {
+		return fmt.Errorf("failed to make executable: %w", err)
 	}
 
 	return nil
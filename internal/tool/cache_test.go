@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -220,6 +221,32 @@ func TestCachedVersions(t *testing.T) {
 		assert.Equal(t, "v1.2.1", cached[3].Version, "v1.2.1 should be last (oldest)")
 	})
 
+	t.Run("sorts prereleases after their release, newest first", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testHome)
+
+		toolDir := filepath.Join(testHome, ".kdev", "kdev", "kubectl")
+
+		for _, v := range []string{"v1.31.0-rc.1", "v1.31.0", "v1.31.0-rc.2", "v1.30.4", "v1.31.0-alpha.3"} {
+			require.NoError(t, afero.WriteFile(fs, filepath.Join(toolDir, v, "kubectl"), []byte("binary"), 0o755))
+		}
+
+		tool := &Tool{
+			Name: "kubectl",
+			Fs:   fs,
+		}
+
+		cached, err := tool.CachedVersions()
+		require.NoError(t, err)
+
+		order := make([]string, 0, len(cached))
+		for _, cv := range cached {
+			order = append(order, cv.Version)
+		}
+
+		assert.Equal(t, []string{"v1.31.0", "v1.31.0-rc.2", "v1.31.0-rc.1", "v1.31.0-alpha.3", "v1.30.4"}, order)
+	})
+
 	t.Run("handles non-semver versions with fallback", func(t *testing.T) {
 		fs := afero.NewMemMapFs()
 		home := testHome
@@ -454,10 +481,12 @@ func TestCleanAll(t *testing.T) {
 		err := tool.CleanAll()
 		require.NoError(t, err)
 
-		// Verify tool directory is gone
-		exists, err := afero.DirExists(fs, toolDir)
-		require.NoError(t, err)
-		assert.False(t, exists)
+		// Verify all versions are gone
+		for _, v := range versions {
+			exists, err := afero.DirExists(fs, filepath.Join(toolDir, v))
+			require.NoError(t, err)
+			assert.False(t, exists, v)
+		}
 	})
 
 	t.Run("no error when tool directory does not exist", func(t *testing.T) {
@@ -562,6 +591,107 @@ func TestDownload(t *testing.T) {
 	})
 }
 
+func TestInstall(t *testing.T) {
+	content := []byte("installed binary")
+	checksum := fmt.Sprintf("%x", sha256.Sum256(content))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/checksum" {
+			_, _ = w.Write([]byte(checksum)) //nolint:errcheck // test helper
+
+			return
+		}
+
+		_, _ = w.Write(content) //nolint:errcheck // test helper
+	}))
+	defer server.Close()
+
+	newTool := func(fs afero.Fs, downloaded *[]string) *Tool {
+		return &Tool{
+			Name: "testtool",
+			Fs:   fs,
+			VersionFunc: func(ctx context.Context) (string, error) {
+				return testVersion, nil
+			},
+			ListVersions: func(ctx context.Context, _ *semver.Constraints) ([]string, error) {
+				return []string{"v0.21.0", "v0.22.0", "v0.22.1", "v0.23.0"}, nil
+			},
+			DownloadURL: func(version, goos, goarch string) string {
+				*downloaded = append(*downloaded, version)
+
+				return server.URL + "/binary"
+			},
+			ChecksumURL: func(version, goos, goarch string) string {
+				return server.URL + "/checksum"
+			},
+			Project: &ProjectConfig{},
+		}
+	}
+
+	t.Run("installs exact version", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testHome)
+
+		var downloaded []string
+
+		version, err := newTool(fs, &downloaded).Install(context.Background(), "v0.22.0")
+		require.NoError(t, err)
+		assert.Equal(t, "v0.22.0", version)
+		assert.Equal(t, []string{"v0.22.0"}, downloaded)
+
+		data, err := afero.ReadFile(fs, filepath.Join(testHome, ".kdev", "kdev", "testtool", "v0.22.0", "testtool"))
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+	})
+
+	t.Run("installs highest version matching constraint", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testHome)
+
+		var downloaded []string
+
+		version, err := newTool(fs, &downloaded).Install(context.Background(), "~0.22")
+		require.NoError(t, err)
+		assert.Equal(t, "v0.22.1", version)
+	})
+
+	t.Run("installs resolved version without spec", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testHome)
+
+		var downloaded []string
+
+		version, err := newTool(fs, &downloaded).Install(context.Background(), "")
+		require.NoError(t, err)
+		assert.Equal(t, testVersion, version)
+	})
+
+	t.Run("skips download when already cached", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testHome)
+
+		binPath := filepath.Join(testHome, ".kdev", "kdev", "testtool", "v0.22.0", "testtool")
+		require.NoError(t, afero.WriteFile(fs, binPath, []byte("existing binary"), 0o755))
+
+		var downloaded []string
+
+		_, err := newTool(fs, &downloaded).Install(context.Background(), "v0.22.0")
+		require.NoError(t, err)
+		assert.Empty(t, downloaded)
+	})
+
+	t.Run("fails when no release matches", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testHome)
+
+		var downloaded []string
+
+		_, err := newTool(fs, &downloaded).Install(context.Background(), "~0.30")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no testtool release matches")
+	})
+}
+
 func TestGetFs(t *testing.T) {
 	t.Run("returns set filesystem", func(t *testing.T) {
 		fs := afero.NewMemMapFs()
@@ -653,8 +783,8 @@ func TestCleanAllErrors(t *testing.T) {
 		dataDir := filepath.Join(home, ".kdev")
 		toolDir := filepath.Join(dataDir, "kdev", "kubectl")
 
-		// Create tool directory
-		err := fs.MkdirAll(toolDir, 0o755)
+		// Create a cached version
+		err := fs.MkdirAll(filepath.Join(toolDir, "v1.30.0"), 0o755)
 		require.NoError(t, err)
 
 		tool := &Tool{
@@ -664,7 +794,7 @@ func TestCleanAllErrors(t *testing.T) {
 
 		err = tool.CleanAll()
 		require.Error(t, err)
-		assert.Contains(t, err.Error(), "failed to remove tool directory")
+		assert.Contains(t, err.Error(), "failed to remove version directory")
 	})
 }
 
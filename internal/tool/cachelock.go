@@ -0,0 +1,209 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// cacheLockSuffix is appended to a version directory path for its lock file.
+	cacheLockSuffix = ".lock"
+
+	// cacheLockPollInterval is how often a busy lock is retried.
+	cacheLockPollInterval = 100 * time.Millisecond
+)
+
+// cacheLock is an exclusive lock on a cached version, shared across kdev
+// processes. The zero value holds no lock.
+type cacheLock struct {
+	file *os.File
+	path string
+}
+
+// Unlock releases the lock. It is safe to call on a nil lock.
+func (l *cacheLock) Unlock() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	// Closing the file releases the lock.
+	err := l.file.Close()
+	l.file = nil
+
+	return err
+}
+
+// removeFile removes the lock file while the lock is still held, so that no
+// lock file stays behind for removed versions. Processes waiting on the
+// removed file notice that it is gone once they acquire it and retry.
+func (l *cacheLock) removeFile() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	if err := os.Remove(l.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove lock file: %w", err)
+	}
+
+	return nil
+}
+
+// lockCachedVersion acquires an exclusive lock on the cached version, waiting
+// for other kdev processes that download, execute or remove it. The lock file
+// lives next to the version directory and is removed with the version.
+// Locking is only done on the OS filesystem.
+func (t *Tool) lockCachedVersion(ctx context.Context, version string) (*cacheLock, error) {
+	if _, ok := t.getFs().(*afero.OsFs); !ok {
+		return &cacheLock{}, nil
+	}
+
+	for {
+		file, path, err := t.openCacheLockFile(version)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := waitForFlock(ctx, file, func() error {
+			return t.writeProgress("Waiting for another kdev process using %s %s...\n", t.Name, version)
+		}); err != nil {
+			_ = file.Close() //nolint:errcheck // close on error path
+
+			return nil, fmt.Errorf("failed to lock %s %s: %w", t.Name, version, err)
+		}
+
+		if lock, current, err := acquiredCacheLock(file, path); current || err != nil {
+			return lock, err
+		}
+	}
+}
+
+// tryLockCachedVersion works like lockCachedVersion, but does not wait. It
+// reports whether the lock was acquired, false if another process holds it.
+func (t *Tool) tryLockCachedVersion(version string) (*cacheLock, bool, error) {
+	if _, ok := t.getFs().(*afero.OsFs); !ok {
+		return &cacheLock{}, true, nil
+	}
+
+	for {
+		file, path, err := t.openCacheLockFile(version)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			_ = file.Close() //nolint:errcheck // close on error path
+
+			if errors.Is(err, syscall.EWOULDBLOCK) {
+				return nil, false, nil
+			}
+
+			return nil, false, fmt.Errorf("failed to lock %s %s: %w", t.Name, version, err)
+		}
+
+		lock, current, err := acquiredCacheLock(file, path)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if current {
+			return lock, true, nil
+		}
+	}
+}
+
+// acquiredCacheLock returns the lock for a flocked file if it is still the
+// lock file at path. It reports false if the previous holder removed the
+// file, in which case the lock must be retried on a newly opened file.
+func acquiredCacheLock(file *os.File, path string) (*cacheLock, bool, error) {
+	held, err := file.Stat()
+	if err != nil {
+		_ = file.Close() //nolint:errcheck // close on error path
+
+		return nil, false, fmt.Errorf("failed to stat lock file: %w", err)
+	}
+
+	current, err := os.Stat(path)
+	if err == nil && os.SameFile(held, current) {
+		return &cacheLock{file: file, path: path}, true, nil
+	}
+
+	_ = file.Close() //nolint:errcheck // the file is no longer used for locking
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, false, fmt.Errorf("failed to stat lock file: %w", err)
+	}
+
+	return nil, false, nil
+}
+
+// cacheLockPath returns the path of the lock file of a cached version.
+func (t *Tool) cacheLockPath(version string) (string, error) {
+	if err := ValidateVersion(version); err != nil {
+		return "", err
+	}
+
+	dataDir, err := DataDir(t.getFs())
+	if err != nil {
+		return "", fmt.Errorf("failed to determine data directory: %w", err)
+	}
+
+	return filepath.Join(dataDir, "kdev", t.Name, version+cacheLockSuffix), nil
+}
+
+// openCacheLockFile opens the lock file of a cached version on the OS
+// filesystem and returns it with its path.
+func (t *Tool) openCacheLockFile(version string) (*os.File, string, error) {
+	path, err := t.cacheLockPath(version)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, "", fmt.Errorf("failed to create tool directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	return file, path, nil
+}
+
+// waitForFlock takes an exclusive flock on file, polling until it is free or
+// ctx is done. onWait is called once if the lock is busy.
+func waitForFlock(ctx context.Context, file *os.File, onWait func() error) error {
+	waiting := false
+
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			return err
+		}
+
+		if !waiting {
+			waiting = true
+
+			if err := onWait(); err != nil {
+				return fmt.Errorf("failed to write progress: %w", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(cacheLockPollInterval):
+		}
+	}
+}
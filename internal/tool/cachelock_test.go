@@ -0,0 +1,245 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCacheLockTestTool(t *testing.T, progress io.Writer) *Tool {
+	t.Helper()
+
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	return &Tool{Name: "testtool", Fs: afero.NewOsFs(), ProgressWriter: progress}
+}
+
+func TestLockCachedVersion(t *testing.T) {
+	t.Run("waits for the lock holder", func(t *testing.T) {
+		var progress bytes.Buffer
+
+		tool := newCacheLockTestTool(t, &progress)
+
+		held, err := tool.lockCachedVersion(context.Background(), testVersion)
+		require.NoError(t, err)
+
+		acquired := make(chan *cacheLock)
+
+		go func() {
+			lock, err := tool.lockCachedVersion(context.Background(), testVersion)
+			assert.NoError(t, err)
+
+			acquired <- lock
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("lock acquired while held by another owner")
+		case <-time.After(3 * cacheLockPollInterval):
+		}
+
+		require.NoError(t, held.Unlock())
+
+		lock := <-acquired
+		require.NoError(t, lock.Unlock())
+		assert.Contains(t, progress.String(), "Waiting for another kdev process using testtool "+testVersion)
+	})
+
+	t.Run("does not block other versions", func(t *testing.T) {
+		tool := newCacheLockTestTool(t, nil)
+
+		held, err := tool.lockCachedVersion(context.Background(), testVersion)
+		require.NoError(t, err)
+
+		defer held.Unlock() //nolint:errcheck // test cleanup
+
+		lock, err := tool.lockCachedVersion(context.Background(), "v2.0.0")
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock())
+	})
+
+	t.Run("gives up when the context is done", func(t *testing.T) {
+		tool := newCacheLockTestTool(t, nil)
+
+		held, err := tool.lockCachedVersion(context.Background(), testVersion)
+		require.NoError(t, err)
+
+		defer held.Unlock() //nolint:errcheck // test cleanup
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*cacheLockPollInterval)
+		defer cancel()
+
+		_, err = tool.lockCachedVersion(ctx, testVersion)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("keeps the lock file outside the version directory", func(t *testing.T) {
+		tool := newCacheLockTestTool(t, nil)
+
+		lock, err := tool.lockCachedVersion(context.Background(), testVersion)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock())
+
+		dataDir, err := DataDir(tool.getFs())
+		require.NoError(t, err)
+
+		_, err = os.Stat(filepath.Join(dataDir, "kdev", "testtool", testVersion+cacheLockSuffix))
+		require.NoError(t, err)
+
+		versions, err := tool.CachedVersions()
+		require.NoError(t, err)
+		assert.Empty(t, versions)
+	})
+
+	t.Run("retries on the new lock file after the holder removed it", func(t *testing.T) {
+		tool := newCacheLockTestTool(t, nil)
+
+		held, err := tool.lockCachedVersion(context.Background(), testVersion)
+		require.NoError(t, err)
+
+		acquired := make(chan *cacheLock)
+
+		go func() {
+			lock, err := tool.lockCachedVersion(context.Background(), testVersion)
+			assert.NoError(t, err)
+
+			acquired <- lock
+		}()
+
+		time.Sleep(2 * cacheLockPollInterval)
+		require.NoError(t, held.removeFile())
+		require.NoError(t, held.Unlock())
+
+		lock := <-acquired
+
+		defer lock.Unlock() //nolint:errcheck // test cleanup
+
+		lockInfo, err := lock.file.Stat()
+		require.NoError(t, err)
+
+		pathInfo, err := os.Stat(lock.path)
+		require.NoError(t, err)
+		assert.True(t, os.SameFile(lockInfo, pathInfo), "lock must be held on the file at the lock path")
+
+		_, acquiredAgain, err := tool.tryLockCachedVersion(testVersion)
+		require.NoError(t, err)
+		assert.False(t, acquiredAgain, "new lock file must be exclusive")
+	})
+
+	t.Run("is a no-op on other filesystems", func(t *testing.T) {
+		tool := &Tool{Name: "testtool", Fs: afero.NewMemMapFs()}
+
+		first, err := tool.lockCachedVersion(context.Background(), testVersion)
+		require.NoError(t, err)
+
+		second, err := tool.lockCachedVersion(context.Background(), testVersion)
+		require.NoError(t, err)
+
+		require.NoError(t, first.Unlock())
+		require.NoError(t, second.Unlock())
+	})
+}
+
+func TestCleanVersionWaitsForLock(t *testing.T) {
+	tool := newCacheLockTestTool(t, nil)
+
+	dataDir, err := DataDir(tool.getFs())
+	require.NoError(t, err)
+
+	binPath := filepath.Join(dataDir, "kdev", "testtool", testVersion, "testtool")
+	require.NoError(t, os.MkdirAll(filepath.Dir(binPath), 0o755))
+	require.NoError(t, os.WriteFile(binPath, []byte("binary"), 0o755))
+
+	held, err := tool.lockCachedVersion(context.Background(), testVersion)
+	require.NoError(t, err)
+
+	cleaned := make(chan error)
+
+	go func() {
+		cleaned <- tool.CleanVersion(testVersion)
+	}()
+
+	select {
+	case <-cleaned:
+		t.Fatal("version removed while locked")
+	case <-time.After(3 * cacheLockPollInterval):
+	}
+
+	_, err = os.Stat(binPath)
+	require.NoError(t, err)
+
+	require.NoError(t, held.Unlock())
+	require.NoError(t, <-cleaned)
+
+	_, err = os.Stat(binPath)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = os.Stat(filepath.Join(dataDir, "kdev", "testtool", testVersion+cacheLockSuffix))
+	assert.True(t, os.IsNotExist(err), "lock file should be removed with the version")
+}
+
+func TestCleanAllKeepsToolDirectory(t *testing.T) {
+	tool := newCacheLockTestTool(t, nil)
+
+	dataDir, err := DataDir(tool.getFs())
+	require.NoError(t, err)
+
+	toolDir := filepath.Join(dataDir, "kdev", "testtool")
+	binPath := filepath.Join(toolDir, testVersion, "testtool")
+	require.NoError(t, os.MkdirAll(filepath.Dir(binPath), 0o755))
+	require.NoError(t, os.WriteFile(binPath, []byte("binary"), 0o755))
+
+	// A failed download leaves a lock file without version directory.
+	stale, err := tool.lockCachedVersion(context.Background(), "v2.0.0")
+	require.NoError(t, err)
+	require.NoError(t, stale.Unlock())
+
+	require.NoError(t, tool.CleanAll())
+
+	entries, err := os.ReadDir(toolDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRemoveIdleVersion(t *testing.T) {
+	tool := newCacheLockTestTool(t, nil)
+
+	dataDir, err := DataDir(tool.getFs())
+	require.NoError(t, err)
+
+	binPath := filepath.Join(dataDir, "kdev", "testtool", testVersion, "testtool")
+	require.NoError(t, os.MkdirAll(filepath.Dir(binPath), 0o755))
+	require.NoError(t, os.WriteFile(binPath, []byte("binary"), 0o755))
+
+	held, err := tool.lockCachedVersion(context.Background(), testVersion)
+	require.NoError(t, err)
+
+	removed, err := tool.removeIdleVersion(testVersion)
+	require.NoError(t, err)
+	assert.False(t, removed, "locked version should be skipped")
+
+	_, err = os.Stat(binPath)
+	require.NoError(t, err)
+
+	require.NoError(t, held.Unlock())
+
+	removed, err = tool.removeIdleVersion(testVersion)
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	_, err = os.Stat(binPath)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = os.Stat(filepath.Join(dataDir, "kdev", "testtool", testVersion+cacheLockSuffix))
+	assert.True(t, os.IsNotExist(err), "lock file should be removed with the version")
+}
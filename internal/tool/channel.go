@@ -0,0 +1,168 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+const (
+	// StableChannel follows the newest stable release (the default).
+	StableChannel = "stable"
+
+	// LatestChannel follows the newest release including prereleases.
+	LatestChannel = "latest"
+
+	// channelQueryPrefix prefixes version cache keys of lookups in a non-default channel.
+	channelQueryPrefix = "channel:"
+)
+
+// Channel selects which upstream releases a tool follows. Channels are named
+// like the Kubernetes release markers: "stable", "latest" (including
+// prereleases), optionally restricted to a minor version as in "latest-1.31".
+type Channel struct {
+	// Prerelease includes prereleases such as release candidates.
+	Prerelease bool
+	// Minor restricts releases to a major.minor version such as "1.31" (empty for any).
+	Minor string
+}
+
+// ParseChannel parses a channel name. An empty name is the stable channel.
+func ParseChannel(name string) (Channel, error) {
+	invalid := fmt.Errorf("invalid channel %q, expected %s or %s with optional -<major>.<minor>", name, StableChannel, LatestChannel)
+	base, minor, hasMinor := strings.Cut(name, "-")
+
+	var channel Channel
+
+	switch base {
+	case "", StableChannel:
+	case LatestChannel:
+		channel.Prerelease = true
+	default:
+		return Channel{}, invalid
+	}
+
+	if hasMinor {
+		majorStr, minorStr, ok := strings.Cut(minor, ".")
+		major, majorErr := strconv.ParseUint(majorStr, 10, 64)
+		minorNum, minorErr := strconv.ParseUint(minorStr, 10, 64)
+
+		if base == "" || !ok || majorErr != nil || minorErr != nil {
+			return Channel{}, invalid
+		}
+
+		channel.Minor = fmt.Sprintf("%d.%d", major, minorNum)
+	}
+
+	return channel, nil
+}
+
+// String returns the channel name, e.g. "stable" or "latest-1.31".
+func (c Channel) String() string {
+	name := StableChannel
+	if c.Prerelease {
+		name = LatestChannel
+	}
+
+	if c.Minor != "" {
+		name += "-" + c.Minor
+	}
+
+	return name
+}
+
+// IsDefault reports whether c is the stable channel without a minor restriction.
+func (c Channel) IsDefault() bool {
+	return c == Channel{}
+}
+
+// Allows reports whether version belongs to the channel. Versions that are not
+// valid semver are only allowed if the channel is not restricted to a minor.
+func (c Channel) Allows(version string) bool {
+	ver, err := semver.NewVersion(version)
+	if err != nil {
+		return c.Minor == ""
+	}
+
+	if !c.Prerelease && ver.Prerelease() != "" {
+		return false
+	}
+
+	return c.Minor == "" || fmt.Sprintf("%d.%d", ver.Major(), ver.Minor()) == c.Minor
+}
+
+// query returns the version cache key for looking up spec in this channel.
+func (c Channel) query(spec string) string {
+	if c.IsDefault() {
+		return spec
+	}
+
+	return channelQueryPrefix + c.String() + ":" + spec
+}
+
+// highestInChannel returns the highest version allowed by channel.
+func highestInChannel(versions []string, channel Channel) (string, bool) {
+	var best string
+
+	for _, version := range versions {
+		if !channel.Allows(version) {
+			continue
+		}
+
+		if best == "" || compareVersions(version, best) > 0 {
+			best = version
+		}
+	}
+
+	return best, best != ""
+}
+
+// channel returns the release channel configured for this tool.
+func (t *Tool) channel() (Channel, error) {
+	project, err := t.getProject()
+	if err != nil {
+		return Channel{}, err
+	}
+
+	channel, err := ParseChannel(project.ChannelName(t.Name))
+	if err != nil {
+		return Channel{}, fmt.Errorf("failed to parse channel for %s: %w", t.Name, err)
+	}
+
+	return channel, nil
+}
+
+// channelVersion looks up the newest upstream release in channel.
+func (t *Tool) channelVersion(ctx context.Context, channel Channel) (string, error) {
+	ctx, err := t.upstreamContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if channel.IsDefault() {
+		return t.VersionFunc(ctx)
+	}
+
+	if t.ChannelVersion != nil {
+		return t.ChannelVersion(ctx, channel)
+	}
+
+	if t.ListVersions == nil {
+		return "", fmt.Errorf("%s does not support the %q channel", t.Name, channel)
+	}
+
+	versions, err := t.ListVersions(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to list %s versions: %w", t.Name, err)
+	}
+
+	version, ok := highestInChannel(versions, channel)
+	if !ok {
+		return "", fmt.Errorf("no %s release in channel %q", t.Name, channel)
+	}
+
+	return version, nil
+}
@@ -0,0 +1,178 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChannel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Channel
+		wantErr bool
+	}{
+		{name: "", want: Channel{}},
+		{name: "stable", want: Channel{}},
+		{name: "latest", want: Channel{Prerelease: true}},
+		{name: "latest-1.31", want: Channel{Prerelease: true, Minor: "1.31"}},
+		{name: "stable-1.30", want: Channel{Minor: "1.30"}},
+		{name: "beta", wantErr: true},
+		{name: "latest-", wantErr: true},
+		{name: "latest-1", wantErr: true},
+		{name: "latest-1.x", wantErr: true},
+		{name: "-1.31", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel, err := ParseChannel(tt.name)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "invalid channel")
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, channel)
+		})
+	}
+}
+
+func TestChannelString(t *testing.T) {
+	assert.Equal(t, "stable", Channel{}.String())
+	assert.Equal(t, "latest", Channel{Prerelease: true}.String())
+	assert.Equal(t, "latest-1.31", Channel{Prerelease: true, Minor: "1.31"}.String())
+	assert.Equal(t, "stable-1.30", Channel{Minor: "1.30"}.String())
+}
+
+func TestChannelAllows(t *testing.T) {
+	stable := Channel{}
+	latest := Channel{Prerelease: true}
+	latest131 := Channel{Prerelease: true, Minor: "1.31"}
+
+	assert.True(t, stable.Allows("v1.31.0"))
+	assert.False(t, stable.Allows("v1.31.0-rc.1"))
+	assert.True(t, stable.Allows("dev-branch"))
+
+	assert.True(t, latest.Allows("v1.31.0-rc.1"))
+	assert.True(t, latest.Allows("v1.30.4"))
+
+	assert.True(t, latest131.Allows("v1.31.0-alpha.2"))
+	assert.False(t, latest131.Allows("v1.32.0-alpha.1"))
+	assert.False(t, latest131.Allows("dev-branch"))
+}
+
+func TestChannelQuery(t *testing.T) {
+	assert.Empty(t, Channel{}.query(""))
+	assert.Equal(t, "~1.30", Channel{}.query("~1.30"))
+	assert.Equal(t, "channel:latest:", Channel{Prerelease: true}.query(""))
+	assert.Equal(t, "channel:latest-1.31:~1.31", Channel{Prerelease: true, Minor: "1.31"}.query("~1.31"))
+}
+
+func TestHighestInChannel(t *testing.T) {
+	versions := []string{"v1.30.4", "v1.31.0-rc.1", "v1.31.0-rc.2", "v1.30.5-rc.0"}
+
+	version, ok := highestInChannel(versions, Channel{})
+	require.True(t, ok)
+	assert.Equal(t, "v1.30.4", version)
+
+	version, ok = highestInChannel(versions, Channel{Prerelease: true})
+	require.True(t, ok)
+	assert.Equal(t, "v1.31.0-rc.2", version)
+
+	version, ok = highestInChannel(versions, Channel{Prerelease: true, Minor: "1.30"})
+	require.True(t, ok)
+	assert.Equal(t, "v1.30.5-rc.0", version)
+
+	_, ok = highestInChannel(versions, Channel{Minor: "1.29"})
+	assert.False(t, ok)
+}
+
+func TestResolveVersionChannel(t *testing.T) {
+	newChannelTool := func(channel, pin string) *Tool {
+		return &Tool{
+			Name: "kubectl",
+			Fs:   newOfflineTestTool(t, nil).Fs,
+			VersionFunc: func(ctx context.Context) (string, error) {
+				return "v1.30.4", nil
+			},
+			ListVersions: func(ctx context.Context, _ *semver.Constraints) ([]string, error) {
+				return []string{"v1.30.4", "v1.30.5-rc.0", "v1.31.0-rc.1", "v1.31.0-rc.2"}, nil
+			},
+			Project: &ProjectConfig{
+				Versions: map[string]string{"kubectl": pin},
+				Channels: map[string]string{"kubectl": channel},
+			},
+		}
+	}
+
+	t.Run("stable channel uses VersionFunc", func(t *testing.T) {
+		version, err := newChannelTool("stable", "").ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.30.4", version)
+	})
+
+	t.Run("latest channel falls back to listing releases", func(t *testing.T) {
+		version, err := newChannelTool("latest", "").ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.31.0-rc.2", version)
+	})
+
+	t.Run("uses ChannelVersion when provided", func(t *testing.T) {
+		tool := newChannelTool("latest-1.31", "")
+		tool.ChannelVersion = func(ctx context.Context, channel Channel) (string, error) {
+			assert.Equal(t, Channel{Prerelease: true, Minor: "1.31"}, channel)
+
+			return "v1.31.0-rc.1", nil
+		}
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.31.0-rc.1", version)
+	})
+
+	t.Run("latest channel includes prereleases in constraints", func(t *testing.T) {
+		version, err := newChannelTool("latest", "~1.30").ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.30.5-rc.0", version)
+	})
+
+	t.Run("stable channel excludes prereleases from constraints", func(t *testing.T) {
+		_, err := newChannelTool("", "~1.31").ResolveVersion(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no kubectl release matches")
+	})
+
+	t.Run("rejects invalid channel", func(t *testing.T) {
+		_, err := newChannelTool("nightly", "").ResolveVersion(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse channel for kubectl")
+	})
+}
+
+func TestOfflineVersionChannel(t *testing.T) {
+	t.Run("stable channel skips cached prereleases", func(t *testing.T) {
+		tool := newOfflineTestTool(t, nil, "v1.30.4", "v1.31.0-rc.1")
+		tool.Offline = true
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.30.4", version)
+	})
+
+	t.Run("latest channel uses newest cached prerelease", func(t *testing.T) {
+		tool := newOfflineTestTool(t, nil, "v1.30.4", "v1.31.0-rc.1", "v1.31.0-rc.2")
+		tool.Offline = true
+		tool.Project.Channels = map[string]string{"kubectl": "latest"}
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.31.0-rc.2", version)
+	})
+}
@@ -0,0 +1,134 @@
+package tool
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ChecksumFormat is the layout of the file published at a tool's ChecksumURL.
+type ChecksumFormat string
+
+const (
+	// ChecksumSingle is a file holding the checksum of a single asset,
+	// optionally followed by its file name as written by sha256sum.
+	ChecksumSingle ChecksumFormat = ""
+	// ChecksumManifest is a file such as checksums.txt or SHA256SUMS with one
+	// line per asset, from which the line of the downloaded asset is selected.
+	ChecksumManifest ChecksumFormat = "manifest"
+)
+
+// newChecksumHash returns the hash matching the length of the expected hex
+// checksum: sha512 for 128 digits, sha256 otherwise.
+func newChecksumHash(expected string) hash.Hash {
+	if len(expected) == 2*sha512.Size {
+		return sha512.New()
+	}
+
+	return sha256.New()
+}
+
+// upstreamChecksum fetches the expected checksum of a version for goos/goarch
+// from the tool's ChecksumURL.
+func (t *Tool) upstreamChecksum(ctx context.Context, version, goos, goarch string) (string, error) {
+	checksumURL := t.ChecksumURL(version, goos, goarch)
+
+	switch t.ChecksumFormat {
+	case ChecksumSingle:
+		return fetchChecksum(ctx, checksumURL)
+	case ChecksumManifest:
+		asset, err := assetName(t.DownloadURL(version, goos, goarch))
+		if err != nil {
+			return "", err
+		}
+
+		return fetchManifestChecksum(ctx, checksumURL, asset)
+	default:
+		return "", fmt.Errorf("unsupported checksum format: %q", t.ChecksumFormat)
+	}
+}
+
+// assetName returns the file name of the asset at downloadURL.
+func assetName(downloadURL string) (string, error) {
+	u, err := url.Parse(downloadURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid download URL %q: %w", downloadURL, err)
+	}
+
+	return path.Base(u.Path), nil
+}
+
+// fetchManifestChecksum downloads the checksum manifest at url and returns the
+// checksum listed for asset.
+func fetchManifestChecksum(ctx context.Context, url, asset string) (string, error) {
+	data, err := fetchHTTPContent(ctx, getRetryableClient(ctx).StandardClient(), url)
+	if err != nil {
+		return "", err
+	}
+
+	checksum, err := parseChecksumManifest(string(data), asset)
+	if err != nil {
+		return "", fmt.Errorf("%w in %s", err, url)
+	}
+
+	return checksum, nil
+}
+
+// parseChecksumManifest returns the checksum of asset from a manifest in the
+// format of sha256sum/sha512sum ("checksum  name", "checksum *name") or their
+// BSD-style --tag output ("SHA256 (name) = checksum"). Entries match by exact
+// name or by base name, so that manifests listing paths such as
+// "dist/kind-linux-amd64" work too. Different checksums for the same asset are
+// rejected rather than picking one.
+func parseChecksumManifest(manifest, asset string) (string, error) {
+	var found string
+
+	for line := range strings.Lines(manifest) {
+		checksum, name, ok := parseChecksumLine(line)
+		if !ok || (name != asset && path.Base(name) != asset) {
+			continue
+		}
+
+		if found != "" && found != checksum {
+			return "", fmt.Errorf("ambiguous checksums for %s", asset)
+		}
+
+		found = checksum
+	}
+
+	if found == "" {
+		return "", fmt.Errorf("no checksum for %s", asset)
+	}
+
+	return found, nil
+}
+
+// parseChecksumLine splits a manifest line into lower-case checksum and file name.
+func parseChecksumLine(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+
+	// BSD style: "SHA256 (name) = checksum"
+	if _, rest, ok := strings.Cut(line, " ("); ok {
+		if name, checksum, ok := strings.Cut(rest, ") = "); ok {
+			return strings.ToLower(checksum), path.Clean(name), true
+		}
+	}
+
+	checksum, name, ok := strings.Cut(line, " ")
+	if !ok {
+		return "", "", false
+	}
+
+	// Binary mode entries prefix the name with '*'.
+	name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+
+	return strings.ToLower(checksum), path.Clean(name), true
+}
@@ -0,0 +1,159 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewChecksumHash(t *testing.T) {
+	assert.Equal(t, sha256.Size, newChecksumHash(strings.Repeat("a", 64)).Size())
+	assert.Equal(t, sha512.Size, newChecksumHash(strings.Repeat("a", 128)).Size())
+	assert.Equal(t, sha256.Size, newChecksumHash("abc123").Size())
+}
+
+func TestParseChecksumManifest(t *testing.T) {
+	manifest := `# release checksums
+1111111111111111111111111111111111111111111111111111111111111111  kind-darwin-arm64
+2222222222222222222222222222222222222222222222222222222222222222  kind-linux-amd64
+3333333333333333333333333333333333333333333333333333333333333333 *helm.tar.gz
+4444444444444444444444444444444444444444444444444444444444444444  ./dist/cilium.zip
+SHA512 (tool.tar.xz) = ABCDEF
+`
+
+	tests := []struct {
+		asset   string
+		want    string
+		wantErr string
+	}{
+		{asset: "kind-linux-amd64", want: strings.Repeat("2", 64)},
+		{asset: "helm.tar.gz", want: strings.Repeat("3", 64)},
+		{asset: "cilium.zip", want: strings.Repeat("4", 64)},
+		{asset: "tool.tar.xz", want: "abcdef"},
+		{asset: "kind-linux-arm64", wantErr: "no checksum for kind-linux-arm64"},
+		{asset: "kind", wantErr: "no checksum for kind"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.asset, func(t *testing.T) {
+			checksum, err := parseChecksumManifest(manifest, tt.asset)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, checksum)
+		})
+	}
+
+	t.Run("rejects ambiguous entries", func(t *testing.T) {
+		_, err := parseChecksumManifest("aaaa  linux/kubectl\nbbbb  darwin/kubectl\n", "kubectl")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "ambiguous checksums for kubectl")
+	})
+
+	t.Run("accepts repeated identical entries", func(t *testing.T) {
+		checksum, err := parseChecksumManifest("aaaa  kubectl\naaaa  bin/kubectl\n", "kubectl")
+		require.NoError(t, err)
+		assert.Equal(t, "aaaa", checksum)
+	})
+}
+
+func TestAssetName(t *testing.T) {
+	name, err := assetName("https://github.com/kubernetes-sigs/kind/releases/download/v0.25.0/kind-linux-amd64?raw=true")
+	require.NoError(t, err)
+	assert.Equal(t, "kind-linux-amd64", name)
+
+	_, err = assetName("://invalid")
+	require.Error(t, err)
+}
+
+func TestDownloadWithChecksumManifest(t *testing.T) {
+	content := []byte("binary content")
+	sha512sum := fmt.Sprintf("%x", sha512.Sum512(content))
+
+	manifest := fmt.Sprintf("%x  testtool-darwin-arm64\n%s  testtool-linux-amd64\n", sha512.Sum512([]byte("other")), sha512sum)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/SHA512SUMS" {
+			_, _ = w.Write([]byte(manifest)) //nolint:errcheck // test helper
+
+			return
+		}
+
+		_, _ = w.Write(content) //nolint:errcheck // test helper
+	}))
+	defer server.Close()
+
+	newTool := func(fs afero.Fs) *Tool {
+		return &Tool{
+			Name: "testtool",
+			Fs:   fs,
+			DownloadURL: func(version, goos, goarch string) string {
+				return fmt.Sprintf("%s/%s/testtool-%s-%s", server.URL, version, goos, goarch)
+			},
+			ChecksumURL: func(version, goos, goarch string) string {
+				return server.URL + "/SHA512SUMS"
+			},
+			ChecksumFormat: ChecksumManifest,
+			Project:        &ProjectConfig{},
+		}
+	}
+
+	t.Run("verifies sha512 from the asset line", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		require.NoError(t, newTool(fs).downloadPlatform(context.Background(), testToolPath, testVersion, "linux", "amd64"))
+
+		data, err := afero.ReadFile(fs, testToolPath)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+	})
+
+	t.Run("fails on the checksum of another asset", func(t *testing.T) {
+		err := newTool(afero.NewMemMapFs()).downloadPlatform(context.Background(), testToolPath, testVersion, "darwin", "arm64")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "checksum mismatch")
+	})
+
+	t.Run("fails for assets missing from the manifest", func(t *testing.T) {
+		err := newTool(afero.NewMemMapFs()).downloadPlatform(context.Background(), testToolPath, testVersion, "linux", "arm64")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no checksum for testtool-linux-arm64 in "+server.URL+"/SHA512SUMS")
+	})
+
+	t.Run("locks sha512 checksums", func(t *testing.T) {
+		tool := newTool(afero.NewMemMapFs())
+		tool.VersionFunc = func(context.Context) (string, error) { return testVersion, nil }
+
+		locked, err := tool.Lock(context.Background(), []string{"linux/amd64"})
+		require.NoError(t, err)
+
+		artifact := locked.Platforms["linux/amd64"]
+		assert.Empty(t, artifact.SHA256)
+		assert.Equal(t, sha512sum, artifact.SHA512)
+		assert.Equal(t, sha512sum, artifact.Checksum())
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		tool := newTool(afero.NewMemMapFs())
+		tool.ChecksumFormat = "sums"
+
+		err := tool.downloadPlatform(context.Background(), testToolPath, testVersion, "linux", "amd64")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unsupported checksum format: "sums"`)
+	})
+}
@@ -5,28 +5,34 @@ import (
 	"fmt"
 	"io"
 
-	"github.com/google/go-github/v58/github"
+	"github.com/Masterminds/semver/v3"
 )
 
+// ciliumReleaseURL is the base URL of the cilium CLI release artifacts.
+const ciliumReleaseURL = "https://github.com/cilium/cilium-cli/releases/download"
+
 // NewCilium creates a Tool configured for cilium CLI.
 func NewCilium(progress io.Writer) *Tool {
 	return NewToolFromConfig(ciliumConfig(), progress)
 }
 
-func ciliumVersion(ctx context.Context) (version string, err error) {
-	client := github.NewClient(nil)
+func ciliumVersion(ctx context.Context) (string, error) {
+	return githubLatestVersion(ctx, "cilium", "cilium-cli")
+}
 
-	release, _, err := client.Repositories.GetLatestRelease(ctx, "cilium", "cilium-cli")
-	if err != nil {
-		return "", fmt.Errorf("failed to get latest cilium-cli release: %w", err)
-	}
+// ciliumVersions lists all published cilium-cli releases.
+func ciliumVersions(ctx context.Context, _ *semver.Constraints) ([]string, error) {
+	return githubReleaseVersions(ctx, "cilium", "cilium-cli")
+}
 
-	return release.GetTagName(), nil
+// ciliumChannelVersion returns the newest cilium-cli release in channel.
+func ciliumChannelVersion(ctx context.Context, channel Channel) (string, error) {
+	return githubChannelVersion(ctx, "cilium", "cilium-cli", channel)
 }
 
 func ciliumDownloadURL(version, goos, goarch string) string {
-	return fmt.Sprintf("https://github.com/cilium/cilium-cli/releases/download/%s/cilium-%s-%s.tar.gz",
-		version, goos, goarch)
+	return fmt.Sprintf("%s/%s/cilium-%s-%s.tar.gz",
+		ciliumReleaseURL, version, goos, goarch)
 }
 
 func ciliumChecksumURL(version, goos, goarch string) string {
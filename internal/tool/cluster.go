@@ -0,0 +1,79 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+const (
+	// ClusterVersionSpec is the version spec selecting the release that matches
+	// the API server of the current kubeconfig context.
+	ClusterVersionSpec = "cluster"
+
+	// clusterQueryPrefix prefixes version cache keys holding API server versions.
+	clusterQueryPrefix = "cluster:"
+)
+
+// resolveClusterSpec resolves the "cluster" spec to the newest release of the
+// API server's minor version, as recommended by the Kubernetes skew policy.
+// Without a reachable cluster any version is compatible, so the latest is used.
+func (t *Tool) resolveClusterSpec(ctx context.Context, ttl time.Duration, useCache bool) (string, error) {
+	if !t.MatchClusterVersion {
+		return "", fmt.Errorf("%s does not support the %q version spec", t.Name, ClusterVersionSpec)
+	}
+
+	serverVersion, err := t.clusterServerVersion(ctx, ttl, useCache)
+	if err != nil {
+		if err := t.writeProgress("Warning: failed to determine cluster version (%v), using latest %s\n", err, t.Name); err != nil {
+			return "", fmt.Errorf("failed to write progress: %w", err)
+		}
+
+		return t.resolveSpec(ctx, "", ttl, useCache)
+	}
+
+	ver, err := semver.NewVersion(serverVersion)
+	if err != nil {
+		return "", fmt.Errorf("invalid cluster version %q: %w", serverVersion, err)
+	}
+
+	return t.resolveSpec(ctx, fmt.Sprintf("~%d.%d", ver.Major(), ver.Minor()), ttl, useCache)
+}
+
+// clusterServerVersion returns the API server version of the current kubeconfig
+// context. Answers are cached per context and server so that the API server is
+// not queried on every invocation; offline, a cached answer of any age is used.
+func (t *Tool) clusterServerVersion(ctx context.Context, ttl time.Duration, useCache bool) (string, error) {
+	endpoint, err := currentClusterEndpoint(t.getFs())
+	if err != nil {
+		return "", err
+	}
+
+	query := clusterQueryPrefix + endpoint.Context + "@" + endpoint.Server
+
+	if t.isOffline() {
+		if version, ok := t.cachedResolvedVersion(query, time.Duration(math.MaxInt64)); ok {
+			return version, nil
+		}
+
+		return "", fmt.Errorf("offline mode: version of context %q not cached", endpoint.Context)
+	}
+
+	if useCache {
+		if version, ok := t.cachedResolvedVersion(query, ttl); ok {
+			return version, nil
+		}
+	}
+
+	version, err := endpoint.serverVersion(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to query server version of context %q: %w", endpoint.Context, err)
+	}
+
+	_ = t.storeResolvedVersion(query, version) //nolint:errcheck // best effort cache
+
+	return version, nil
+}
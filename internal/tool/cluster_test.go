@@ -0,0 +1,125 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newClusterTestTool creates a kubectl tool pinned to the "cluster" spec.
+func newClusterTestTool(fs afero.Fs, progress *bytes.Buffer) *Tool {
+	tool := &Tool{
+		Name:                "kubectl",
+		Fs:                  fs,
+		Project:             &ProjectConfig{Versions: map[string]string{"kubectl": ClusterVersionSpec}},
+		MatchClusterVersion: true,
+		VersionFunc: func(ctx context.Context) (string, error) {
+			return "v1.31.2", nil
+		},
+		ListVersions: func(ctx context.Context, c *semver.Constraints) ([]string, error) {
+			return []string{"v1.31.2", "v1.30.6", "v1.29.10"}, nil
+		},
+	}
+
+	if progress != nil {
+		tool.ProgressWriter = progress
+	}
+
+	return tool
+}
+
+func TestResolveClusterSpec(t *testing.T) {
+	t.Run("selects release matching the server minor", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+		t.Setenv("KUBECONFIG", "")
+
+		requests := 0
+		server := newVersionServer(t, "v1.29.4", &requests)
+		writeKubeconfig(t, fs, testKubeconfigPath, "kind-dev", server)
+
+		tool := newClusterTestTool(fs, nil)
+
+		for range 2 {
+			version, err := tool.ResolveVersion(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "v1.29.10", version)
+		}
+
+		assert.Equal(t, 1, requests, "server version should be cached per context")
+	})
+
+	t.Run("caches server version per context", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+		t.Setenv("KUBECONFIG", "")
+
+		old := newVersionServer(t, "v1.29.4", nil)
+		writeKubeconfig(t, fs, testKubeconfigPath, "kind-old", old)
+
+		version, err := newClusterTestTool(fs, nil).ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.29.10", version)
+
+		current := newVersionServer(t, "v1.30.1", nil)
+		writeKubeconfig(t, fs, testKubeconfigPath, "kind-new", current)
+
+		version, err = newClusterTestTool(fs, nil).ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.30.6", version)
+	})
+
+	t.Run("falls back to latest without reachable cluster", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+		t.Setenv("KUBECONFIG", "")
+
+		var progress bytes.Buffer
+
+		version, err := newClusterTestTool(fs, &progress).ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.31.2", version)
+		assert.Contains(t, progress.String(), "Warning: failed to determine cluster version")
+	})
+
+	t.Run("uses cached server version offline", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+		t.Setenv("KUBECONFIG", "")
+
+		server := newVersionServer(t, "v1.30.1", nil)
+		writeKubeconfig(t, fs, testKubeconfigPath, "kind-dev", server)
+
+		tool := newClusterTestTool(fs, nil)
+		require.NoError(t, tool.storeResolvedVersion(clusterQueryPrefix+"kind-dev@"+server.URL, "v1.29.4"))
+
+		for _, version := range []string{"v1.29.2", "v1.31.0"} {
+			binPath := fmt.Sprintf("%s/.kdev/kdev/kubectl/%s/kubectl", testUser, version)
+			require.NoError(t, afero.WriteFile(fs, binPath, []byte("cached binary"), 0o755))
+		}
+
+		tool.Offline = true
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.29.2", version)
+	})
+
+	t.Run("rejects tools without cluster matching", func(t *testing.T) {
+		tool := newClusterTestTool(afero.NewMemMapFs(), nil)
+		tool.Name = "kind"
+		tool.Project = &ProjectConfig{Versions: map[string]string{"kind": ClusterVersionSpec}}
+		tool.MatchClusterVersion = false
+
+		_, err := tool.ResolveVersion(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `kind does not support the "cluster" version spec`)
+	})
+}
@@ -3,55 +3,94 @@ package tool
 import (
 	"context"
 	"io"
+	"net/http"
+
+	"github.com/Masterminds/semver/v3"
 )
 
 // Config defines the configuration for creating a Tool.
 //
 //nolint:govet // fieldalignment: readability preferred over optimization
 type Config struct {
-	Name        string
-	VersionFunc func(context.Context) (string, error)
-	DownloadURL func(version, goos, goarch string) string
-	ChecksumURL func(version, goos, goarch string) string
+	Name                string
+	VersionFunc         func(context.Context) (string, error)
+	ListVersions        func(context.Context, *semver.Constraints) ([]string, error)
+	ChannelVersion      func(context.Context, Channel) (string, error)
+	DownloadURL         func(version, goos, goarch string) string
+	ChecksumURL         func(version, goos, goarch string) string
+	ChecksumFormat      ChecksumFormat
+	ArchiveFormat       ArchiveFormat
+	ArchiveMember       func(version, goos, goarch string) string
+	UpstreamURL         string
+	BaseURL             string
+	GitHubAPIURL        string
+	HTTPClient          *http.Client
+	MatchClusterVersion bool
 }
 
 // NewToolFromConfig creates a Tool from a configuration.
 func NewToolFromConfig(cfg Config, progress io.Writer) *Tool {
 	return &Tool{
-		Name:           cfg.Name,
-		ProgressWriter: progress,
-		VersionFunc:    cfg.VersionFunc,
-		DownloadURL:    cfg.DownloadURL,
-		ChecksumURL:    cfg.ChecksumURL,
+		Name:                cfg.Name,
+		ProgressWriter:      progress,
+		VersionFunc:         cfg.VersionFunc,
+		ListVersions:        cfg.ListVersions,
+		ChannelVersion:      cfg.ChannelVersion,
+		DownloadURL:         cfg.DownloadURL,
+		ChecksumURL:         cfg.ChecksumURL,
+		ChecksumFormat:      cfg.ChecksumFormat,
+		ArchiveFormat:       cfg.ArchiveFormat,
+		ArchiveMember:       cfg.ArchiveMember,
+		UpstreamURL:         cfg.UpstreamURL,
+		BaseURL:             cfg.BaseURL,
+		GitHubAPIURL:        cfg.GitHubAPIURL,
+		HTTPClient:          cfg.HTTPClient,
+		MatchClusterVersion: cfg.MatchClusterVersion,
 	}
 }
 
+// BuiltinConfigs returns the configurations of the built-in tools, e.g. to
+// point them at other hosts before passing them to NewRegistryFromConfigs.
+func BuiltinConfigs() []Config {
+	return []Config{ciliumConfig(), kindConfig(), kubectlConfig()}
+}
+
 // kubectlConfig returns the configuration for kubectl.
 func kubectlConfig() Config {
 	return Config{
-		Name:        "kubectl",
-		VersionFunc: kubectlVersion,
-		DownloadURL: kubectlDownloadURL,
-		ChecksumURL: kubectlChecksumURL,
+		Name:                "kubectl",
+		VersionFunc:         kubectlVersion,
+		ListVersions:        kubectlVersions,
+		ChannelVersion:      kubectlChannelVersion,
+		DownloadURL:         kubectlDownloadURL,
+		ChecksumURL:         kubectlChecksumURL,
+		UpstreamURL:         kubectlReleaseURL,
+		MatchClusterVersion: true,
 	}
 }
 
 // kindConfig returns the configuration for kind.
 func kindConfig() Config {
 	return Config{
-		Name:        "kind",
-		VersionFunc: kindVersion,
-		DownloadURL: kindDownloadURL,
-		ChecksumURL: kindChecksumURL,
+		Name:           "kind",
+		VersionFunc:    kindVersion,
+		ListVersions:   kindVersions,
+		ChannelVersion: kindChannelVersion,
+		DownloadURL:    kindDownloadURL,
+		ChecksumURL:    kindChecksumURL,
+		UpstreamURL:    kindReleaseURL,
 	}
 }
 
 // ciliumConfig returns the configuration for cilium CLI.
 func ciliumConfig() Config {
 	return Config{
-		Name:        "cilium",
-		VersionFunc: ciliumVersion,
-		DownloadURL: ciliumDownloadURL,
-		ChecksumURL: ciliumChecksumURL,
+		Name:           "cilium",
+		VersionFunc:    ciliumVersion,
+		ListVersions:   ciliumVersions,
+		ChannelVersion: ciliumChannelVersion,
+		DownloadURL:    ciliumDownloadURL,
+		ChecksumURL:    ciliumChecksumURL,
+		UpstreamURL:    ciliumReleaseURL,
 	}
 }
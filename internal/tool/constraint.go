@@ -0,0 +1,95 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// parseConstraint returns the semver constraint expressed by spec (e.g. "~1.30"
+// or ">=0.16 <0.17"). It returns nil if spec is empty, an exact version such as
+// "v1.30.4", or not a valid constraint; such specs are used verbatim.
+func parseConstraint(spec string) *semver.Constraints {
+	if spec == "" {
+		return nil
+	}
+
+	if _, err := semver.StrictNewVersion(strings.TrimPrefix(spec, "v")); err == nil {
+		return nil
+	}
+
+	constraint, err := semver.NewConstraint(spec)
+	if err != nil {
+		return nil
+	}
+
+	return constraint
+}
+
+// matchesConstraint reports whether version satisfies constraint. A nil
+// constraint matches every version.
+func matchesConstraint(version string, constraint *semver.Constraints) bool {
+	if constraint == nil {
+		return true
+	}
+
+	ver, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+
+	return constraint.Check(ver)
+}
+
+// highestMatching returns the highest version satisfying constraint.
+// Versions that are not valid semver are ignored.
+func highestMatching(versions []string, constraint *semver.Constraints) (string, bool) {
+	var (
+		best    string
+		bestVer *semver.Version
+	)
+
+	for _, version := range versions {
+		ver, err := semver.NewVersion(version)
+		if err != nil || !constraint.Check(ver) {
+			continue
+		}
+
+		if bestVer == nil || ver.GreaterThan(bestVer) {
+			best, bestVer = version, ver
+		}
+	}
+
+	return best, bestVer != nil
+}
+
+// resolveConstraint resolves a constraint to the highest matching upstream release in channel.
+func (t *Tool) resolveConstraint(ctx context.Context, spec string, constraint *semver.Constraints, channel Channel) (string, error) {
+	if t.ListVersions == nil {
+		return "", fmt.Errorf("%s does not support version constraints", t.Name)
+	}
+
+	ctx, err := t.upstreamContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	versions, err := t.ListVersions(ctx, constraint)
+	if err != nil {
+		return "", fmt.Errorf("failed to list %s versions: %w", t.Name, err)
+	}
+
+	versions = slices.DeleteFunc(versions, func(version string) bool {
+		return !channel.Allows(version)
+	})
+
+	version, ok := highestMatching(versions, constraint)
+	if !ok {
+		return "", fmt.Errorf("no %s release matches %q", t.Name, spec)
+	}
+
+	return version, nil
+}
@@ -0,0 +1,178 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mustConstraint parses a semver constraint or fails the test.
+func mustConstraint(t *testing.T, spec string) *semver.Constraints {
+	t.Helper()
+
+	constraint, err := semver.NewConstraint(spec)
+	require.NoError(t, err)
+
+	return constraint
+}
+
+func TestParseConstraint(t *testing.T) {
+	tests := []struct {
+		spec         string
+		isConstraint bool
+	}{
+		{"", false},
+		{"v1.30.4", false},
+		{"1.30.4", false},
+		{"v1.31.0-rc.1", false},
+		{"latest", false},
+		{"~1.30", true},
+		{"1.30", true},
+		{">=0.16 <0.17", true},
+		{"^0.22", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("spec %q", tt.spec), func(t *testing.T) {
+			assert.Equal(t, tt.isConstraint, parseConstraint(tt.spec) != nil)
+		})
+	}
+}
+
+func TestHighestMatching(t *testing.T) {
+	versions := []string{"v0.15.2", "v0.16.1", "v0.16.10", "v0.16.3", "v0.17.0", "v0.17.0-rc.1", "nightly"}
+
+	t.Run("selects highest matching version", func(t *testing.T) {
+		version, ok := highestMatching(versions, mustConstraint(t, ">=0.16 <0.17"))
+		assert.True(t, ok)
+		assert.Equal(t, "v0.16.10", version)
+	})
+
+	t.Run("excludes prereleases by default", func(t *testing.T) {
+		version, ok := highestMatching(versions, mustConstraint(t, "^0.17"))
+		assert.True(t, ok)
+		assert.Equal(t, "v0.17.0", version)
+	})
+
+	t.Run("reports no match", func(t *testing.T) {
+		_, ok := highestMatching(versions, mustConstraint(t, "~1.0"))
+		assert.False(t, ok)
+	})
+}
+
+func TestResolveConstraint(t *testing.T) {
+	t.Run("resolves to highest listed match", func(t *testing.T) {
+		tool := &Tool{
+			Name: "cilium",
+			ListVersions: func(ctx context.Context, c *semver.Constraints) ([]string, error) {
+				return []string{"v0.16.1", "v0.16.4", "v0.17.0"}, nil
+			},
+		}
+
+		version, err := tool.resolveConstraint(context.Background(), "~0.16", mustConstraint(t, "~0.16"), Channel{})
+		require.NoError(t, err)
+		assert.Equal(t, "v0.16.4", version)
+	})
+
+	t.Run("fails without version lister", func(t *testing.T) {
+		tool := &Tool{Name: "custom"}
+
+		_, err := tool.resolveConstraint(context.Background(), "~1.0", mustConstraint(t, "~1.0"), Channel{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "does not support version constraints")
+	})
+
+	t.Run("propagates listing errors", func(t *testing.T) {
+		tool := &Tool{
+			Name: "kind",
+			ListVersions: func(ctx context.Context, c *semver.Constraints) ([]string, error) {
+				return nil, fmt.Errorf("rate limited")
+			},
+		}
+
+		_, err := tool.resolveConstraint(context.Background(), "~0.22", mustConstraint(t, "~0.22"), Channel{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to list kind versions")
+	})
+
+	t.Run("fails when nothing matches", func(t *testing.T) {
+		tool := &Tool{
+			Name: "kind",
+			ListVersions: func(ctx context.Context, c *semver.Constraints) ([]string, error) {
+				return []string{"v0.22.0"}, nil
+			},
+		}
+
+		_, err := tool.resolveConstraint(context.Background(), "~0.30", mustConstraint(t, "~0.30"), Channel{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `no kind release matches "~0.30"`)
+	})
+}
+
+func TestResolveVersionConstraint(t *testing.T) {
+	t.Run("resolves pinned constraint and caches the answer", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+
+		calls := 0
+		tool := &Tool{
+			Name:    "kubectl",
+			Fs:      fs,
+			Project: &ProjectConfig{Versions: map[string]string{"kubectl": "~1.30"}},
+			ListVersions: func(ctx context.Context, c *semver.Constraints) ([]string, error) {
+				calls++
+
+				return []string{"v1.31.0", "v1.30.6"}, nil
+			},
+		}
+
+		for range 2 {
+			version, err := tool.ResolveVersion(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "v1.30.6", version)
+		}
+
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("uses newest matching cached version offline", func(t *testing.T) {
+		var progress bytes.Buffer
+
+		tool := newOfflineTestTool(t, &progress, "v1.29.0", "v1.30.2", "v1.31.0")
+		tool.Offline = true
+		tool.Project = &ProjectConfig{Versions: map[string]string{"kubectl": "~1.30"}}
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.30.2", version)
+	})
+
+	t.Run("falls back to matching cached version on listing error", func(t *testing.T) {
+		tool := newOfflineTestTool(t, nil, "v1.29.0", "v1.31.0")
+		tool.Project = &ProjectConfig{Versions: map[string]string{"kubectl": "<1.30"}}
+		tool.ListVersions = func(ctx context.Context, c *semver.Constraints) ([]string, error) {
+			return nil, fmt.Errorf("network unreachable")
+		}
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.29.0", version)
+	})
+
+	t.Run("reports constraint when offline without match", func(t *testing.T) {
+		tool := newOfflineTestTool(t, nil, "v1.31.0")
+		tool.Offline = true
+		tool.Project = &ProjectConfig{Versions: map[string]string{"kubectl": "~1.30"}}
+
+		_, err := tool.ResolveVersion(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "matching")
+	})
+}
@@ -0,0 +1,252 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ToolsConfigEnvVar names the file declaring user-defined tools, replacing
+	// the default location in the kdev configuration directory.
+	ToolsConfigEnvVar = "KDEV_TOOLS_CONFIG"
+
+	// toolsConfigFile is the name of the file declaring user-defined tools.
+	toolsConfigFile = "tools.yaml"
+)
+
+// toolNamePattern matches valid names of user-defined tools. Names become
+// commands, cache directories and environment variable names, so they are
+// restricted to lower case letters, digits and dashes.
+var toolNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// ToolsConfig holds user-level settings read from a tools.yaml file. Unlike
+// the settings of a .kdev.yaml file, they apply to all projects.
+type ToolsConfig struct {
+	// Tools declares additional tools next to the built-in ones.
+	Tools []ToolDefinition `yaml:"tools"`
+	// GC configures garbage collection of the tool cache, which all projects
+	// share, by "kdev tools gc" and, if enabled, after downloads.
+	GC GCConfig `yaml:"gc"`
+	// Path is the location of the loaded file (empty if none was found).
+	Path string `yaml:"-"`
+}
+
+// ToolDefinition declares a user-defined tool. The URL templates are Go
+// templates expanded with {{.Version}}, {{.OS}} and {{.Arch}}, e.g.
+// "https://get.helm.sh/helm-{{.Version}}-{{.OS}}-{{.Arch}}.tar.gz".
+//
+//nolint:govet // fieldalignment: readability preferred over optimization
+type ToolDefinition struct {
+	// Name is the name of the tool and of its command.
+	Name string `yaml:"name"`
+	// GitHub is the "owner/repo" whose releases provide the versions of the tool.
+	GitHub string `yaml:"github"`
+	// VersionURL serves the latest version as plain text, as an alternative to GitHub.
+	VersionURL string `yaml:"versionURL"`
+	// Download is the URL template of the binary or archive.
+	Download string `yaml:"download"`
+	// Checksum is the URL template of the checksum of the download.
+	Checksum string `yaml:"checksum"`
+	// ChecksumFormat is the layout of the checksum file ("manifest" for one line per asset).
+	ChecksumFormat ChecksumFormat `yaml:"checksumFormat"`
+	// ArchiveFormat is the packaging of the download (defaults to detection from the URL).
+	ArchiveFormat ArchiveFormat `yaml:"archiveFormat"`
+	// ArchiveMember is the template of the binary's path pattern inside archives (defaults to the name).
+	ArchiveMember string `yaml:"archiveMember"`
+}
+
+// definitionTemplateData holds the values available in the templates of user-defined tools.
+type definitionTemplateData struct {
+	Version string
+	OS      string
+	Arch    string
+}
+
+// ToolsConfigPath returns the path of the file declaring user-defined tools:
+// the file named by KDEV_TOOLS_CONFIG, or tools.yaml in the kdev
+// configuration directory.
+func ToolsConfigPath() (string, error) {
+	if path := os.Getenv(ToolsConfigEnvVar); path != "" {
+		return path, nil
+	}
+
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine config directory: %w", err)
+	}
+
+	return filepath.Join(configDir, "kdev", toolsConfigFile), nil
+}
+
+// LoadToolsConfig loads and validates the user-level settings at path.
+// It returns an empty configuration if the file does not exist.
+func LoadToolsConfig(fs afero.Fs, path string) (*ToolsConfig, error) {
+	data, err := afero.ReadFile(fs, path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &ToolsConfig{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var cfg ToolsConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool, len(cfg.Tools))
+
+	for i, def := range cfg.Tools {
+		if _, err := def.toolConfig(); err != nil {
+			return nil, fmt.Errorf("invalid tool %d in %s: %w", i+1, path, err)
+		}
+
+		if seen[def.Name] {
+			return nil, fmt.Errorf("invalid tool %d in %s: %s is declared more than once", i+1, path, def.Name)
+		}
+
+		seen[def.Name] = true
+	}
+
+	if _, err := cfg.GC.Policy(); err != nil {
+		return nil, fmt.Errorf("invalid gc settings in %s: %w", path, err)
+	}
+
+	cfg.Path = path
+
+	return &cfg, nil
+}
+
+// toolConfig validates the definition and returns the configuration of the tool.
+func (d ToolDefinition) toolConfig() (Config, error) {
+	if !toolNamePattern.MatchString(d.Name) {
+		return Config{}, fmt.Errorf("invalid name %q: use lower case letters, digits and dashes", d.Name)
+	}
+
+	cfg := Config{
+		Name:           d.Name,
+		ChecksumFormat: d.ChecksumFormat,
+		ArchiveFormat:  d.ArchiveFormat,
+		UpstreamURL:    templatePrefix(d.Download),
+	}
+
+	switch {
+	case d.GitHub != "" && d.VersionURL != "":
+		return Config{}, errors.New("github and versionURL are mutually exclusive")
+	case d.GitHub != "":
+		owner, repo, ok := strings.Cut(d.GitHub, "/")
+		if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+			return Config{}, fmt.Errorf("invalid github %q: must be owner/repo", d.GitHub)
+		}
+
+		cfg.VersionFunc = func(ctx context.Context) (string, error) {
+			return githubLatestVersion(ctx, owner, repo)
+		}
+		cfg.ListVersions = func(ctx context.Context, _ *semver.Constraints) ([]string, error) {
+			return githubReleaseVersions(ctx, owner, repo)
+		}
+		cfg.ChannelVersion = func(ctx context.Context, channel Channel) (string, error) {
+			return githubChannelVersion(ctx, owner, repo, channel)
+		}
+	case d.VersionURL != "":
+		versionURL := d.VersionURL
+		cfg.VersionFunc = func(ctx context.Context) (string, error) {
+			data, err := fetchHTTPContent(ctx, getRetryableClient(ctx).StandardClient(), versionURL)
+			if err != nil {
+				return "", fmt.Errorf("failed to get latest %s version: %w", d.Name, err)
+			}
+
+			return strings.TrimSpace(string(data)), nil
+		}
+	default:
+		return Config{}, errors.New("github or versionURL is required")
+	}
+
+	switch d.ChecksumFormat {
+	case ChecksumSingle, ChecksumManifest:
+	default:
+		return Config{}, fmt.Errorf("unsupported checksumFormat %q", d.ChecksumFormat)
+	}
+
+	switch d.ArchiveFormat {
+	case ArchiveAuto, ArchivePlain, ArchiveTarGz, ArchiveTarXz, ArchiveZip:
+	default:
+		return Config{}, fmt.Errorf("unsupported archiveFormat %q", d.ArchiveFormat)
+	}
+
+	var err error
+
+	if cfg.DownloadURL, err = parseDefinitionTemplate("download", d.Download, true); err != nil {
+		return Config{}, err
+	}
+
+	if cfg.ChecksumURL, err = parseDefinitionTemplate("checksum", d.Checksum, true); err != nil {
+		return Config{}, err
+	}
+
+	if cfg.ArchiveMember, err = parseDefinitionTemplate("archiveMember", d.ArchiveMember, false); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// parseDefinitionTemplate parses the template of a ToolDefinition field and returns
+// a function expanding it. An empty optional template yields a nil function.
+func parseDefinitionTemplate(field, text string, required bool) (func(version, goos, goarch string) string, error) {
+	if text == "" {
+		if required {
+			return nil, fmt.Errorf("%s is required", field)
+		}
+
+		return nil, nil //nolint:nilnil // a nil function selects the default
+	}
+
+	tmpl, err := template.New(field).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", field, err)
+	}
+
+	// Catch references to unknown fields, which only fail on execution.
+	if err := tmpl.Execute(&bytes.Buffer{}, definitionTemplateData{}); err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", field, err)
+	}
+
+	return func(version, goos, goarch string) string {
+		var buf bytes.Buffer
+
+		// Execution was checked while parsing and cannot fail for valid data.
+		_ = tmpl.Execute(&buf, definitionTemplateData{Version: version, OS: goos, Arch: goarch}) //nolint:errcheck // checked above
+
+		return buf.String()
+	}, nil
+}
+
+// templatePrefix returns the URL up to the last slash before the first
+// template action, which serves as the tool's UpstreamURL, or an empty string
+// if the template does not start with a fixed scheme and host.
+func templatePrefix(text string) string {
+	prefix, _, _ := strings.Cut(text, "{{")
+
+	scheme, rest, ok := strings.Cut(prefix, "://")
+	if !ok {
+		return ""
+	}
+
+	i := strings.LastIndex(rest, "/")
+	if i <= 0 {
+		return ""
+	}
+
+	return scheme + "://" + rest[:i]
+}
@@ -0,0 +1,222 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToolsConfigPath = "/config/kdev/tools.yaml"
+
+// loadTestToolsConfig loads the user-defined tools declared by content.
+func loadTestToolsConfig(t *testing.T, content string) (*ToolsConfig, error) {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, testToolsConfigPath, []byte(content), 0o644))
+
+	return LoadToolsConfig(fs, testToolsConfigPath)
+}
+
+func TestToolsConfigPath(t *testing.T) {
+	t.Run("uses the kdev config directory", func(t *testing.T) {
+		t.Setenv(ToolsConfigEnvVar, "")
+		t.Setenv("XDG_CONFIG_HOME", "/custom/config")
+
+		path, err := ToolsConfigPath()
+		require.NoError(t, err)
+		assert.Equal(t, "/custom/config/kdev/tools.yaml", path)
+	})
+
+	t.Run("uses the file named by the environment", func(t *testing.T) {
+		t.Setenv(ToolsConfigEnvVar, "/etc/kdev-tools.yaml")
+
+		path, err := ToolsConfigPath()
+		require.NoError(t, err)
+		assert.Equal(t, "/etc/kdev-tools.yaml", path)
+	})
+}
+
+func TestLoadToolsConfig(t *testing.T) {
+	t.Run("returns an empty configuration without file", func(t *testing.T) {
+		cfg, err := LoadToolsConfig(afero.NewMemMapFs(), testToolsConfigPath)
+		require.NoError(t, err)
+		assert.Empty(t, cfg.Tools)
+		assert.Empty(t, cfg.Path)
+	})
+
+	t.Run("loads tool definitions", func(t *testing.T) {
+		cfg, err := loadTestToolsConfig(t, `tools:
+  - name: helm
+    github: helm/helm
+    download: https://get.helm.sh/helm-{{.Version}}-{{.OS}}-{{.Arch}}.tar.gz
+    checksum: https://get.helm.sh/helm-{{.Version}}-{{.OS}}-{{.Arch}}.tar.gz.sha256sum
+    archiveMember: "{{.OS}}-{{.Arch}}/helm"
+  - name: kustomize
+    versionURL: https://example.com/kustomize/stable.txt
+    download: https://example.com/kustomize/{{.Version}}/kustomize_{{.OS}}_{{.Arch}}.zip
+    checksum: https://example.com/kustomize/{{.Version}}/checksums.txt
+    checksumFormat: manifest
+    archiveFormat: zip
+`)
+		require.NoError(t, err)
+		assert.Equal(t, testToolsConfigPath, cfg.Path)
+		require.Len(t, cfg.Tools, 2)
+		assert.Equal(t, "helm", cfg.Tools[0].Name)
+		assert.Equal(t, "helm/helm", cfg.Tools[0].GitHub)
+		assert.Equal(t, ChecksumManifest, cfg.Tools[1].ChecksumFormat)
+		assert.Equal(t, ArchiveZip, cfg.Tools[1].ArchiveFormat)
+	})
+
+	tests := []struct {
+		name    string
+		tool    string
+		wantErr string
+	}{
+		{"missing name", "github: a/b", `invalid name ""`},
+		{"invalid name", "name: My_Tool\n    github: a/b", `invalid name "My_Tool"`},
+		{"hidden name", "name: .blobs\n    github: a/b", `invalid name ".blobs"`},
+		{"missing version source", "name: tool", "github or versionURL is required"},
+		{"both version sources", "name: tool\n    github: a/b\n    versionURL: https://example.com", "mutually exclusive"},
+		{"invalid github", "name: tool\n    github: a", `invalid github "a"`},
+		{"missing download", "name: tool\n    github: a/b\n    checksum: https://example.com", "download is required"},
+		{"missing checksum", "name: tool\n    github: a/b\n    download: https://example.com", "checksum is required"},
+		{"template syntax", "name: tool\n    github: a/b\n    download: https://example.com/{{.Version\n    checksum: x", "invalid download template"},
+		{"unknown template field", "name: tool\n    github: a/b\n    download: https://example.com/{{.Platform}}\n    checksum: x", "invalid download template"},
+		{"unknown checksum format", "name: tool\n    github: a/b\n    checksumFormat: md5", `unsupported checksumFormat "md5"`},
+		{"unknown archive format", "name: tool\n    github: a/b\n    archiveFormat: rar", `unsupported archiveFormat "rar"`},
+	}
+
+	for _, tt := range tests {
+		t.Run("rejects "+tt.name, func(t *testing.T) {
+			_, err := loadTestToolsConfig(t, "tools:\n  - "+tt.tool+"\n")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid tool 1 in "+testToolsConfigPath)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+
+	t.Run("rejects duplicate names", func(t *testing.T) {
+		definition := "  - name: tool\n    github: a/b\n    download: https://example.com/d\n    checksum: https://example.com/c\n"
+
+		_, err := loadTestToolsConfig(t, "tools:\n"+definition+definition)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid tool 2")
+		assert.Contains(t, err.Error(), "declared more than once")
+	})
+
+	t.Run("rejects invalid YAML", func(t *testing.T) {
+		_, err := loadTestToolsConfig(t, "tools: [")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse")
+	})
+}
+
+func TestToolDefinitionConfig(t *testing.T) {
+	t.Run("expands the templates", func(t *testing.T) {
+		cfg, err := ToolDefinition{
+			Name:          "helm",
+			GitHub:        "helm/helm",
+			Download:      "https://get.helm.sh/helm-{{.Version}}-{{.OS}}-{{.Arch}}.tar.gz",
+			Checksum:      "https://get.helm.sh/helm-{{.Version}}-{{.OS}}-{{.Arch}}.tar.gz.sha256sum",
+			ArchiveMember: "{{.OS}}-{{.Arch}}/helm",
+		}.toolConfig()
+		require.NoError(t, err)
+
+		assert.Equal(t, "helm", cfg.Name)
+		assert.Equal(t, "https://get.helm.sh/helm-v3.15.0-linux-arm64.tar.gz", cfg.DownloadURL("v3.15.0", "linux", "arm64"))
+		assert.Equal(t, "https://get.helm.sh/helm-v3.15.0-linux-arm64.tar.gz.sha256sum", cfg.ChecksumURL("v3.15.0", "linux", "arm64"))
+		assert.Equal(t, "linux-arm64/helm", cfg.ArchiveMember("v3.15.0", "linux", "arm64"))
+		assert.Equal(t, "https://get.helm.sh", cfg.UpstreamURL)
+		assert.NotNil(t, cfg.VersionFunc)
+		assert.NotNil(t, cfg.ListVersions)
+		assert.NotNil(t, cfg.ChannelVersion)
+	})
+
+	t.Run("defaults the archive member to the tool name", func(t *testing.T) {
+		cfg, err := ToolDefinition{
+			Name:       "tool",
+			VersionURL: "https://example.com/stable.txt",
+			Download:   "https://example.com/{{.Version}}/tool",
+			Checksum:   "https://example.com/{{.Version}}/tool.sha256",
+		}.toolConfig()
+		require.NoError(t, err)
+
+		assert.Nil(t, cfg.ArchiveMember)
+		assert.Nil(t, cfg.ListVersions, "versionURL only provides the latest version")
+		assert.Nil(t, cfg.ChannelVersion)
+	})
+}
+
+func TestTemplatePrefix(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"https://get.helm.sh/helm-{{.Version}}.tar.gz", "https://get.helm.sh"},
+		{"https://example.com/releases/{{.Version}}/tool", "https://example.com/releases"},
+		{"https://example.com/tool", "https://example.com"},
+		{"https://{{.OS}}.example.com/tool", ""},
+		{"{{.Version}}/tool", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			assert.Equal(t, tt.want, templatePrefix(tt.template))
+		})
+	}
+}
+
+func TestUserDefinedToolDownload(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asset, isChecksum := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/"), ".sha256")
+		content := []byte("binary " + asset)
+
+		switch {
+		case r.URL.Path == "/stable.txt":
+			_, _ = fmt.Fprintln(w, "v1.2.3") //nolint:errcheck // test helper
+		case isChecksum:
+			_, _ = fmt.Fprintf(w, "%x  %s\n", sha256.Sum256(content), filepath.Base(asset)) //nolint:errcheck // test helper
+		default:
+			_, _ = w.Write(content) //nolint:errcheck // test helper
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	cfg, err := ToolDefinition{
+		Name:       "mytool",
+		VersionURL: server.URL + "/stable.txt",
+		Download:   server.URL + "/{{.Version}}/mytool-{{.OS}}-{{.Arch}}",
+		Checksum:   server.URL + "/{{.Version}}/mytool-{{.OS}}-{{.Arch}}.sha256",
+	}.toolConfig()
+	require.NoError(t, err)
+
+	fs := afero.NewMemMapFs()
+	tool := NewToolFromConfig(cfg, nil)
+	tool.Fs = fs
+	tool.Project = &ProjectConfig{}
+
+	require.NoError(t, tool.Download(context.Background()))
+
+	versions, err := tool.CachedVersions()
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, "v1.2.3", versions[0].Version)
+
+	data, err := afero.ReadFile(fs, filepath.Join(testDataDir, "kdev", "mytool", "v1.2.3", "mytool"))
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("binary v1.2.3/mytool-%s-%s", runtime.GOOS, runtime.GOARCH), string(data))
+}
@@ -1,95 +1,199 @@
 package tool
 
 import (
-	"archive/tar"
-	"compress/gzip"
 	"context"
-	"crypto/sha256"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
+	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 
 	"github.com/spf13/afero"
-)
 
-const (
-	// tarGzExtension is the file extension for tar.gz archive files.
-	tarGzExtension = ".tar.gz"
+	"github.com/dennisklein/kdev/internal/util"
 )
 
+// download downloads and verifies version for the host platform to destPath
+// and records its metadata next to it.
 func (t *Tool) download(ctx context.Context, destPath, version string) error {
-	fs := t.getFs()
+	sourceURL, err := t.fetchArtifact(ctx, destPath, version, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+
+	return recordDownload(t.getFs(), destPath, VersionMetadata{SourceURL: sourceURL})
+}
 
-	url := t.DownloadURL(version, runtime.GOOS, runtime.GOARCH)
-	checksumURL := t.ChecksumURL(version, runtime.GOOS, runtime.GOARCH)
+// downloadPlatform downloads and verifies version for goos/goarch to destPath.
+func (t *Tool) downloadPlatform(ctx context.Context, destPath, version, goos, goarch string) error {
+	_, err := t.fetchArtifact(ctx, destPath, version, goos, goarch)
+
+	return err
+}
+
+// fetchArtifact downloads and verifies version for goos/goarch to destPath and
+// returns the URL it was downloaded from.
+func (t *Tool) fetchArtifact(ctx context.Context, destPath, version, goos, goarch string) (string, error) {
+	fs := t.getFs()
 
 	if err := fs.MkdirAll(filepath.Dir(destPath), 0o755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+		return "", fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	expectedChecksum, err := fetchChecksum(ctx, checksumURL)
+	ctx, err := t.upstreamContext(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to fetch checksum: %w", err)
+		return "", err
 	}
 
-	client := getRetryableClient()
+	url, expectedChecksum, err := t.downloadSource(ctx, version, goos, goarch)
+	if err != nil {
+		return "", err
+	}
+
+	partialPath := destPath + partialSuffix
+
+	actualChecksum, err := t.fetchPartial(ctx, url, partialPath, newChecksumHash(expectedChecksum))
+	if err != nil {
+		return "", err
+	}
+
+	if actualChecksum != expectedChecksum {
+		if removeErr := removePartial(fs, partialPath); removeErr != nil {
+			return "", removeErr
+		}
+
+		return "", fmt.Errorf("checksum mismatch: expected %s, got %s", expectedChecksum, actualChecksum)
+	}
+
+	if err := fs.Remove(partialPath + partialMetaSuffix); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if format := t.archiveFormat(url); format != ArchivePlain {
+		if err := extractArchive(fs, format, partialPath, destPath, t.archiveMember(version, goos, goarch), t.maxExtractSize()); err != nil {
+			return "", fmt.Errorf("failed to extract archive: %w", err)
+		}
+
+		// Remove the archive file after successful extraction
+		return requestURL(ctx, url), fs.Remove(partialPath)
+	}
+
+	if err := fs.Rename(partialPath, destPath); err != nil {
+		_ = fs.Remove(partialPath) //nolint:errcheck // cleanup on error path
+
+		return "", err
+	}
+
+	return requestURL(ctx, url), nil
+}
+
+// fetchPartial downloads url into partialPath and returns the checksum of the
+// complete content computed with hasher. An incomplete download left behind by an earlier attempt
+// is resumed with a Range request if the server confirms via If-Range that the
+// content is unchanged; otherwise the download starts over. A partial file that
+// already holds the complete content is verified without downloading it again.
+// The partial file is kept when the transfer fails so that the next attempt can
+// resume it.
+func (t *Tool) fetchPartial(ctx context.Context, url, partialPath string, hasher hash.Hash) (checksum string, err error) {
+	fs := t.getFs()
+	offset, meta := resumeOffset(fs, partialPath, url)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", meta.validator())
 	}
 
-	resp, err := client.StandardClient().Do(req)
+	resp, err := getRetryableClient(ctx).StandardClient().Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	defer func() {
-		if closeErr := resp.Body.Close(); closeErr != nil {
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
 			err = closeErr
 		}
 	}()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && validContentRange(resp, offset):
+		if err := t.writeProgress("Resuming download at %s\n", util.FormatBytes(offset)); err != nil {
+			return "", fmt.Errorf("failed to write progress: %w", err)
+		}
+	case resp.StatusCode == http.StatusOK:
+		offset, meta = 0, newPartialDownload(url, resp)
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		if completeContentRange(resp, offset) {
+			// An earlier attempt received everything, but was not verified.
+			return hashPartial(fs, partialPath, offset, hasher)
+		}
+
+		// The partial file does not match the content, download it from scratch.
+		if err := removePartial(fs, partialPath); err != nil {
+			return "", err
+		}
+
+		hasher.Reset()
 
-	tmpFile := destPath + ".tmp"
+		return t.fetchPartial(ctx, url, partialPath, hasher)
+	default:
+		if offset > 0 {
+			// The partial file cannot be continued, start from scratch next time.
+			_ = removePartial(fs, partialPath) //nolint:errcheck // cleanup on error path
+		}
 
-	out, err := fs.Create(tmpFile)
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	out, err := openPartial(fs, partialPath, offset, hasher)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	defer func() {
-		if removeErr := fs.Remove(tmpFile); removeErr != nil && err == nil {
-			err = removeErr
-		}
-	}()
+	if err := writePartialMeta(fs, partialPath, meta); err != nil {
+		_ = out.Close() //nolint:errcheck // close on error path
 
-	hasher := sha256.New()
+		return "", err
+	}
+
+	// Without a validator the partial file could not be resumed safely.
+	resumable := meta.validator() != ""
 
-	// Use progress reader if we have a progress writer and content length
+	// Report progress via callback, or draw a progress bar if we have a
+	// progress writer and content length
 	var reader io.Reader = resp.Body
 
 	var progReader *ProgressReader
 
-	if t.ProgressWriter != nil && resp.ContentLength > 0 {
-		progReader = NewProgressReader(resp.Body, resp.ContentLength, t.ProgressWriter)
+	switch {
+	case t.OnProgress != nil:
+		var total int64
+		if resp.ContentLength > 0 {
+			total = offset + resp.ContentLength
+		}
+
+		reader = &callbackReader{reader: resp.Body, report: t.OnProgress, total: total, current: offset}
+	case t.ProgressWriter != nil && resp.ContentLength > 0:
+		progReader = NewProgressReader(resp.Body, offset+resp.ContentLength, t.ProgressWriter)
+		progReader.current = offset
 		reader = progReader
 	}
 
-	writer := io.MultiWriter(out, hasher)
+	if _, err := io.Copy(io.MultiWriter(out, hasher), reader); err != nil {
+		_ = out.Close() //nolint:errcheck // close on error path
 
-	if _, err := io.Copy(writer, reader); err != nil {
-		if closeErr := out.Close(); closeErr != nil {
-			return closeErr
+		if !resumable {
+			_ = removePartial(fs, partialPath) //nolint:errcheck // cleanup on error path
 		}
 
-		return err
+		return "", err
 	}
 
 	// Finish progress display
@@ -98,106 +202,88 @@ func (t *Tool) download(ctx context.Context, destPath, version string) error {
 	}
 
 	if err := out.Close(); err != nil {
-		return err
+		return "", err
 	}
 
-	actualChecksum := fmt.Sprintf("%x", hasher.Sum(nil))
-	if actualChecksum != expectedChecksum {
-		return fmt.Errorf("checksum mismatch: expected %s, got %s", expectedChecksum, actualChecksum)
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
+
+// openPartial opens the partial file for writing. When resuming at offset, the
+// bytes already downloaded are fed into hasher and new data is appended;
+// otherwise the file is truncated.
+func openPartial(fs afero.Fs, partialPath string, offset int64, hasher io.Writer) (afero.File, error) {
+	if offset == 0 {
+		return fs.Create(partialPath)
 	}
 
-	// If the downloaded file is a tar.gz, extract it
-	if strings.HasSuffix(url, tarGzExtension) {
-		if err := extractTarGzFile(fs, tmpFile, destPath, t.Name); err != nil {
-			return fmt.Errorf("failed to extract archive: %w", err)
-		}
+	existing, err := fs.Open(partialPath)
+	if err != nil {
+		return nil, err
+	}
 
-		return nil
+	_, copyErr := io.CopyN(hasher, existing, offset)
+	if closeErr := existing.Close(); copyErr == nil {
+		copyErr = closeErr
 	}
 
-	return fs.Rename(tmpFile, destPath)
-}
+	if copyErr != nil {
+		return nil, fmt.Errorf("failed to read partial download: %w", copyErr)
+	}
 
-func fetchChecksum(ctx context.Context, url string) (string, error) {
-	client := getRetryableClient()
+	return fs.OpenFile(partialPath, os.O_WRONLY|os.O_APPEND, 0o644)
+}
 
-	data, err := fetchHTTPContent(ctx, client.StandardClient(), url)
+// hashPartial returns the checksum of the complete partial file of size bytes
+// computed with hasher.
+func hashPartial(fs afero.Fs, partialPath string, size int64, hasher hash.Hash) (string, error) {
+	out, err := openPartial(fs, partialPath, size, hasher)
 	if err != nil {
 		return "", err
 	}
 
-	checksumStr := strings.TrimSpace(string(data))
-
-	// Handle checksums in the format "checksum  filename" (like sha256sum output)
-	// Extract just the checksum part (first field)
-	if parts := strings.Fields(checksumStr); len(parts) > 0 {
-		return parts[0], nil
+	if err := out.Close(); err != nil {
+		return "", err
 	}
 
-	return checksumStr, nil
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
 }
 
-// extractTarGzFile extracts a single binary from a tar.gz file.
-// It looks for a file matching the tool name in the archive root.
-func extractTarGzFile(fs afero.Fs, archivePath, destPath, toolName string) (err error) {
-	// Open the archive
-	archiveFile, err := fs.Open(archivePath)
+// downloadSource returns the download URL and expected checksum of a version for
+// goos/goarch. Locked artifacts are verified against the lockfile, anything else
+// against the upstream checksum.
+func (t *Tool) downloadSource(ctx context.Context, version, goos, goarch string) (string, string, error) {
+	lock, err := t.getLock()
 	if err != nil {
-		return fmt.Errorf("failed to open archive: %w", err)
+		return "", "", err
 	}
 
-	defer func() {
-		closeErr := archiveFile.Close()
-		if err == nil && closeErr != nil {
-			err = fmt.Errorf("failed to close archive file: %w", closeErr)
-		}
-	}()
+	if artifact, ok := lock.Artifact(t.Name, version, goos, goarch); ok {
+		return artifact.URL, artifact.Checksum(), nil
+	}
 
-	gzr, err := gzip.NewReader(archiveFile)
+	checksum, err := t.upstreamChecksum(ctx, version, goos, goarch)
 	if err != nil {
-		return fmt.Errorf("failed to create gzip reader: %w", err)
+		return "", "", fmt.Errorf("failed to fetch checksum: %w", err)
 	}
 
-	defer func() {
-		closeErr := gzr.Close()
-		if err == nil && closeErr != nil {
-			err = fmt.Errorf("failed to close gzip reader: %w", closeErr)
-		}
-	}()
-
-	tr := tar.NewReader(gzr)
-
-	for {
-		header, err := tr.Next()
-		if err == io.EOF {
-			break
-		}
-
-		if err != nil {
-			return fmt.Errorf("failed to read tar: %w", err)
-		}
-
-		// Look for the binary matching the tool name
-		if filepath.Base(header.Name) == toolName {
-			out, err := fs.Create(destPath)
-			if err != nil {
-				return fmt.Errorf("failed to create output file: %w", err)
-			}
+	return t.DownloadURL(version, goos, goarch), checksum, nil
+}
 
-			if _, err := io.Copy(out, tr); err != nil {
-				_ = out.Close() //nolint:errcheck // close on error path
+func fetchChecksum(ctx context.Context, url string) (string, error) {
+	client := getRetryableClient(ctx)
 
-				return fmt.Errorf("failed to extract binary: %w", err)
-			}
+	data, err := fetchHTTPContent(ctx, client.StandardClient(), url)
+	if err != nil {
+		return "", err
+	}
 
-			if err := out.Close(); err != nil {
-				return err
-			}
+	checksumStr := strings.ToLower(strings.TrimSpace(string(data)))
 
-			// Remove the archive file after successful extraction
-			return fs.Remove(archivePath)
-		}
+	// Handle checksums in the format "checksum  filename" (like sha256sum output)
+	// Extract just the checksum part (first field)
+	if parts := strings.Fields(checksumStr); len(parts) > 0 {
+		return parts[0], nil
 	}
 
-	return fmt.Errorf("binary %s not found in archive", toolName)
+	return checksumStr, nil
 }
@@ -2,6 +2,7 @@
 package tool
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"net/http/httptest"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -127,6 +129,48 @@ func TestToolDownload(t *testing.T) { //nolint:maintidx // test function complex
 		assert.Equal(t, content, data)
 	})
 
+	t.Run("reports progress to OnProgress", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		content := []byte("fake binary content")
+		checksum := fmt.Sprintf("%x", sha256.Sum256(content))
+
+		checksumServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(checksum)) //nolint:errcheck // test helper
+		}))
+		defer checksumServer.Close()
+
+		binaryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, _ = w.Write(content) //nolint:errcheck // test helper
+		}))
+		defer binaryServer.Close()
+
+		var progressOutput bytes.Buffer
+
+		var current, total int64
+
+		tool := &Tool{
+			Name:           "testtool",
+			Fs:             fs,
+			ProgressWriter: &progressOutput,
+			OnProgress: func(c, n int64) {
+				current, total = c, n
+			},
+			DownloadURL: func(version, goos, goarch string) string {
+				return binaryServer.URL
+			},
+			ChecksumURL: func(version, goos, goarch string) string {
+				return checksumServer.URL
+			},
+		}
+
+		require.NoError(t, tool.download(context.Background(), testToolPath, testVersion))
+		assert.Equal(t, int64(len(content)), current)
+		assert.Equal(t, int64(len(content)), total)
+		// No progress bar is drawn when progress is reported via callback.
+		assert.Empty(t, progressOutput.String())
+	})
+
 	t.Run("fails on checksum mismatch", func(t *testing.T) {
 		fs := afero.NewMemMapFs()
 		content := []byte("fake binary content")
@@ -165,8 +209,8 @@ func TestToolDownload(t *testing.T) { //nolint:maintidx // test function complex
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "checksum mismatch")
 
-		// Verify temp file was cleaned up
-		tmpPath := destPath + ".tmp"
+		// Verify partial file was cleaned up
+		tmpPath := destPath + partialSuffix
 		exists, err := afero.Exists(fs, tmpPath)
 		require.NoError(t, err)
 		assert.False(t, exists)
@@ -493,8 +537,8 @@ func TestToolDownload(t *testing.T) { //nolint:maintidx // test function complex
 		err := tool.download(context.Background(), destPath, testVersion)
 		require.Error(t, err)
 
-		// Verify temp file was cleaned up
-		tmpPath := destPath + ".tmp"
+		// Verify partial file was cleaned up
+		tmpPath := destPath + partialSuffix
 		exists, err := afero.Exists(fs, tmpPath)
 		require.NoError(t, err)
 		assert.False(t, exists)
@@ -0,0 +1,307 @@
+package tool
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/spf13/afero"
+
+	"github.com/dennisklein/kdev/internal/util"
+)
+
+// GCConfig holds the tool cache garbage collection settings of a tools.yaml file.
+type GCConfig struct {
+	// MaxSize limits the total size of the tool cache, e.g. "500MiB".
+	MaxSize string `yaml:"maxSize"`
+	// Keep is the number of versions kept per tool, newest first.
+	Keep int `yaml:"keep"`
+	// UnusedFor removes versions that were not used for this long, e.g. "30d".
+	UnusedFor string `yaml:"unusedFor"`
+	// Auto runs garbage collection after each download.
+	Auto bool `yaml:"auto"`
+}
+
+// Policy parses the settings into a GCPolicy.
+func (c GCConfig) Policy() (GCPolicy, error) {
+	policy := GCPolicy{Keep: c.Keep}
+
+	if c.Keep < 0 {
+		return GCPolicy{}, fmt.Errorf("invalid keep %d: must not be negative", c.Keep)
+	}
+
+	if c.MaxSize != "" {
+		size, err := util.ParseBytes(c.MaxSize)
+		if err != nil {
+			return GCPolicy{}, fmt.Errorf("invalid maxSize: %w", err)
+		}
+
+		policy.MaxSize = size
+	}
+
+	if c.UnusedFor != "" {
+		unusedFor, err := util.ParseDuration(c.UnusedFor)
+		if err != nil {
+			return GCPolicy{}, fmt.Errorf("invalid unusedFor: %w", err)
+		}
+
+		policy.UnusedFor = unusedFor
+	}
+
+	return policy, nil
+}
+
+// GCPolicy selects cached versions for garbage collection. Rules with a zero
+// value are disabled; a version is removed if any rule selects it.
+type GCPolicy struct {
+	// MaxSize removes the least recently used versions until the cached
+	// versions of all tools fit.
+	MaxSize int64
+	// Keep removes all but the newest Keep versions of each tool.
+	Keep int
+	// UnusedFor removes versions that were not used for this long.
+	UnusedFor time.Duration
+}
+
+// IsZero reports whether all rules of the policy are disabled.
+func (p GCPolicy) IsZero() bool {
+	return p.MaxSize == 0 && p.Keep == 0 && p.UnusedFor == 0
+}
+
+// GCCandidate is a cached version selected for removal.
+type GCCandidate struct {
+	Tool    *Tool
+	Version CachedVersion
+	// LastUsed is when the version was last executed, or when it was cached
+	// if it was never executed through kdev.
+	LastUsed time.Time
+	// Reason describes the rule that selected the version.
+	Reason string
+}
+
+// PlanGC returns the cached versions of tools that policy selects for removal
+// at time now, least recently used first.
+func PlanGC(tools []*Tool, policy GCPolicy, now time.Time) ([]GCCandidate, error) {
+	return planGC(tools, policy, now, nil)
+}
+
+// planGC works like PlanGC, but never selects versions for which protected
+// returns true. Protected versions still count towards the cache size.
+func planGC(tools []*Tool, policy GCPolicy, now time.Time, protected func(*Tool, string) bool) ([]GCCandidate, error) {
+	var (
+		selected, evictable []GCCandidate
+		keptSize            int64
+	)
+
+	// Versions sharing a blob only free its space once all of them are removed.
+	keptLinks := make(map[string]int)
+	keep := func(v CachedVersion) {
+		key := v.storageKey()
+
+		keptLinks[key]++
+		if keptLinks[key] == 1 {
+			keptSize += v.Size
+		}
+	}
+
+	for _, t := range tools {
+		versions, err := t.CachedVersions()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cached versions for %s: %w", t.Name, err)
+		}
+
+		// Cached versions are sorted newest first.
+		for i, v := range versions {
+			candidate := GCCandidate{Tool: t, Version: v, LastUsed: t.lastUsed(v)}
+
+			switch {
+			case protected != nil && protected(t, v.Version):
+				keep(v)
+
+				continue
+			case policy.Keep > 0 && i >= policy.Keep:
+				candidate.Reason = fmt.Sprintf("more than %d versions cached", policy.Keep)
+			case policy.UnusedFor > 0 && now.Sub(candidate.LastUsed) > policy.UnusedFor:
+				candidate.Reason = "unused since " + candidate.LastUsed.Local().Format(time.DateOnly)
+			default:
+				keep(v)
+				evictable = append(evictable, candidate)
+
+				continue
+			}
+
+			selected = append(selected, candidate)
+		}
+	}
+
+	if policy.MaxSize > 0 && keptSize > policy.MaxSize {
+		sortLeastRecentlyUsed(evictable)
+
+		for _, candidate := range evictable {
+			if keptSize <= policy.MaxSize {
+				break
+			}
+
+			candidate.Reason = "cache larger than " + util.FormatBytes(policy.MaxSize)
+			selected = append(selected, candidate)
+
+			key := candidate.Version.storageKey()
+
+			keptLinks[key]--
+			if keptLinks[key] == 0 {
+				keptSize -= candidate.Version.Size
+			}
+		}
+	}
+
+	sortLeastRecentlyUsed(selected)
+
+	return selected, nil
+}
+
+// sortLeastRecentlyUsed sorts candidates by last use, oldest first. Ties are
+// broken by tool name and version, oldest version first.
+func sortLeastRecentlyUsed(candidates []GCCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+
+		if !a.LastUsed.Equal(b.LastUsed) {
+			return a.LastUsed.Before(b.LastUsed)
+		}
+
+		if a.Tool.Name != b.Tool.Name {
+			return a.Tool.Name < b.Tool.Name
+		}
+
+		return compareVersions(a.Version.Version, b.Version.Version) < 0
+	})
+}
+
+// CollectGarbage removes the candidates from the cache. Versions in use by
+// other kdev processes are skipped. It returns the removed candidates.
+func CollectGarbage(candidates []GCCandidate) ([]GCCandidate, error) {
+	removed := make([]GCCandidate, 0, len(candidates))
+
+	for _, candidate := range candidates {
+		ok, err := candidate.Tool.removeIdleVersion(candidate.Version.Version)
+		if err != nil {
+			return removed, fmt.Errorf("failed to remove %s %s: %w", candidate.Tool.Name, candidate.Version.Version, err)
+		}
+
+		if ok {
+			removed = append(removed, candidate)
+		}
+	}
+
+	if len(removed) == 0 {
+		return removed, nil
+	}
+
+	return removed, pruneBlobs(removed[0].Tool.getFs())
+}
+
+// gcVersions returns the cached versions of candidates.
+func gcVersions(candidates []GCCandidate) []CachedVersion {
+	versions := make([]CachedVersion, 0, len(candidates))
+	for _, candidate := range candidates {
+		versions = append(versions, candidate.Version)
+	}
+
+	return versions
+}
+
+// lastUsed returns when a cached version was last executed, falling back to
+// when it was cached for versions never executed through kdev.
+func (t *Tool) lastUsed(v CachedVersion) time.Time {
+	if v.Metadata != nil {
+		if !v.Metadata.LastUsed.IsZero() {
+			return v.Metadata.LastUsed
+		}
+
+		if !v.Metadata.DownloadedAt.IsZero() {
+			return v.Metadata.DownloadedAt
+		}
+	}
+
+	info, err := t.getFs().Stat(v.Path)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
+
+// autoGC collects garbage in the cache of all tools after version was
+// downloaded, if enabled in the user configuration. The downloaded version is
+// never removed.
+func (t *Tool) autoGC(version string) error {
+	user, err := t.getUserConfig()
+	if err != nil {
+		return err
+	}
+
+	if !user.GC.Auto {
+		return nil
+	}
+
+	policy, err := user.GC.Policy()
+	if err != nil || policy.IsZero() {
+		return err
+	}
+
+	tools, err := t.cachedTools()
+	if err != nil {
+		return err
+	}
+
+	candidates, err := planGC(tools, policy, time.Now(), func(other *Tool, v string) bool {
+		return other == t && v == version
+	})
+	if err != nil {
+		return err
+	}
+
+	removed, err := CollectGarbage(candidates)
+	if err != nil || len(removed) == 0 {
+		return err
+	}
+
+	reclaimed, err := ReclaimedSize(t.getFs(), gcVersions(removed))
+	if err != nil {
+		return err
+	}
+
+	return t.writeProgress("Removed %d unused cached versions (%s)\n", len(removed), util.FormatBytes(reclaimed))
+}
+
+// cachedTools returns a tool for each tool directory in the cache, so that
+// garbage collection also covers tools unknown to this kdev binary.
+func (t *Tool) cachedTools() ([]*Tool, error) {
+	fs := t.getFs()
+
+	dataDir, err := DataDir(fs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data directory: %w", err)
+	}
+
+	entries, err := afero.ReadDir(fs, filepath.Join(dataDir, "kdev"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	tools := make([]*Tool, 0, len(entries))
+
+	for _, entry := range entries {
+		switch {
+		case !entry.IsDir() || isBlobStoreDir(entry.Name()):
+			continue
+		case entry.Name() == t.Name:
+			tools = append(tools, t)
+		default:
+			tools = append(tools, &Tool{Name: entry.Name(), Fs: t.Fs, ProgressWriter: t.ProgressWriter})
+		}
+	}
+
+	return tools, nil
+}
@@ -0,0 +1,263 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cacheGCTestVersion caches a version of the named tool with size bytes that
+// was last used at lastUsed.
+func cacheGCTestVersion(t *testing.T, fs afero.Fs, name, version string, size int, lastUsed time.Time) string {
+	t.Helper()
+
+	binPath := filepath.Join(testDataDir, "kdev", name, version, name)
+	require.NoError(t, afero.WriteFile(fs, binPath, make([]byte, size), 0o755))
+	require.NoError(t, writeMetadata(fs, binPath, &VersionMetadata{LastUsed: lastUsed}))
+
+	return binPath
+}
+
+// gcCandidateNames returns the candidates as "tool@version" in order.
+func gcCandidateNames(candidates []GCCandidate) []string {
+	names := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		names = append(names, c.Tool.Name+"@"+c.Version.Version)
+	}
+
+	return names
+}
+
+func TestGCConfigPolicy(t *testing.T) {
+	t.Run("parses sizes and durations", func(t *testing.T) {
+		policy, err := GCConfig{MaxSize: "500MiB", Keep: 2, UnusedFor: "30d"}.Policy()
+		require.NoError(t, err)
+		assert.Equal(t, GCPolicy{MaxSize: 500 << 20, Keep: 2, UnusedFor: 30 * 24 * time.Hour}, policy)
+		assert.False(t, policy.IsZero())
+	})
+
+	t.Run("is zero when unset", func(t *testing.T) {
+		policy, err := GCConfig{Auto: true}.Policy()
+		require.NoError(t, err)
+		assert.True(t, policy.IsZero())
+	})
+
+	t.Run("rejects invalid values", func(t *testing.T) {
+		_, err := GCConfig{MaxSize: "lots"}.Policy()
+		require.ErrorContains(t, err, "invalid maxSize")
+
+		_, err = GCConfig{UnusedFor: "a while"}.Policy()
+		require.ErrorContains(t, err, "invalid unusedFor")
+
+		_, err = GCConfig{Keep: -1}.Policy()
+		require.ErrorContains(t, err, "invalid keep -1")
+	})
+
+	t.Run("is validated when loading the user configuration", func(t *testing.T) {
+		cfg, err := loadTestToolsConfig(t, "gc:\n  maxSize: 1 GiB\n  auto: true\n")
+		require.NoError(t, err)
+		assert.Equal(t, GCConfig{MaxSize: "1 GiB", Auto: true}, cfg.GC)
+
+		_, err = loadTestToolsConfig(t, "gc:\n  unusedFor: 1 month\n")
+		require.ErrorContains(t, err, "invalid gc settings")
+	})
+
+	t.Run("is not a project setting", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, ProjectConfigFile)
+		require.NoError(t, afero.WriteFile(fs, path, []byte("gc:\n  keep: 1\n"), 0o644))
+
+		_, err := LoadProjectConfig(fs, testProjectDir)
+		require.ErrorContains(t, err, `unknown setting "gc"`)
+	})
+}
+
+func TestPlanGC(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	fs := afero.NewMemMapFs()
+
+	cacheGCTestVersion(t, fs, "kind", "v0.20.0", 100, now.Add(-1*day))
+	cacheGCTestVersion(t, fs, "kind", "v0.19.0", 100, now.Add(-40*day))
+	cacheGCTestVersion(t, fs, "kind", "v0.18.0", 100, now.Add(-10*day))
+	cacheGCTestVersion(t, fs, "kubectl", "v1.31.0", 300, now.Add(-2*day))
+	cacheGCTestVersion(t, fs, "kubectl", "v1.30.0", 300, now.Add(-5*day))
+
+	tools := []*Tool{{Name: "kind", Fs: fs}, {Name: "kubectl", Fs: fs}}
+
+	tests := []struct {
+		name   string
+		policy GCPolicy
+		want   []string
+	}{
+		{name: "selects nothing without rules", policy: GCPolicy{}, want: []string{}},
+		{name: "keeps the newest versions", policy: GCPolicy{Keep: 1}, want: []string{"kind@v0.19.0", "kind@v0.18.0", "kubectl@v1.30.0"}},
+		{name: "removes unused versions", policy: GCPolicy{UnusedFor: 30 * day}, want: []string{"kind@v0.19.0"}},
+		{name: "removes least recently used versions above the size limit", policy: GCPolicy{MaxSize: 500}, want: []string{"kind@v0.19.0", "kind@v0.18.0", "kubectl@v1.30.0"}},
+		{name: "selects nothing below the size limit", policy: GCPolicy{MaxSize: 900}, want: []string{}},
+		{name: "combines rules", policy: GCPolicy{Keep: 2, MaxSize: 500}, want: []string{"kind@v0.19.0", "kind@v0.18.0", "kubectl@v1.30.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, err := PlanGC(tools, tt.policy, now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, gcCandidateNames(candidates))
+		})
+	}
+
+	t.Run("explains the selection", func(t *testing.T) {
+		candidates, err := PlanGC(tools, GCPolicy{Keep: 2, UnusedFor: 30 * day, MaxSize: 700}, now)
+		require.NoError(t, err)
+		require.Len(t, candidates, 2)
+
+		assert.Equal(t, "unused since "+now.Add(-40*day).Local().Format(time.DateOnly), candidates[0].Reason)
+		assert.Equal(t, "kind@v0.18.0", gcCandidateNames(candidates)[1])
+		assert.Equal(t, "more than 2 versions cached", candidates[1].Reason)
+
+		candidates, err = PlanGC(tools, GCPolicy{MaxSize: 800}, now)
+		require.NoError(t, err)
+		require.Len(t, candidates, 1)
+		assert.Equal(t, "cache larger than 800 B", candidates[0].Reason)
+		assert.True(t, candidates[0].LastUsed.Equal(now.Add(-40*day)))
+	})
+
+	t.Run("never selects protected versions", func(t *testing.T) {
+		candidates, err := planGC(tools, GCPolicy{MaxSize: 500}, now, func(tool *Tool, version string) bool {
+			return tool.Name == "kind" && version == "v0.19.0"
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"kind@v0.18.0", "kubectl@v1.30.0"}, gcCandidateNames(candidates))
+	})
+}
+
+func TestLastUsed(t *testing.T) {
+	used := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	downloaded := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	modified := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, testToolPath, []byte("binary"), 0o755))
+	require.NoError(t, fs.Chtimes(testToolPath, modified, modified))
+
+	tool := &Tool{Name: "testtool", Fs: fs}
+
+	assert.Equal(t, used, tool.lastUsed(CachedVersion{Path: testToolPath, Metadata: &VersionMetadata{LastUsed: used, DownloadedAt: downloaded}}))
+	assert.Equal(t, downloaded, tool.lastUsed(CachedVersion{Path: testToolPath, Metadata: &VersionMetadata{DownloadedAt: downloaded}}))
+	assert.True(t, modified.Equal(tool.lastUsed(CachedVersion{Path: testToolPath})))
+}
+
+func TestCollectGarbage(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+
+	fs := afero.NewMemMapFs()
+	now := time.Now()
+	oldPath := cacheGCTestVersion(t, fs, "testtool", "v0.9.0", 10, now.Add(-time.Hour))
+	newPath := cacheGCTestVersion(t, fs, "testtool", testVersion, 10, now)
+
+	tool := &Tool{Name: "testtool", Fs: fs}
+
+	candidates, err := PlanGC([]*Tool{tool}, GCPolicy{Keep: 1}, now)
+	require.NoError(t, err)
+
+	removed, err := CollectGarbage(candidates)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"testtool@v0.9.0"}, gcCandidateNames(removed))
+
+	exists, err := afero.DirExists(fs, filepath.Dir(oldPath))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	exists, err = afero.Exists(fs, newPath)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestAutoGC(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+
+	content := []byte("downloaded binary")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/testtool.sha256" {
+			_, _ = fmt.Fprintf(w, "%x", sha256.Sum256(content)) //nolint:errcheck // test helper
+
+			return
+		}
+
+		_, _ = w.Write(content) //nolint:errcheck // test helper
+	}))
+	defer server.Close()
+
+	newTool := func(fs afero.Fs, progress *bytes.Buffer, gc GCConfig) *Tool {
+		return &Tool{
+			Name:           "testtool",
+			Fs:             fs,
+			ProgressWriter: progress,
+			VersionFunc: func(context.Context) (string, error) {
+				return testVersion, nil
+			},
+			DownloadURL: func(version, goos, goarch string) string {
+				return server.URL + "/testtool"
+			},
+			ChecksumURL: func(version, goos, goarch string) string {
+				return server.URL + "/testtool.sha256"
+			},
+			Project:    &ProjectConfig{},
+			UserConfig: &ToolsConfig{GC: gc},
+		}
+	}
+
+	t.Run("collects garbage of all tools after downloads", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		old := time.Now().Add(-time.Hour)
+		newerPath := cacheGCTestVersion(t, fs, "testtool", "v2.0.0", 10, old)
+		olderPath := cacheGCTestVersion(t, fs, "testtool", "v0.9.0", 10, old)
+		otherPath := cacheGCTestVersion(t, fs, "othertool", "v0.1.0", 10, old)
+		otherNewPath := cacheGCTestVersion(t, fs, "othertool", "v0.2.0", 10, old)
+
+		var progress bytes.Buffer
+
+		require.NoError(t, newTool(fs, &progress, GCConfig{Keep: 1, Auto: true}).Download(context.Background()))
+		assert.Contains(t, progress.String(), "Removed 2 unused cached versions (20 B)")
+
+		for path, want := range map[string]bool{
+			cachedTestBinary(testVersion): true,
+			newerPath:                     true,
+			olderPath:                     false,
+			otherPath:                     false,
+			otherNewPath:                  true,
+		} {
+			exists, err := afero.Exists(fs, path)
+			require.NoError(t, err)
+			assert.Equal(t, want, exists, path)
+		}
+	})
+
+	t.Run("is disabled by default", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		olderPath := cacheGCTestVersion(t, fs, "testtool", "v0.9.0", 10, time.Now().Add(-time.Hour))
+
+		var progress bytes.Buffer
+
+		require.NoError(t, newTool(fs, &progress, GCConfig{Keep: 1}).Download(context.Background()))
+		assert.NotContains(t, progress.String(), "Removed")
+
+		exists, err := afero.Exists(fs, olderPath)
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+}
@@ -0,0 +1,255 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	// GitHubTokenFileEnvVar names a file holding the token for GitHub API
+	// requests, used if neither GITHUB_TOKEN nor GH_TOKEN is set.
+	GitHubTokenFileEnvVar = "KDEV_GITHUB_TOKEN_FILE"
+
+	// githubAPIURL is the base URL of the GitHub REST API.
+	githubAPIURL = "https://api.github.com/"
+
+	// githubReleasesPerPage is the page size used when listing GitHub releases.
+	githubReleasesPerPage = 100
+
+	// maxRateLimitWait is the longest time to wait for a GitHub rate limit to
+	// reset before giving up with an error.
+	maxRateLimitWait = time.Minute
+)
+
+// githubTokenEnvVars are the environment variables checked for a GitHub token in order.
+var githubTokenEnvVars = []string{"GITHUB_TOKEN", "GH_TOKEN"}
+
+// githubToken returns the token for GitHub API requests from the environment
+// or the file named by KDEV_GITHUB_TOKEN_FILE. It returns an empty string if
+// none is configured.
+func githubToken() (string, error) {
+	for _, name := range githubTokenEnvVars {
+		if token := strings.TrimSpace(os.Getenv(name)); token != "" {
+			return token, nil
+		}
+	}
+
+	path := os.Getenv(GitHubTokenFileEnvVar)
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read GitHub token file: %w", err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// newGitHubClient returns a GitHub API client that authenticates with the
+// configured token and sends its requests through the retryable HTTP client
+// for ctx, which waits for rate limits that reset within maxRateLimitWait.
+func newGitHubClient(ctx context.Context) (*github.Client, error) {
+	token, err := githubToken()
+	if err != nil {
+		return nil, err
+	}
+
+	retryClient := getRetryableClient(ctx)
+	retryClient.CheckRetry = githubCheckRetry
+	retryClient.Backoff = githubBackoff
+	// Pass the last response on, so that go-github reports rate limits with their reset time.
+	retryClient.ErrorHandler = retryablehttp.PassthroughErrorHandler
+
+	client := github.NewClient(retryClient.StandardClient())
+	if token != "" {
+		client = client.WithAuthToken(token)
+	}
+
+	return client, nil
+}
+
+// rateLimitWait returns how long to wait before retrying a response rejected
+// by a GitHub rate limit, based on Retry-After for secondary limits or
+// X-RateLimit-Reset for the primary limit.
+func rateLimitWait(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(time.Until(time.Unix(reset, 0)), 0), true
+}
+
+// githubCheckRetry retries rate limited requests if the limit resets soon
+// enough, and anything else like the default policy.
+func githubCheckRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if err == nil && resp != nil {
+		if wait, limited := rateLimitWait(resp); limited {
+			return wait <= maxRateLimitWait && ctx.Err() == nil, nil
+		}
+	}
+
+	return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+}
+
+// githubBackoff waits for rate limits to reset, and otherwise backs off like
+// the default policy.
+func githubBackoff(minWait, maxWait time.Duration, attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, limited := rateLimitWait(resp); limited {
+			return wait
+		}
+	}
+
+	return retryablehttp.DefaultBackoff(minWait, maxWait, attempt, resp)
+}
+
+// explainGitHubError adds the reset time and a hint at authentication to
+// GitHub rate limit errors.
+func explainGitHubError(err error) error {
+	hint := ""
+	if token, tokenErr := githubToken(); tokenErr == nil && token == "" {
+		hint = fmt.Sprintf("; set GITHUB_TOKEN, GH_TOKEN or %s to raise the limit", GitHubTokenFileEnvVar)
+	}
+
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) {
+		return fmt.Errorf("GitHub API rate limit exceeded, resets at %s%s: %w", rateErr.Rate.Reset.Format(time.TimeOnly), hint, err)
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		return fmt.Errorf("GitHub API secondary rate limit exceeded, retry after %s%s: %w", abuseErr.GetRetryAfter(), hint, err)
+	}
+
+	return err
+}
+
+// githubLatestVersion returns the tag of the latest release of a GitHub repository.
+func githubLatestVersion(ctx context.Context, owner, repo string) (string, error) {
+	client, err := newGitHubClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	release, _, err := client.Repositories.GetLatestRelease(ctx, owner, repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest %s release: %w", repo, explainGitHubError(err))
+	}
+
+	return release.GetTagName(), nil
+}
+
+// githubReleaseVersions lists the release tags of a GitHub repository.
+func githubReleaseVersions(ctx context.Context, owner, repo string) ([]string, error) {
+	client, err := newGitHubClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return githubReleaseVersionsWithClient(ctx, client, owner, repo)
+}
+
+// githubReleaseVersionsWithClient lists the tag names of all published
+// (non-draft) releases of a GitHub repository, following pagination.
+func githubReleaseVersionsWithClient(ctx context.Context, client *github.Client, owner, repo string) ([]string, error) {
+	releases, err := githubReleasesWithClient(ctx, client, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(releases))
+	for _, release := range releases {
+		versions = append(versions, release.GetTagName())
+	}
+
+	return versions, nil
+}
+
+// githubChannelVersion returns the newest release of a GitHub repository in channel.
+func githubChannelVersion(ctx context.Context, owner, repo string, channel Channel) (string, error) {
+	client, err := newGitHubClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return githubChannelVersionWithClient(ctx, client, owner, repo, channel)
+}
+
+// githubChannelVersionWithClient returns the newest release in channel. Releases
+// flagged as prerelease on GitHub are only considered in prerelease channels.
+func githubChannelVersionWithClient(ctx context.Context, client *github.Client, owner, repo string, channel Channel) (string, error) {
+	releases, err := githubReleasesWithClient(ctx, client, owner, repo)
+	if err != nil {
+		return "", err
+	}
+
+	versions := make([]string, 0, len(releases))
+
+	for _, release := range releases {
+		if release.GetPrerelease() && !channel.Prerelease {
+			continue
+		}
+
+		versions = append(versions, release.GetTagName())
+	}
+
+	version, ok := highestInChannel(versions, channel)
+	if !ok {
+		return "", fmt.Errorf("no %s/%s release in channel %q", owner, repo, channel)
+	}
+
+	return version, nil
+}
+
+// githubReleasesWithClient lists all published (non-draft) releases of a
+// GitHub repository, following pagination.
+func githubReleasesWithClient(ctx context.Context, client *github.Client, owner, repo string) ([]*github.RepositoryRelease, error) {
+	var releases []*github.RepositoryRelease
+
+	opts := &github.ListOptions{PerPage: githubReleasesPerPage}
+
+	for {
+		page, resp, err := client.Repositories.ListReleases(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s/%s releases: %w", owner, repo, explainGitHubError(err))
+		}
+
+		for _, release := range page {
+			if release.GetDraft() {
+				continue
+			}
+
+			releases = append(releases, release)
+		}
+
+		if resp.NextPage == 0 {
+			return releases, nil
+		}
+
+		opts.Page = resp.NextPage
+	}
+}
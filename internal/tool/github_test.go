@@ -0,0 +1,284 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGithubReleaseVersions(t *testing.T) {
+	t.Run("lists releases across pages and skips drafts", func(t *testing.T) {
+		var server *httptest.Server
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/repos/cilium/cilium-cli/releases", r.URL.Path)
+
+			var releases []*github.RepositoryRelease
+
+			if r.URL.Query().Get("page") == "2" {
+				releases = []*github.RepositoryRelease{
+					{TagName: github.String("v0.15.0")},
+				}
+			} else {
+				w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=2>; rel="next"`, server.URL, r.URL.Path))
+
+				releases = []*github.RepositoryRelease{
+					{TagName: github.String("v0.17.0"), Draft: github.Bool(true)},
+					{TagName: github.String("v0.16.1"), Prerelease: github.Bool(true)},
+					{TagName: github.String("v0.16.0")},
+				}
+			}
+
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(releases) //nolint:errcheck // test helper
+		}))
+		defer server.Close()
+
+		client := github.NewClient(nil)
+		client.BaseURL = mustParseURL(server.URL + "/")
+
+		versions, err := githubReleaseVersionsWithClient(context.Background(), client, "cilium", "cilium-cli")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v0.16.1", "v0.16.0", "v0.15.0"}, versions)
+	})
+
+	t.Run("handles API errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{"message": "Not Found"}) //nolint:errcheck // test helper
+		}))
+		defer server.Close()
+
+		client := github.NewClient(nil)
+		client.BaseURL = mustParseURL(server.URL + "/")
+
+		_, err := githubReleaseVersionsWithClient(context.Background(), client, "cilium", "cilium-cli")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to list cilium/cilium-cli releases")
+	})
+}
+
+func TestGithubChannelVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		releases := []*github.RepositoryRelease{
+			{TagName: github.String("v0.17.0-pre.1"), Prerelease: github.Bool(true)},
+			{TagName: github.String("v0.16.2"), Prerelease: github.Bool(true)},
+			{TagName: github.String("v0.16.1")},
+			{TagName: github.String("v0.15.9")},
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(releases) //nolint:errcheck // test helper
+	}))
+	defer server.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL = mustParseURL(server.URL + "/")
+
+	tests := []struct {
+		channel Channel
+		want    string
+	}{
+		{channel: Channel{}, want: "v0.16.1"},
+		{channel: Channel{Prerelease: true}, want: "v0.17.0-pre.1"},
+		{channel: Channel{Prerelease: true, Minor: "0.16"}, want: "v0.16.2"},
+		{channel: Channel{Minor: "0.15"}, want: "v0.15.9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.channel.String(), func(t *testing.T) {
+			version, err := githubChannelVersionWithClient(context.Background(), client, "cilium", "cilium-cli", tt.channel)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, version)
+		})
+	}
+
+	t.Run("fails when channel has no release", func(t *testing.T) {
+		_, err := githubChannelVersionWithClient(context.Background(), client, "cilium", "cilium-cli", Channel{Minor: "0.14"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `no cilium/cilium-cli release in channel "stable-0.14"`)
+	})
+}
+
+// clearGitHubTokenEnv unsets all sources of a GitHub token for the test.
+func clearGitHubTokenEnv(t *testing.T) {
+	t.Helper()
+
+	for _, name := range append(githubTokenEnvVars, GitHubTokenFileEnvVar) {
+		t.Setenv(name, "")
+	}
+}
+
+func TestGithubToken(t *testing.T) {
+	t.Run("prefers GITHUB_TOKEN over GH_TOKEN", func(t *testing.T) {
+		clearGitHubTokenEnv(t)
+		t.Setenv("GITHUB_TOKEN", "token-a")
+		t.Setenv("GH_TOKEN", "token-b")
+
+		token, err := githubToken()
+		require.NoError(t, err)
+		assert.Equal(t, "token-a", token)
+	})
+
+	t.Run("reads the token file", func(t *testing.T) {
+		clearGitHubTokenEnv(t)
+
+		path := filepath.Join(t.TempDir(), "token")
+		require.NoError(t, os.WriteFile(path, []byte("token-c\n"), 0o600))
+		t.Setenv(GitHubTokenFileEnvVar, path)
+
+		token, err := githubToken()
+		require.NoError(t, err)
+		assert.Equal(t, "token-c", token)
+	})
+
+	t.Run("fails on a missing token file", func(t *testing.T) {
+		clearGitHubTokenEnv(t)
+		t.Setenv(GitHubTokenFileEnvVar, filepath.Join(t.TempDir(), "missing"))
+
+		_, err := githubToken()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to read GitHub token file")
+	})
+
+	t.Run("returns nothing without configuration", func(t *testing.T) {
+		clearGitHubTokenEnv(t)
+
+		token, err := githubToken()
+		require.NoError(t, err)
+		assert.Empty(t, token)
+	})
+}
+
+func TestRateLimitWait(t *testing.T) {
+	newResponse := func(status int, headers map[string]string) *http.Response {
+		resp := &http.Response{StatusCode: status, Header: http.Header{}}
+		for key, value := range headers {
+			resp.Header.Set(key, value)
+		}
+
+		return resp
+	}
+
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	wait, limited := rateLimitWait(newResponse(http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}))
+	assert.True(t, limited)
+	assert.InDelta(t, time.Hour.Seconds(), wait.Seconds(), 5)
+
+	wait, limited = rateLimitWait(newResponse(http.StatusTooManyRequests, map[string]string{"Retry-After": "30"}))
+	assert.True(t, limited)
+	assert.Equal(t, 30*time.Second, wait)
+
+	wait, limited = rateLimitWait(newResponse(http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1"}))
+	assert.True(t, limited)
+	assert.Equal(t, time.Duration(0), wait)
+
+	_, limited = rateLimitWait(newResponse(http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "10"}))
+	assert.False(t, limited)
+
+	_, limited = rateLimitWait(newResponse(http.StatusOK, map[string]string{"X-RateLimit-Remaining": "0"}))
+	assert.False(t, limited)
+}
+
+func TestNewGitHubClient(t *testing.T) {
+	// newRateLimitServer rejects the first requests with a rate limit resetting at reset.
+	newRateLimitServer := func(t *testing.T, limited int32, reset time.Time) (*httptest.Server, *atomic.Int32, *atomic.Value) {
+		t.Helper()
+
+		var (
+			requests atomic.Int32
+			auth     atomic.Value
+		)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth.Store(r.Header.Get("Authorization"))
+
+			if requests.Add(1) <= limited {
+				w.Header().Set("X-RateLimit-Limit", "60")
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+				w.WriteHeader(http.StatusForbidden)
+				_ = json.NewEncoder(w).Encode(map[string]string{"message": "API rate limit exceeded"}) //nolint:errcheck // test helper
+
+				return
+			}
+
+			_ = json.NewEncoder(w).Encode([]*github.RepositoryRelease{{TagName: github.String("v0.25.0")}}) //nolint:errcheck // test helper
+		}))
+		t.Cleanup(server.Close)
+
+		return server, &requests, &auth
+	}
+
+	newClient := func(t *testing.T, server *httptest.Server) *github.Client {
+		t.Helper()
+
+		client, err := newGitHubClient(context.Background())
+		require.NoError(t, err)
+
+		client.BaseURL = mustParseURL(server.URL + "/")
+
+		return client
+	}
+
+	t.Run("authenticates with the token", func(t *testing.T) {
+		clearGitHubTokenEnv(t)
+		t.Setenv("GH_TOKEN", "secret")
+
+		server, _, auth := newRateLimitServer(t, 0, time.Now())
+
+		_, err := githubReleaseVersionsWithClient(context.Background(), newClient(t, server), "kubernetes-sigs", "kind")
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer secret", auth.Load())
+	})
+
+	t.Run("waits for a rate limit resetting soon", func(t *testing.T) {
+		clearGitHubTokenEnv(t)
+
+		server, requests, auth := newRateLimitServer(t, 1, time.Now())
+
+		versions, err := githubReleaseVersionsWithClient(context.Background(), newClient(t, server), "kubernetes-sigs", "kind")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v0.25.0"}, versions)
+		assert.Equal(t, int32(2), requests.Load())
+		assert.Equal(t, "", auth.Load())
+	})
+
+	t.Run("explains a rate limit resetting later", func(t *testing.T) {
+		clearGitHubTokenEnv(t)
+
+		reset := time.Now().Add(time.Hour)
+		server, requests, _ := newRateLimitServer(t, 1, reset)
+
+		_, err := githubReleaseVersionsWithClient(context.Background(), newClient(t, server), "kubernetes-sigs", "kind")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "GitHub API rate limit exceeded, resets at "+reset.Format(time.TimeOnly))
+		assert.Contains(t, err.Error(), "set GITHUB_TOKEN, GH_TOKEN or "+GitHubTokenFileEnvVar)
+		assert.Equal(t, int32(1), requests.Load())
+
+		var rateErr *github.RateLimitError
+		assert.ErrorAs(t, err, &rateErr)
+	})
+
+	t.Run("fails on an unreadable token file", func(t *testing.T) {
+		clearGitHubTokenEnv(t)
+		t.Setenv(GitHubTokenFileEnvVar, filepath.Join(t.TempDir(), "missing"))
+
+		_, err := newGitHubClient(context.Background())
+		require.Error(t, err)
+	})
+}
@@ -15,14 +15,41 @@ type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
-// getRetryableClient creates a configured retryable HTTP client for production use.
-func getRetryableClient() *retryablehttp.Client {
+// httpClientKey is the context key for the HTTP client of upstream requests.
+type httpClientKey struct{}
+
+// withHTTPClient returns a context whose upstream requests are sent by client.
+func withHTTPClient(ctx context.Context, client *http.Client) context.Context {
+	if client == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, httpClientKey{}, client)
+}
+
+// getRetryableClient creates a configured retryable HTTP client for the
+// upstream requests made with ctx. Requests are sent by the client carried by
+// ctx, if any, and rewritten by the mirror rules of their context.
+func getRetryableClient(ctx context.Context) *retryablehttp.Client {
 	client := retryablehttp.NewClient()
 	client.RetryMax = 3
 	client.RetryWaitMin = 1 * time.Second
 	client.RetryWaitMax = 10 * time.Second
 	client.Logger = nil // Disable logging to avoid cluttering output
 
+	if base, ok := ctx.Value(httpClientKey{}).(*http.Client); ok {
+		// Copy the client, so that wrapping its transport does not modify it.
+		copied := *base
+		client.HTTPClient = &copied
+	}
+
+	transport := client.HTTPClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	client.HTTPClient.Transport = &mirrorTransport{base: transport}
+
 	return client
 }
 
@@ -5,28 +5,34 @@ import (
 	"fmt"
 	"io"
 
-	"github.com/google/go-github/v58/github"
+	"github.com/Masterminds/semver/v3"
 )
 
+// kindReleaseURL is the base URL of the kind release artifacts.
+const kindReleaseURL = "https://github.com/kubernetes-sigs/kind/releases/download"
+
 // NewKind creates a Tool configured for kind (Kubernetes in Docker).
 func NewKind(progress io.Writer) *Tool {
 	return NewToolFromConfig(kindConfig(), progress)
 }
 
-func kindVersion(ctx context.Context) (version string, err error) {
-	client := github.NewClient(nil)
+func kindVersion(ctx context.Context) (string, error) {
+	return githubLatestVersion(ctx, "kubernetes-sigs", "kind")
+}
 
-	release, _, err := client.Repositories.GetLatestRelease(ctx, "kubernetes-sigs", "kind")
-	if err != nil {
-		return "", fmt.Errorf("failed to get latest kind release: %w", err)
-	}
+// kindVersions lists all published kind releases.
+func kindVersions(ctx context.Context, _ *semver.Constraints) ([]string, error) {
+	return githubReleaseVersions(ctx, "kubernetes-sigs", "kind")
+}
 
-	return release.GetTagName(), nil
+// kindChannelVersion returns the newest kind release in channel.
+func kindChannelVersion(ctx context.Context, channel Channel) (string, error) {
+	return githubChannelVersion(ctx, "kubernetes-sigs", "kind", channel)
 }
 
 func kindDownloadURL(version, goos, goarch string) string {
-	return fmt.Sprintf("https://github.com/kubernetes-sigs/kind/releases/download/%s/kind-%s-%s",
-		version, goos, goarch)
+	return fmt.Sprintf("%s/%s/kind-%s-%s",
+		kindReleaseURL, version, goos, goarch)
 }
 
 func kindChecksumURL(version, goos, goarch string) string {
@@ -0,0 +1,310 @@
+package tool
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// clusterVersionTimeout bounds the request to the API server's /version endpoint.
+const clusterVersionTimeout = 5 * time.Second
+
+// errNoCurrentContext is returned when the kubeconfig does not select a context.
+var errNoCurrentContext = errors.New("no current context set in kubeconfig")
+
+// kubeconfig is the subset of a kubeconfig file needed to reach the API server.
+type kubeconfig struct {
+	CurrentContext string              `yaml:"current-context"`
+	Contexts       []kubeconfigContext `yaml:"contexts"`
+	Clusters       []kubeconfigCluster `yaml:"clusters"`
+	Users          []kubeconfigUser    `yaml:"users"`
+}
+
+// kubeconfigContext is a named context entry of a kubeconfig.
+type kubeconfigContext struct {
+	Name    string `yaml:"name"`
+	Context struct {
+		Cluster string `yaml:"cluster"`
+		User    string `yaml:"user"`
+	} `yaml:"context"`
+}
+
+// kubeconfigCluster is a named cluster entry of a kubeconfig.
+type kubeconfigCluster struct {
+	Name    string `yaml:"name"`
+	Cluster struct {
+		Server                   string `yaml:"server"`
+		CertificateAuthority     string `yaml:"certificate-authority"`
+		CertificateAuthorityData string `yaml:"certificate-authority-data"`
+		InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+	} `yaml:"cluster"`
+}
+
+// kubeconfigUser is a named user entry of a kubeconfig.
+type kubeconfigUser struct {
+	Name string `yaml:"name"`
+	User struct {
+		Token                 string `yaml:"token"`
+		ClientCertificate     string `yaml:"client-certificate"`
+		ClientCertificateData string `yaml:"client-certificate-data"`
+		ClientKey             string `yaml:"client-key"`
+		ClientKeyData         string `yaml:"client-key-data"`
+	} `yaml:"user"`
+}
+
+// clusterEndpoint describes how to reach the API server of the current context.
+type clusterEndpoint struct {
+	Context  string
+	Server   string
+	CAData   []byte
+	CertData []byte
+	KeyData  []byte
+	Token    string
+	Insecure bool
+}
+
+// kubeconfigPaths returns the kubeconfig files to consult, honouring KUBECONFIG.
+func kubeconfigPaths() ([]string, error) {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		return filepath.SplitList(env), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{filepath.Join(homeDir, ".kube", "config")}, nil
+}
+
+// currentClusterEndpoint resolves the API server of the current kubeconfig context.
+// Like kubectl, the first file to define a value wins when several files are given.
+func currentClusterEndpoint(fs afero.Fs) (*clusterEndpoint, error) {
+	paths, err := kubeconfigPaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate kubeconfig: %w", err)
+	}
+
+	configs := make([]*kubeconfig, 0, len(paths))
+
+	for _, path := range paths {
+		data, err := afero.ReadFile(fs, path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return nil, fmt.Errorf("failed to read kubeconfig %s: %w", path, err)
+		}
+
+		var cfg kubeconfig
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse kubeconfig %s: %w", path, err)
+		}
+
+		cfg.resolvePaths(filepath.Dir(path))
+
+		configs = append(configs, &cfg)
+	}
+
+	return resolveClusterEndpoint(fs, configs)
+}
+
+// resolvePaths makes the relative file references of the kubeconfig absolute.
+// Like kubectl, they are relative to dir, the directory of the kubeconfig file.
+func (c *kubeconfig) resolvePaths(dir string) {
+	resolve := func(path *string) {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+
+	for i := range c.Clusters {
+		resolve(&c.Clusters[i].Cluster.CertificateAuthority)
+	}
+
+	for i := range c.Users {
+		resolve(&c.Users[i].User.ClientCertificate)
+		resolve(&c.Users[i].User.ClientKey)
+	}
+}
+
+// resolveClusterEndpoint looks up the current context across the merged kubeconfigs.
+func resolveClusterEndpoint(fs afero.Fs, configs []*kubeconfig) (*clusterEndpoint, error) {
+	var current string
+
+	for _, cfg := range configs {
+		if cfg.CurrentContext != "" {
+			current = cfg.CurrentContext
+
+			break
+		}
+	}
+
+	if current == "" {
+		return nil, errNoCurrentContext
+	}
+
+	kubeCtx := findKubeconfigEntry(configs, current, func(cfg *kubeconfig) []kubeconfigContext { return cfg.Contexts },
+		func(c kubeconfigContext) string { return c.Name })
+	if kubeCtx == nil {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", current)
+	}
+
+	cluster := findKubeconfigEntry(configs, kubeCtx.Context.Cluster, func(cfg *kubeconfig) []kubeconfigCluster { return cfg.Clusters },
+		func(c kubeconfigCluster) string { return c.Name })
+	if cluster == nil || cluster.Cluster.Server == "" {
+		return nil, fmt.Errorf("cluster %q not found in kubeconfig", kubeCtx.Context.Cluster)
+	}
+
+	ca, err := readInlineOrFile(fs, cluster.Cluster.CertificateAuthorityData, cluster.Cluster.CertificateAuthority)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate authority: %w", err)
+	}
+
+	endpoint := &clusterEndpoint{
+		Context:  current,
+		Server:   cluster.Cluster.Server,
+		CAData:   ca,
+		Insecure: cluster.Cluster.InsecureSkipTLSVerify,
+	}
+
+	user := findKubeconfigEntry(configs, kubeCtx.Context.User, func(cfg *kubeconfig) []kubeconfigUser { return cfg.Users },
+		func(u kubeconfigUser) string { return u.Name })
+	if user == nil {
+		return endpoint, nil
+	}
+
+	if endpoint.CertData, err = readInlineOrFile(fs, user.User.ClientCertificateData, user.User.ClientCertificate); err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+
+	if endpoint.KeyData, err = readInlineOrFile(fs, user.User.ClientKeyData, user.User.ClientKey); err != nil {
+		return nil, fmt.Errorf("failed to load client key: %w", err)
+	}
+
+	endpoint.Token = user.User.Token
+
+	return endpoint, nil
+}
+
+// findKubeconfigEntry returns the first entry named name across configs, or nil.
+func findKubeconfigEntry[T any](configs []*kubeconfig, name string, entries func(*kubeconfig) []T, entryName func(T) string) *T {
+	if name == "" {
+		return nil
+	}
+
+	for _, cfg := range configs {
+		for _, entry := range entries(cfg) {
+			if entryName(entry) == name {
+				return &entry
+			}
+		}
+	}
+
+	return nil
+}
+
+// readInlineOrFile returns base64-decoded inline data, or the content of path.
+func readInlineOrFile(fs afero.Fs, inline, path string) ([]byte, error) {
+	if inline != "" {
+		return base64.StdEncoding.DecodeString(inline)
+	}
+
+	if path == "" {
+		return nil, nil
+	}
+
+	return afero.ReadFile(fs, path)
+}
+
+// bearerClient adds a bearer token to every request of the wrapped client.
+type bearerClient struct {
+	client HTTPClient
+	token  string
+}
+
+// Do implements HTTPClient.
+func (c *bearerClient) Do(req *http.Request) (*http.Response, error) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	return c.client.Do(req)
+}
+
+// httpClient builds a client trusting the cluster's CA and presenting its credentials.
+func (e *clusterEndpoint) httpClient() (HTTPClient, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: e.Insecure, //nolint:gosec // honours insecure-skip-tls-verify from kubeconfig
+	}
+
+	if len(e.CAData) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(e.CAData) {
+			return nil, errors.New("invalid certificate authority data")
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if len(e.CertData) > 0 && len(e.KeyData) > 0 {
+		cert, err := tls.X509KeyPair(e.CertData, e.KeyData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	// Start from the default transport to keep its proxy settings and timeouts.
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert // the default transport is an *http.Transport
+	transport.TLSClientConfig = tlsConfig
+
+	client := &http.Client{
+		Timeout:   clusterVersionTimeout,
+		Transport: transport,
+	}
+
+	return &bearerClient{client: client, token: e.Token}, nil
+}
+
+// serverVersion queries the API server's /version endpoint and returns its gitVersion.
+func (e *clusterEndpoint) serverVersion(ctx context.Context) (string, error) {
+	client, err := e.httpClient()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := fetchHTTPContent(ctx, client, strings.TrimSuffix(e.Server, "/")+"/version")
+	if err != nil {
+		return "", err
+	}
+
+	var info struct {
+		GitVersion string `json:"gitVersion"`
+	}
+
+	if err := json.Unmarshal(data, &info); err != nil {
+		return "", fmt.Errorf("failed to decode server version: %w", err)
+	}
+
+	if info.GitVersion == "" {
+		return "", errors.New("server did not report a version")
+	}
+
+	return info.GitVersion, nil
+}
@@ -0,0 +1,241 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKubeconfigPath = "/home/testuser/.kube/config"
+
+// newVersionServer starts a TLS API server stub answering /version with gitVersion.
+func newVersionServer(t *testing.T, gitVersion string, requests *int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests != nil {
+			*requests++
+		}
+
+		if r.URL.Path != "/version" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprintf(w, `{"major":"1","gitVersion":%q}`, gitVersion) //nolint:errcheck // test helper
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// writeKubeconfig writes a kubeconfig selecting a context for server at path.
+func writeKubeconfig(t *testing.T, fs afero.Fs, path, contextName string, server *httptest.Server) {
+	t.Helper()
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	content := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: %[1]s
+contexts:
+- name: %[1]s
+  context:
+    cluster: %[1]s-cluster
+    user: %[1]s-user
+clusters:
+- name: %[1]s-cluster
+  cluster:
+    server: %[2]s
+    certificate-authority-data: %[3]s
+users:
+- name: %[1]s-user
+  user:
+    token: secret-token
+`, contextName, server.URL, base64.StdEncoding.EncodeToString(caPEM))
+
+	require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0o600))
+}
+
+func TestCurrentClusterEndpoint(t *testing.T) {
+	t.Run("resolves current context from default kubeconfig", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+		t.Setenv("KUBECONFIG", "")
+
+		server := newVersionServer(t, "v1.29.4", nil)
+		writeKubeconfig(t, fs, testKubeconfigPath, "kind-dev", server)
+
+		endpoint, err := currentClusterEndpoint(fs)
+		require.NoError(t, err)
+		assert.Equal(t, "kind-dev", endpoint.Context)
+		assert.Equal(t, server.URL, endpoint.Server)
+		assert.Equal(t, "secret-token", endpoint.Token)
+		assert.NotEmpty(t, endpoint.CAData)
+	})
+
+	t.Run("merges files from KUBECONFIG with first wins", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		server := newVersionServer(t, "v1.29.4", nil)
+		writeKubeconfig(t, fs, "/kube/a", "first", server)
+		writeKubeconfig(t, fs, "/kube/b", "second", server)
+
+		t.Setenv("KUBECONFIG", "/kube/missing"+string(filepath.ListSeparator)+"/kube/a"+string(filepath.ListSeparator)+"/kube/b")
+
+		endpoint, err := currentClusterEndpoint(fs)
+		require.NoError(t, err)
+		assert.Equal(t, "first", endpoint.Context)
+	})
+
+	t.Run("resolves relative file references against the kubeconfig directory", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("KUBECONFIG", "/kube/config")
+
+		content := `current-context: dev
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://127.0.0.1:6443
+    certificate-authority: certs/ca.crt
+users:
+- name: dev
+  user:
+    client-certificate: certs/client.crt
+    client-key: /abs/client.key
+`
+		require.NoError(t, afero.WriteFile(fs, "/kube/config", []byte(content), 0o600))
+		require.NoError(t, afero.WriteFile(fs, "/kube/certs/ca.crt", []byte("ca"), 0o600))
+		require.NoError(t, afero.WriteFile(fs, "/kube/certs/client.crt", []byte("cert"), 0o600))
+		require.NoError(t, afero.WriteFile(fs, "/abs/client.key", []byte("key"), 0o600))
+
+		endpoint, err := currentClusterEndpoint(fs)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("ca"), endpoint.CAData)
+		assert.Equal(t, []byte("cert"), endpoint.CertData)
+		assert.Equal(t, []byte("key"), endpoint.KeyData)
+	})
+
+	t.Run("fails without current context", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("KUBECONFIG", "/kube/empty")
+		require.NoError(t, afero.WriteFile(fs, "/kube/empty", []byte("apiVersion: v1\n"), 0o600))
+
+		_, err := currentClusterEndpoint(fs)
+		require.ErrorIs(t, err, errNoCurrentContext)
+	})
+
+	t.Run("fails for unknown context", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("KUBECONFIG", "/kube/config")
+		require.NoError(t, afero.WriteFile(fs, "/kube/config", []byte("current-context: gone\n"), 0o600))
+
+		_, err := currentClusterEndpoint(fs)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `context "gone" not found`)
+	})
+
+	t.Run("fails for invalid YAML", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("KUBECONFIG", "/kube/config")
+		require.NoError(t, afero.WriteFile(fs, "/kube/config", []byte("contexts: [\n"), 0o600))
+
+		_, err := currentClusterEndpoint(fs)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse kubeconfig")
+	})
+}
+
+func TestClusterEndpointServerVersion(t *testing.T) {
+	t.Run("queries version over TLS with bearer token", func(t *testing.T) {
+		var authorization string
+
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"gitVersion":"v1.30.2"}`)) //nolint:errcheck // test helper
+		}))
+		defer server.Close()
+
+		endpoint := &clusterEndpoint{
+			Server: server.URL,
+			CAData: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}),
+			Token:  "secret-token",
+		}
+
+		version, err := endpoint.serverVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.30.2", version)
+		assert.Equal(t, "Bearer secret-token", authorization)
+	})
+
+	t.Run("rejects untrusted server certificate", func(t *testing.T) {
+		server := newVersionServer(t, "v1.30.2", nil)
+
+		endpoint := &clusterEndpoint{Server: server.URL}
+
+		_, err := endpoint.serverVersion(context.Background())
+		require.Error(t, err)
+	})
+
+	t.Run("honours insecure-skip-tls-verify", func(t *testing.T) {
+		server := newVersionServer(t, "v1.30.2", nil)
+
+		endpoint := &clusterEndpoint{Server: server.URL, Insecure: true}
+
+		version, err := endpoint.serverVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.30.2", version)
+	})
+
+	t.Run("honours proxy settings", func(t *testing.T) {
+		client, err := (&clusterEndpoint{Server: "https://127.0.0.1:1"}).httpClient()
+		require.NoError(t, err)
+
+		bearer, ok := client.(*bearerClient)
+		require.True(t, ok)
+
+		httpClient, ok := bearer.client.(*http.Client)
+		require.True(t, ok)
+
+		transport, ok := httpClient.Transport.(*http.Transport)
+		require.True(t, ok)
+		assert.NotNil(t, transport.Proxy)
+	})
+
+	t.Run("fails on invalid CA data", func(t *testing.T) {
+		endpoint := &clusterEndpoint{Server: "https://127.0.0.1:1", CAData: []byte("not a certificate")}
+
+		_, err := endpoint.serverVersion(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid certificate authority data")
+	})
+
+	t.Run("fails on missing gitVersion", func(t *testing.T) {
+		server := newVersionServer(t, "", nil)
+
+		endpoint := &clusterEndpoint{Server: server.URL, Insecure: true}
+
+		_, err := endpoint.serverVersion(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "server did not report a version")
+	})
+}
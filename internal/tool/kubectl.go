@@ -5,6 +5,17 @@ import (
 	"fmt"
 	"io"
 	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+const (
+	// kubectlReleaseURL is the base URL of the Kubernetes release artifacts.
+	kubectlReleaseURL = "https://dl.k8s.io/release"
+
+	// kubectlMaxPatch is an upper bound on patch releases used to probe whether
+	// a constraint can match any release of a minor version.
+	kubectlMaxPatch = 999
 )
 
 // NewKubectl creates a Tool configured for kubectl.
@@ -13,9 +24,9 @@ func NewKubectl(progress io.Writer) *Tool {
 }
 
 func kubectlVersion(ctx context.Context) (version string, err error) {
-	client := getRetryableClient()
+	client := getRetryableClient(ctx)
 
-	return kubectlVersionWithClient(ctx, client.StandardClient(), "https://dl.k8s.io/release/stable.txt")
+	return kubectlVersionWithClient(ctx, client.StandardClient(), kubectlReleaseURL+"/stable.txt")
 }
 
 // kubectlVersionWithClient fetches kubectl version from the specified URL using the given client.
@@ -29,9 +40,83 @@ func kubectlVersionWithClient(ctx context.Context, client HTTPClient, url string
 	return strings.TrimSpace(string(data)), nil
 }
 
+// kubectlChannelVersion returns the newest kubectl release in channel using the
+// release markers published on dl.k8s.io (latest.txt, stable-1.31.txt, ...).
+func kubectlChannelVersion(ctx context.Context, channel Channel) (string, error) {
+	client := getRetryableClient(ctx)
+
+	return kubectlVersionWithClient(ctx, client.StandardClient(), kubectlReleaseURL+"/"+channel.String()+".txt")
+}
+
+// kubectlVersions lists candidate kubectl releases for constraint.
+func kubectlVersions(ctx context.Context, constraint *semver.Constraints) ([]string, error) {
+	client := getRetryableClient(ctx)
+
+	return kubectlVersionsWithClient(ctx, client.StandardClient(), kubectlReleaseURL, constraint)
+}
+
+// kubectlVersionsWithClient lists candidate kubectl releases for constraint.
+// dl.k8s.io only publishes the newest patch release of each minor version
+// (stable-1.N.txt), so the minors are walked downwards from the current stable
+// release, skipping minors the constraint cannot match, until a match is found.
+// Constraints matching only older patch releases, such as ">=1.30.0 <1.30.3",
+// cannot be resolved.
+func kubectlVersionsWithClient(ctx context.Context, client HTTPClient, baseURL string, constraint *semver.Constraints) ([]string, error) {
+	stable, err := kubectlVersionWithClient(ctx, client, baseURL+"/stable.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := semver.NewVersion(stable)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stable kubectl version %q: %w", stable, err)
+	}
+
+	versions := []string{stable}
+	if matchesConstraint(stable, constraint) {
+		return versions, nil
+	}
+
+	major := latest.Major()
+
+	for minor := latest.Minor(); ; minor-- {
+		if kubectlMinorMayMatch(major, minor, constraint) {
+			url := fmt.Sprintf("%s/stable-%d.%d.txt", baseURL, major, minor)
+
+			version, err := kubectlVersionWithClient(ctx, client, url)
+			if err != nil {
+				return nil, err
+			}
+
+			versions = append(versions, version)
+
+			if matchesConstraint(version, constraint) {
+				return versions, nil
+			}
+		}
+
+		if minor == 0 {
+			return nil, fmt.Errorf("no kubectl release matches %s: only the newest patch release of each minor version "+
+				"(stable-1.N.txt on dl.k8s.io) is probed, pin an exact version to use an older patch release", constraint)
+		}
+	}
+}
+
+// kubectlMinorMayMatch reports whether any patch release of major.minor could satisfy constraint.
+func kubectlMinorMayMatch(major, minor uint64, constraint *semver.Constraints) bool {
+	if constraint == nil {
+		return true
+	}
+
+	first := semver.New(major, minor, 0, "", "")
+	last := semver.New(major, minor, kubectlMaxPatch, "", "")
+
+	return constraint.Check(first) || constraint.Check(last)
+}
+
 func kubectlDownloadURL(version, goos, goarch string) string {
-	return fmt.Sprintf("https://dl.k8s.io/release/%s/bin/%s/%s/kubectl",
-		version, goos, goarch)
+	return fmt.Sprintf("%s/%s/bin/%s/%s/kubectl",
+		kubectlReleaseURL, version, goos, goarch)
 }
 
 func kubectlChecksumURL(version, goos, goarch string) string {
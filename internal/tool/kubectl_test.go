@@ -126,6 +126,115 @@ func TestKubectlVersion(t *testing.T) {
 	})
 }
 
+// newKubectlReleaseServer serves stable.txt and stable-1.N.txt files from the given map.
+func newKubectlReleaseServer(t *testing.T, files map[string]string, requested *[]string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requested = append(*requested, r.URL.Path)
+
+		version, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(version + "\n")) //nolint:errcheck // test helper
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestKubectlVersions(t *testing.T) {
+	files := map[string]string{
+		"/stable.txt":      "v1.31.2",
+		"/stable-1.31.txt": "v1.31.2",
+		"/stable-1.30.txt": "v1.30.6",
+		"/stable-1.29.txt": "v1.29.10",
+		"/stable-1.28.txt": "v1.28.15",
+	}
+
+	t.Run("returns stable when it matches", func(t *testing.T) {
+		var requested []string
+
+		server := newKubectlReleaseServer(t, files, &requested)
+
+		versions, err := kubectlVersionsWithClient(context.Background(), http.DefaultClient, server.URL, mustConstraint(t, ">=1.30"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1.31.2"}, versions)
+		assert.Equal(t, []string{"/stable.txt"}, requested)
+	})
+
+	t.Run("walks down minors skipping those that cannot match", func(t *testing.T) {
+		var requested []string
+
+		server := newKubectlReleaseServer(t, files, &requested)
+
+		versions, err := kubectlVersionsWithClient(context.Background(), http.DefaultClient, server.URL, mustConstraint(t, "~1.29"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v1.31.2", "v1.29.10"}, versions)
+		assert.Equal(t, []string{"/stable.txt", "/stable-1.29.txt"}, requested)
+	})
+
+	t.Run("explains that older patch releases cannot be resolved", func(t *testing.T) {
+		var requested []string
+
+		server := newKubectlReleaseServer(t, files, &requested)
+
+		_, err := kubectlVersionsWithClient(context.Background(), http.DefaultClient, server.URL, mustConstraint(t, ">=1.30.0 <1.30.3"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "only the newest patch release of each minor version (stable-1.N.txt on dl.k8s.io) is probed")
+		assert.Equal(t, []string{"/stable.txt", "/stable-1.30.txt"}, requested)
+	})
+
+	t.Run("handles stable fetch error", func(t *testing.T) {
+		var requested []string
+
+		server := newKubectlReleaseServer(t, map[string]string{}, &requested)
+
+		_, err := kubectlVersionsWithClient(context.Background(), http.DefaultClient, server.URL, mustConstraint(t, "~1.29"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unexpected status code: 404")
+	})
+
+	t.Run("handles invalid stable version", func(t *testing.T) {
+		var requested []string
+
+		server := newKubectlReleaseServer(t, map[string]string{"/stable.txt": "garbage"}, &requested)
+
+		_, err := kubectlVersionsWithClient(context.Background(), http.DefaultClient, server.URL, mustConstraint(t, "~1.29"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid stable kubectl version")
+	})
+}
+
+func TestKubectlMinorMayMatch(t *testing.T) {
+	tests := []struct {
+		constraint string
+		minor      uint64
+		expected   bool
+	}{
+		{"~1.30", 30, true},
+		{"~1.30", 29, false},
+		{">=1.30.3 <1.31", 30, true},
+		{"<1.30", 30, false},
+		{"<1.30", 29, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			assert.Equal(t, tt.expected, kubectlMinorMayMatch(1, tt.minor, mustConstraint(t, tt.constraint)))
+		})
+	}
+
+	t.Run("nil constraint matches", func(t *testing.T) {
+		assert.True(t, kubectlMinorMayMatch(1, 30, nil))
+	})
+}
+
 func TestKubectlDownloadURL(t *testing.T) {
 	tests := []struct {
 		name    string
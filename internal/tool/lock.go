@@ -0,0 +1,246 @@
+package tool
+
+import (
+	"context"
+	"crypto/sha512"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// LockFileName is the name of the lockfile recording exact tool versions and checksums.
+const LockFileName = "kdev.lock"
+
+// lockFileHeader is written at the top of every generated lockfile.
+const lockFileHeader = "# This file is generated by \"kdev tools lock\". Do not edit.\n"
+
+// DefaultLockPlatforms are the os/arch pairs recorded when none are requested explicitly.
+var DefaultLockPlatforms = []string{"darwin/amd64", "darwin/arm64", "linux/amd64", "linux/arm64"}
+
+// LockFile records the exact version, download URL and checksum of every tool per platform.
+type LockFile struct {
+	Tools map[string]LockedTool `yaml:"tools"`
+	// Path is the location the lockfile was loaded from (empty if none was found).
+	Path string `yaml:"-"`
+}
+
+// LockedTool is the locked state of a single tool.
+type LockedTool struct {
+	// Spec is the version pin the version was resolved from (empty for latest).
+	Spec string `yaml:"spec,omitempty"`
+	// Channel is the release channel the version was resolved in (empty for stable).
+	Channel   string                    `yaml:"channel,omitempty"`
+	Version   string                    `yaml:"version"`
+	Platforms map[string]LockedArtifact `yaml:"platforms"`
+}
+
+// LockedArtifact is the download of a tool for one platform.
+type LockedArtifact struct {
+	URL    string `yaml:"url"`
+	SHA256 string `yaml:"sha256,omitempty"`
+	SHA512 string `yaml:"sha512,omitempty"` // Set instead of SHA256 for tools publishing sha512 checksums
+}
+
+// newLockedArtifact records checksum as SHA256 or SHA512 depending on its length.
+func newLockedArtifact(url, checksum string) LockedArtifact {
+	if len(checksum) == 2*sha512.Size {
+		return LockedArtifact{URL: url, SHA512: checksum}
+	}
+
+	return LockedArtifact{URL: url, SHA256: checksum}
+}
+
+// Checksum returns the expected checksum of the artifact, preferring SHA512.
+func (a LockedArtifact) Checksum() string {
+	if a.SHA512 != "" {
+		return a.SHA512
+	}
+
+	return a.SHA256
+}
+
+// Platform formats an os/arch pair as used for lockfile platform keys.
+func Platform(goos, goarch string) string {
+	return goos + "/" + goarch
+}
+
+// splitPlatform splits a platform key such as "linux/amd64" into os and arch.
+func splitPlatform(platform string) (string, string, error) {
+	goos, goarch, ok := strings.Cut(platform, "/")
+	if !ok || goos == "" || goarch == "" {
+		return "", "", fmt.Errorf("invalid platform %q, expected os/arch", platform)
+	}
+
+	return goos, goarch, nil
+}
+
+// LoadLockFile loads the lockfile that applies to dir by walking up the directory tree.
+// It returns an empty lockfile if none is found.
+func LoadLockFile(fs afero.Fs, dir string) (*LockFile, error) {
+	path := findUp(fs, dir, LockFileName)
+	if path == "" {
+		return &LockFile{}, nil
+	}
+
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var lock LockFile
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	lock.Path = path
+
+	return &lock, nil
+}
+
+// LockFilePath returns where the lockfile for dir should be written: next to an
+// existing lockfile or .kdev.yaml if there is one, otherwise in dir itself.
+func LockFilePath(fs afero.Fs, dir string) string {
+	if path := findUp(fs, dir, LockFileName); path != "" {
+		return path
+	}
+
+	if path := FindProjectConfig(fs, dir); path != "" {
+		return filepath.Join(filepath.Dir(path), LockFileName)
+	}
+
+	return filepath.Join(dir, LockFileName)
+}
+
+// Save writes the lockfile to path.
+func (l *LockFile) Save(fs afero.Fs, path string) error {
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+
+	if err := afero.WriteFile(fs, tmpPath, append([]byte(lockFileHeader), data...), 0o644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+
+	if err := fs.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+
+	l.Path = path
+
+	return nil
+}
+
+// Set records the locked state of a tool.
+func (l *LockFile) Set(name string, locked LockedTool) {
+	if l.Tools == nil {
+		l.Tools = map[string]LockedTool{}
+	}
+
+	l.Tools[name] = locked
+}
+
+// LockedVersion returns the locked version of a tool if it was resolved from
+// spec in channel.
+func (l *LockFile) LockedVersion(name, spec string, channel Channel) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+
+	locked, ok := l.Tools[name]
+	if !ok || locked.Spec != spec || locked.Version == "" {
+		return "", false
+	}
+
+	lockedChannel, err := ParseChannel(locked.Channel)
+	if err != nil || lockedChannel != channel {
+		return "", false
+	}
+
+	return locked.Version, true
+}
+
+// Artifact returns the locked download of a tool version for a platform.
+func (l *LockFile) Artifact(name, version, goos, goarch string) (LockedArtifact, bool) {
+	if l == nil {
+		return LockedArtifact{}, false
+	}
+
+	locked, ok := l.Tools[name]
+	if !ok || locked.Version != version {
+		return LockedArtifact{}, false
+	}
+
+	artifact, ok := locked.Platforms[Platform(goos, goarch)]
+
+	return artifact, ok && artifact.Checksum() != ""
+}
+
+// Lock resolves the version of the tool from upstream (ignoring any existing
+// lock and cached answers) and records download URL and checksum per platform.
+func (t *Tool) Lock(ctx context.Context, platforms []string) (LockedTool, error) {
+	project, err := t.getProject()
+	if err != nil {
+		return LockedTool{}, err
+	}
+
+	spec := project.PinnedVersion(t.Name)
+
+	channel, err := t.channel()
+	if err != nil {
+		return LockedTool{}, err
+	}
+
+	version, err := t.resolveVersion(ctx, false, false)
+	if err != nil {
+		return LockedTool{}, fmt.Errorf("failed to get version: %w", err)
+	}
+
+	locked := LockedTool{
+		Spec:      spec,
+		Version:   version,
+		Platforms: make(map[string]LockedArtifact, len(platforms)),
+	}
+
+	if !channel.IsDefault() {
+		locked.Channel = channel.String()
+	}
+
+	// Artifacts are recorded with their upstream URLs, mirrors only apply when fetching.
+	ctx, err = t.upstreamContext(ctx)
+	if err != nil {
+		return LockedTool{}, err
+	}
+
+	for _, platform := range platforms {
+		goos, goarch, err := splitPlatform(platform)
+		if err != nil {
+			return LockedTool{}, err
+		}
+
+		checksum, err := t.upstreamChecksum(ctx, version, goos, goarch)
+		if err != nil {
+			return LockedTool{}, fmt.Errorf("failed to fetch checksum for %s: %w", platform, err)
+		}
+
+		locked.Platforms[platform] = newLockedArtifact(t.DownloadURL(version, goos, goarch), checksum)
+	}
+
+	return locked, nil
+}
+
+// loadLockFileFromWd loads the lockfile for the current working directory.
+func loadLockFileFromWd(fs afero.Fs) (*LockFile, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine working directory: %w", err)
+	}
+
+	return LoadLockFile(fs, wd)
+}
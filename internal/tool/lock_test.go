@@ -0,0 +1,297 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLockFile(t *testing.T) {
+	t.Run("returns empty lockfile when none exists", func(t *testing.T) {
+		lock, err := LoadLockFile(afero.NewMemMapFs(), testProjectDir)
+		require.NoError(t, err)
+		assert.Empty(t, lock.Tools)
+		assert.Empty(t, lock.Path)
+	})
+
+	t.Run("loads lockfile from parent directory", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, LockFileName)
+		content := `tools:
+  kind:
+    spec: ~0.20
+    version: v0.20.0
+    platforms:
+      linux/amd64:
+        url: https://example.com/kind
+        sha256: abc123
+`
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0o644))
+
+		lock, err := LoadLockFile(fs, filepath.Join(testProjectDir, "sub"))
+		require.NoError(t, err)
+		assert.Equal(t, path, lock.Path)
+		assert.Equal(t, LockedTool{
+			Spec:    "~0.20",
+			Version: "v0.20.0",
+			Platforms: map[string]LockedArtifact{
+				"linux/amd64": {URL: "https://example.com/kind", SHA256: "abc123"},
+			},
+		}, lock.Tools["kind"])
+	})
+
+	t.Run("fails on invalid YAML", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, LockFileName)
+		require.NoError(t, afero.WriteFile(fs, path, []byte("tools: [\n"), 0o644))
+
+		_, err := LoadLockFile(fs, testProjectDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse")
+	})
+}
+
+func TestLockFileSave(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, fs.MkdirAll(testProjectDir, 0o755))
+
+	lock := &LockFile{}
+	lock.Set("kubectl", LockedTool{
+		Version:   "v1.31.0",
+		Platforms: map[string]LockedArtifact{"linux/arm64": {URL: "https://example.com/kubectl", SHA256: "def456"}},
+	})
+
+	path := filepath.Join(testProjectDir, LockFileName)
+	require.NoError(t, lock.Save(fs, path))
+	assert.Equal(t, path, lock.Path)
+
+	data, err := afero.ReadFile(fs, path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), lockFileHeader)
+
+	loaded, err := LoadLockFile(fs, testProjectDir)
+	require.NoError(t, err)
+	assert.Equal(t, lock.Tools, loaded.Tools)
+}
+
+func TestLockFilePath(t *testing.T) {
+	subDir := filepath.Join(testProjectDir, "sub")
+
+	t.Run("defaults to the given directory", func(t *testing.T) {
+		assert.Equal(t, filepath.Join(subDir, LockFileName), LockFilePath(afero.NewMemMapFs(), subDir))
+	})
+
+	t.Run("places lockfile next to project config", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, filepath.Join(testProjectDir, ProjectConfigFile), []byte("versions: {}\n"), 0o644))
+
+		assert.Equal(t, filepath.Join(testProjectDir, LockFileName), LockFilePath(fs, subDir))
+	})
+
+	t.Run("reuses existing lockfile", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, LockFileName)
+		require.NoError(t, afero.WriteFile(fs, path, []byte("tools: {}\n"), 0o644))
+		require.NoError(t, afero.WriteFile(fs, filepath.Join(subDir, ProjectConfigFile), []byte("versions: {}\n"), 0o644))
+
+		assert.Equal(t, path, LockFilePath(fs, subDir))
+	})
+}
+
+func TestLockFileLookups(t *testing.T) {
+	lock := &LockFile{Tools: map[string]LockedTool{
+		"kind": {
+			Spec:    "~0.20",
+			Version: "v0.20.0",
+			Platforms: map[string]LockedArtifact{
+				"linux/amd64": {URL: "https://example.com/kind", SHA256: "abc123"},
+			},
+		},
+	}}
+
+	t.Run("locked version requires matching spec", func(t *testing.T) {
+		version, ok := lock.LockedVersion("kind", "~0.20", Channel{})
+		assert.True(t, ok)
+		assert.Equal(t, "v0.20.0", version)
+
+		_, ok = lock.LockedVersion("kind", "", Channel{})
+		assert.False(t, ok)
+
+		_, ok = lock.LockedVersion("kubectl", "", Channel{})
+		assert.False(t, ok)
+	})
+
+	t.Run("artifact requires matching version and platform", func(t *testing.T) {
+		artifact, ok := lock.Artifact("kind", "v0.20.0", "linux", "amd64")
+		assert.True(t, ok)
+		assert.Equal(t, "abc123", artifact.SHA256)
+
+		_, ok = lock.Artifact("kind", "v0.21.0", "linux", "amd64")
+		assert.False(t, ok)
+
+		_, ok = lock.Artifact("kind", "v0.20.0", "darwin", "arm64")
+		assert.False(t, ok)
+	})
+
+	t.Run("nil lockfile has no entries", func(t *testing.T) {
+		var nilLock *LockFile
+
+		_, ok := nilLock.LockedVersion("kind", "", Channel{})
+		assert.False(t, ok)
+
+		_, ok = nilLock.Artifact("kind", "v0.20.0", "linux", "amd64")
+		assert.False(t, ok)
+	})
+}
+
+func TestToolLock(t *testing.T) {
+	t.Run("records url and checksum per platform", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("sum-of" + r.URL.Path)) //nolint:errcheck // test helper
+		}))
+		defer server.Close()
+
+		tool := &Tool{
+			Name: "testtool",
+			Fs:   afero.NewMemMapFs(),
+			VersionFunc: func(ctx context.Context) (string, error) {
+				return testVersion, nil
+			},
+			DownloadURL: func(version, goos, goarch string) string {
+				return fmt.Sprintf("https://example.com/%s/%s-%s", version, goos, goarch)
+			},
+			ChecksumURL: func(version, goos, goarch string) string {
+				return fmt.Sprintf("%s/%s-%s", server.URL, goos, goarch)
+			},
+			Project: &ProjectConfig{},
+			// An existing lock must not influence the result.
+			LockFile: &LockFile{Tools: map[string]LockedTool{"testtool": {Version: "v0.0.1"}}},
+		}
+
+		locked, err := tool.Lock(context.Background(), []string{"linux/amd64", "darwin/arm64"})
+		require.NoError(t, err)
+		assert.Equal(t, LockedTool{
+			Version: testVersion,
+			Platforms: map[string]LockedArtifact{
+				"linux/amd64":  {URL: "https://example.com/" + testVersion + "/linux-amd64", SHA256: "sum-of/linux-amd64"},
+				"darwin/arm64": {URL: "https://example.com/" + testVersion + "/darwin-arm64", SHA256: "sum-of/darwin-arm64"},
+			},
+		}, locked)
+	})
+
+	t.Run("records the pinned spec", func(t *testing.T) {
+		tool := &Tool{
+			Name:    "testtool",
+			Fs:      afero.NewMemMapFs(),
+			Project: &ProjectConfig{Versions: map[string]string{"testtool": "v1.2.3"}},
+		}
+
+		locked, err := tool.Lock(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "v1.2.3", locked.Spec)
+		assert.Equal(t, "v1.2.3", locked.Version)
+	})
+
+	t.Run("rejects invalid platforms", func(t *testing.T) {
+		tool := &Tool{
+			Name:    "testtool",
+			Fs:      afero.NewMemMapFs(),
+			Project: &ProjectConfig{Versions: map[string]string{"testtool": "v1.2.3"}},
+		}
+
+		_, err := tool.Lock(context.Background(), []string{"linux"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid platform")
+	})
+}
+
+func TestResolveVersionLocked(t *testing.T) {
+	newTool := func(spec string, lock *LockFile) *Tool {
+		return &Tool{
+			Name: "testtool",
+			Fs:   afero.NewMemMapFs(),
+			VersionFunc: func(ctx context.Context) (string, error) {
+				return testVersion, nil
+			},
+			Project:  &ProjectConfig{Versions: map[string]string{"testtool": spec}},
+			LockFile: lock,
+		}
+	}
+
+	t.Run("uses locked version for the current pin", func(t *testing.T) {
+		lock := &LockFile{Tools: map[string]LockedTool{"testtool": {Version: "v0.9.0"}}}
+
+		version, err := newTool("", lock).ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v0.9.0", version)
+	})
+
+	t.Run("ignores lock when the pin changed", func(t *testing.T) {
+		lock := &LockFile{Tools: map[string]LockedTool{"testtool": {Spec: "v0.9.0", Version: "v0.9.0"}}}
+
+		version, err := newTool("", lock).ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, testVersion, version)
+	})
+}
+
+func TestDownloadLocked(t *testing.T) {
+	content := []byte("locked binary content")
+	checksum := fmt.Sprintf("%x", sha256.Sum256(content))
+
+	binaryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content) //nolint:errcheck // test helper
+	}))
+	defer binaryServer.Close()
+
+	newTool := func(sha string) (*Tool, afero.Fs) {
+		fs := afero.NewMemMapFs()
+
+		return &Tool{
+			Name: "testtool",
+			Fs:   fs,
+			DownloadURL: func(version, goos, goarch string) string {
+				return "http://invalid.invalid/not-used"
+			},
+			ChecksumURL: func(version, goos, goarch string) string {
+				t.Fatal("checksum URL must not be used for locked artifacts")
+
+				return ""
+			},
+			LockFile: &LockFile{Tools: map[string]LockedTool{"testtool": {
+				Version: testVersion,
+				Platforms: map[string]LockedArtifact{
+					Platform(runtime.GOOS, runtime.GOARCH): {URL: binaryServer.URL, SHA256: sha},
+				},
+			}}},
+		}, fs
+	}
+
+	t.Run("verifies against locked checksum", func(t *testing.T) {
+		tool, fs := newTool(checksum)
+
+		require.NoError(t, tool.download(context.Background(), testToolPath, testVersion))
+
+		data, err := afero.ReadFile(fs, testToolPath)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+	})
+
+	t.Run("fails on mismatch with locked checksum", func(t *testing.T) {
+		tool, _ := newTool("deadbeef")
+
+		err := tool.download(context.Background(), testToolPath, testVersion)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "checksum mismatch")
+	})
+}
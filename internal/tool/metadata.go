@@ -0,0 +1,110 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// metadataFileName is the name of the metadata file next to a cached binary.
+const metadataFileName = "metadata.json"
+
+// VersionMetadata records where a cached version came from and when it was
+// last used.
+type VersionMetadata struct {
+	// SourceURL is the URL the version was downloaded from, after applying mirror rules.
+	SourceURL string `json:"sourceURL,omitempty"`
+	// Imported is set for versions imported from an offline bundle.
+	Imported bool `json:"imported,omitempty"`
+	// SHA256 is the checksum of the cached binary.
+	SHA256 string `json:"sha256,omitempty"`
+	// DownloadedAt is when the version was added to the cache.
+	DownloadedAt time.Time `json:"downloadedAt,omitzero"`
+	// KdevVersion is the version of kdev that added the version to the cache.
+	KdevVersion string `json:"kdevVersion,omitempty"`
+	// LastUsed is when the version was last executed through kdev.
+	LastUsed time.Time `json:"lastUsed,omitzero"`
+}
+
+// metadataPath returns the path of the metadata file of the binary at binPath.
+func metadataPath(binPath string) string {
+	return filepath.Join(filepath.Dir(binPath), metadataFileName)
+}
+
+// readMetadata reads the metadata of the binary at binPath. It returns nil if
+// there is none, e.g. for versions cached by older kdev releases.
+func readMetadata(fs afero.Fs, binPath string) *VersionMetadata {
+	data, err := afero.ReadFile(fs, metadataPath(binPath))
+	if err != nil {
+		return nil
+	}
+
+	var metadata VersionMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil
+	}
+
+	return &metadata
+}
+
+// writeMetadata replaces the metadata of the binary at binPath.
+func writeMetadata(fs afero.Fs, binPath string, metadata *VersionMetadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	path := metadataPath(binPath)
+	tmpPath := path + ".tmp"
+
+	if err := afero.WriteFile(fs, tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	return fs.Rename(tmpPath, path)
+}
+
+// recordDownload writes the metadata of a binary just added to the cache at
+// binPath and moves the binary into the blob store.
+func recordDownload(fs afero.Fs, binPath string, metadata VersionMetadata) error {
+	checksum, _, err := fileSHA256(fs, binPath)
+	if err != nil {
+		return err
+	}
+
+	if err := storeBlob(fs, binPath, checksum); err != nil {
+		return err
+	}
+
+	metadata.SHA256 = checksum
+	metadata.DownloadedAt = time.Now().UTC()
+	metadata.KdevVersion = kdevVersion()
+
+	return writeMetadata(fs, binPath, &metadata)
+}
+
+// recordUse updates the last-used time in the metadata of the binary at binPath.
+func recordUse(fs afero.Fs, binPath string) error {
+	metadata := readMetadata(fs, binPath)
+	if metadata == nil {
+		metadata = &VersionMetadata{}
+	}
+
+	metadata.LastUsed = time.Now().UTC()
+
+	return writeMetadata(fs, binPath, metadata)
+}
+
+// kdevVersion returns the version of the running kdev binary.
+func kdevVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" {
+		return "unknown"
+	}
+
+	return info.Main.Version
+}
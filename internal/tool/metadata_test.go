@@ -0,0 +1,148 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordDownload(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+
+	content := []byte("downloaded binary")
+	checksum := fmt.Sprintf("%x", sha256.Sum256(content))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/mirror/testtool.sha256" {
+			_, _ = w.Write([]byte(checksum)) //nolint:errcheck // test helper
+
+			return
+		}
+
+		_, _ = w.Write(content) //nolint:errcheck // test helper
+	}))
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	tool := &Tool{
+		Name: "testtool",
+		Fs:   fs,
+		VersionFunc: func(context.Context) (string, error) {
+			return testVersion, nil
+		},
+		DownloadURL: func(version, goos, goarch string) string {
+			return "https://upstream.invalid/testtool"
+		},
+		ChecksumURL: func(version, goos, goarch string) string {
+			return "https://upstream.invalid/testtool.sha256"
+		},
+		Project: &ProjectConfig{Mirrors: []MirrorRule{{From: "https://upstream.invalid/", To: server.URL + "/mirror/"}}},
+	}
+
+	before := time.Now()
+
+	require.NoError(t, tool.Download(context.Background()))
+
+	versions, err := tool.CachedVersions()
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+
+	metadata := versions[0].Metadata
+	require.NotNil(t, metadata)
+	assert.Equal(t, server.URL+"/mirror/testtool", metadata.SourceURL)
+	assert.Equal(t, checksum, metadata.SHA256)
+	assert.False(t, metadata.Imported)
+	assert.NotEmpty(t, metadata.KdevVersion)
+	assert.False(t, metadata.DownloadedAt.Before(before.Add(-time.Second)))
+	assert.True(t, metadata.LastUsed.IsZero())
+}
+
+func TestRecordUse(t *testing.T) {
+	t.Run("keeps download metadata", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		binPath := cachedTestBinary(testVersion)
+		downloaded := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		require.NoError(t, afero.WriteFile(fs, binPath, []byte("binary"), 0o755))
+		require.NoError(t, writeMetadata(fs, binPath, &VersionMetadata{SourceURL: "https://example.com/testtool", DownloadedAt: downloaded}))
+
+		require.NoError(t, recordUse(fs, binPath))
+
+		metadata := readMetadata(fs, binPath)
+		require.NotNil(t, metadata)
+		assert.Equal(t, "https://example.com/testtool", metadata.SourceURL)
+		assert.True(t, metadata.DownloadedAt.Equal(downloaded))
+		assert.WithinDuration(t, time.Now(), metadata.LastUsed, time.Minute)
+	})
+
+	t.Run("creates metadata for versions cached without it", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		binPath := cachedTestBinary(testVersion)
+		require.NoError(t, afero.WriteFile(fs, binPath, []byte("binary"), 0o755))
+		assert.Nil(t, readMetadata(fs, binPath))
+
+		require.NoError(t, recordUse(fs, binPath))
+
+		metadata := readMetadata(fs, binPath)
+		require.NotNil(t, metadata)
+		assert.Empty(t, metadata.SourceURL)
+		assert.False(t, metadata.LastUsed.IsZero())
+	})
+}
+
+func TestPrepareExecRecordsUse(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+
+	fs := afero.NewMemMapFs()
+	binPath := cachedTestBinary(testVersion)
+	require.NoError(t, afero.WriteFile(fs, binPath, []byte("binary"), 0o755))
+
+	tool := &Tool{
+		Name:    "testtool",
+		Fs:      fs,
+		Project: &ProjectConfig{Versions: map[string]string{"testtool": testVersion}},
+	}
+
+	_, _, lock, err := tool.prepareExec(context.Background(), nil)
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+
+	metadata := readMetadata(fs, binPath)
+	require.NotNil(t, metadata)
+	assert.False(t, metadata.LastUsed.IsZero())
+}
+
+func TestReadMetadata(t *testing.T) {
+	t.Run("ignores invalid files", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		binPath := cachedTestBinary(testVersion)
+		require.NoError(t, afero.WriteFile(fs, metadataPath(binPath), []byte("{invalid"), 0o644))
+
+		assert.Nil(t, readMetadata(fs, binPath))
+	})
+}
+
+func TestImportBundleRecordsMetadata(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+
+	bundle, manifest := exportTestBundle(t)
+	fs := afero.NewMemMapFs()
+	registry := &Registry{tools: map[string]*Tool{"testtool": {Name: "testtool", Fs: fs}}}
+
+	_, err := ImportBundle(context.Background(), bundle, registry)
+	require.NoError(t, err)
+
+	metadata := readMetadata(fs, cachedTestBinary(testVersion))
+	require.NotNil(t, metadata)
+	assert.True(t, metadata.Imported)
+	assert.Equal(t, manifest.Artifacts[0].SHA256, metadata.SHA256)
+}
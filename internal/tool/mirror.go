@@ -0,0 +1,122 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MirrorRule rewrites upstream URLs starting with From to start with To instead,
+// e.g. to fetch from an internal artifact repository in an air-gapped network.
+type MirrorRule struct {
+	// From is the URL prefix to replace, e.g. "https://dl.k8s.io/".
+	From string `yaml:"from"`
+	// To is the replacement prefix, e.g. "https://artifactory.example.com/k8s/".
+	To string `yaml:"to"`
+	// Tool restricts the rule to a single tool (empty applies to all tools).
+	Tool string `yaml:"tool,omitempty"`
+}
+
+// mirrorRulesKey is the context key for the mirror rules of upstream requests.
+type mirrorRulesKey struct{}
+
+// withMirrorRules returns a context whose upstream requests are rewritten by rules.
+func withMirrorRules(ctx context.Context, rules []MirrorRule) context.Context {
+	if len(rules) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, mirrorRulesKey{}, rules)
+}
+
+// upstreamContext returns a context carrying the HTTP client, base URL
+// overrides and mirror rules configured for this tool, which are applied to
+// the download, checksum and version URLs requested with it.
+func (t *Tool) upstreamContext(ctx context.Context) (context.Context, error) {
+	project, err := t.getProject()
+	if err != nil {
+		return nil, err
+	}
+
+	rules := append(t.baseURLRules(), project.MirrorRules(t.Name)...)
+
+	return withHTTPClient(withMirrorRules(ctx, rules), t.HTTPClient), nil
+}
+
+// baseURLRules returns rules replacing the upstream URLs of the tool with
+// BaseURL and the GitHub API with GitHubAPIURL, if set.
+func (t *Tool) baseURLRules() []MirrorRule {
+	var rules []MirrorRule
+
+	if t.BaseURL != "" && t.UpstreamURL != "" {
+		rules = append(rules, MirrorRule{From: withTrailingSlash(t.UpstreamURL), To: withTrailingSlash(t.BaseURL)})
+	}
+
+	if t.GitHubAPIURL != "" {
+		rules = append(rules, MirrorRule{From: githubAPIURL, To: withTrailingSlash(t.GitHubAPIURL)})
+	}
+
+	return rules
+}
+
+// withTrailingSlash returns url ending in exactly one slash, so that prefixes
+// only match whole path segments.
+func withTrailingSlash(url string) string {
+	return strings.TrimRight(url, "/") + "/"
+}
+
+// rewriteURL applies the first rule whose prefix matches rawURL.
+func rewriteURL(rules []MirrorRule, rawURL string) string {
+	for _, rule := range rules {
+		if rest, ok := strings.CutPrefix(rawURL, rule.From); ok {
+			return rule.To + rest
+		}
+	}
+
+	return rawURL
+}
+
+// requestURL returns the URL requested for rawURL with ctx, after applying the
+// mirror rules of ctx.
+func requestURL(ctx context.Context, rawURL string) string {
+	rules, ok := ctx.Value(mirrorRulesKey{}).([]MirrorRule)
+	if !ok {
+		return rawURL
+	}
+
+	return rewriteURL(rules, rawURL)
+}
+
+// mirrorTransport rewrites request URLs according to the mirror rules of the
+// request context before passing them on. Like redirects, requests moved to
+// another host do not carry the Authorization header along.
+type mirrorTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (m *mirrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	original := req.URL.String()
+
+	rewritten := requestURL(req.Context(), original)
+	if rewritten == original {
+		return m.base.RoundTrip(req)
+	}
+
+	target, err := url.Parse(rewritten)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mirror URL %q: %w", rewritten, err)
+	}
+
+	req = req.Clone(req.Context())
+	if target.Host != req.URL.Host {
+		req.Header.Del("Authorization")
+	}
+
+	req.URL = target
+	req.Host = target.Host
+
+	return m.base.RoundTrip(req)
+}
@@ -0,0 +1,291 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewriteURL(t *testing.T) {
+	rules := []MirrorRule{
+		{From: "https://dl.k8s.io/release/", To: "http://files.lab/k8s/"},
+		{From: "https://dl.k8s.io/", To: "http://files.lab/other/"},
+	}
+
+	assert.Equal(t, "http://files.lab/k8s/stable.txt", rewriteURL(rules, "https://dl.k8s.io/release/stable.txt"))
+	assert.Equal(t, "http://files.lab/other/ci/latest.txt", rewriteURL(rules, "https://dl.k8s.io/ci/latest.txt"))
+	assert.Equal(t, "https://github.com/kind", rewriteURL(rules, "https://github.com/kind"))
+	assert.Equal(t, "https://dl.k8s.io/stable.txt", rewriteURL(nil, "https://dl.k8s.io/stable.txt"))
+}
+
+// newMirrorTestServer records the paths requested from it and answers them with handler.
+func newMirrorTestServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &paths
+}
+
+func TestMirrors(t *testing.T) {
+	t.Run("rewrites download and checksum URLs", func(t *testing.T) {
+		content := []byte("mirrored binary")
+		checksum := fmt.Sprintf("%x", sha256.Sum256(content))
+
+		server, paths := newMirrorTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/mirror/testtool.sha256" {
+				_, _ = w.Write([]byte(checksum)) //nolint:errcheck // test helper
+
+				return
+			}
+
+			_, _ = w.Write(content) //nolint:errcheck // test helper
+		})
+
+		fs := afero.NewMemMapFs()
+		tool := &Tool{
+			Name: "testtool",
+			Fs:   fs,
+			DownloadURL: func(version, goos, goarch string) string {
+				return "https://upstream.invalid/testtool"
+			},
+			ChecksumURL: func(version, goos, goarch string) string {
+				return "https://upstream.invalid/testtool.sha256"
+			},
+			Project: &ProjectConfig{Mirrors: []MirrorRule{{From: "https://upstream.invalid/", To: server.URL + "/mirror/"}}},
+		}
+
+		require.NoError(t, tool.download(context.Background(), testToolPath, testVersion))
+		assert.ElementsMatch(t, []string{"/mirror/testtool.sha256", "/mirror/testtool"}, *paths)
+
+		data, err := afero.ReadFile(fs, testToolPath)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+	})
+
+	t.Run("rewrites kubectl version URLs", func(t *testing.T) {
+		server, paths := newMirrorTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("v1.31.2\n")) //nolint:errcheck // test helper
+		})
+
+		tool := NewKubectl(nil)
+		tool.Fs = afero.NewMemMapFs()
+		tool.Project = &ProjectConfig{Mirrors: []MirrorRule{{From: kubectlReleaseURL + "/", To: server.URL + "/k8s/", Tool: "kubectl"}}}
+
+		version, err := tool.RefreshVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.31.2", version)
+		assert.Equal(t, []string{"/k8s/stable.txt"}, *paths)
+	})
+
+	t.Run("rewrites GitHub API URLs", func(t *testing.T) {
+		server, paths := newMirrorTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(&github.RepositoryRelease{TagName: github.String("v0.25.0")}) //nolint:errcheck // test helper
+		})
+
+		tool := NewKind(nil)
+		tool.Fs = afero.NewMemMapFs()
+		tool.Project = &ProjectConfig{Mirrors: []MirrorRule{{From: "https://api.github.com/", To: server.URL + "/github/"}}}
+
+		version, err := tool.RefreshVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v0.25.0", version)
+		assert.Equal(t, []string{"/github/repos/kubernetes-sigs/kind/releases/latest"}, *paths)
+	})
+
+	t.Run("drops credentials when changing the host", func(t *testing.T) {
+		clearGitHubTokenEnv(t)
+		t.Setenv("GITHUB_TOKEN", "secret")
+
+		var auth string
+
+		server, _ := newMirrorTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			auth = r.Header.Get("Authorization")
+			_ = json.NewEncoder(w).Encode(&github.RepositoryRelease{TagName: github.String("v0.25.0")}) //nolint:errcheck // test helper
+		})
+
+		tool := NewKind(nil)
+		tool.Fs = afero.NewMemMapFs()
+		tool.Project = &ProjectConfig{Mirrors: []MirrorRule{{From: "https://api.github.com/", To: server.URL + "/github/"}}}
+
+		_, err := tool.RefreshVersion(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, auth)
+	})
+
+	t.Run("ignores rules of other tools", func(t *testing.T) {
+		tool := &Tool{
+			Name:    "kind",
+			Project: &ProjectConfig{Mirrors: []MirrorRule{{From: "https://github.com/", To: "http://files.lab/", Tool: "cilium"}}},
+		}
+
+		ctx, err := tool.upstreamContext(context.Background())
+		require.NoError(t, err)
+		assert.Nil(t, ctx.Value(mirrorRulesKey{}))
+	})
+}
+
+// countingTransport counts the requests sent through it.
+type countingTransport struct {
+	base     http.RoundTripper
+	requests atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+
+	return c.base.RoundTrip(req)
+}
+
+func TestBaseURLs(t *testing.T) {
+	content := []byte("served binary")
+	checksum := fmt.Sprintf("%x", sha256.Sum256(content))
+
+	// newUpstreamServer serves version lookups and a binary with its checksum,
+	// recording all requested paths.
+	newUpstreamServer := func(t *testing.T, version string) (*httptest.Server, *[]string) {
+		t.Helper()
+
+		return newMirrorTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/stable.txt":
+				_, _ = w.Write([]byte(version + "\n")) //nolint:errcheck // test helper
+			case strings.HasSuffix(r.URL.Path, "/releases/latest"):
+				_ = json.NewEncoder(w).Encode(&github.RepositoryRelease{TagName: github.String(version)}) //nolint:errcheck // test helper
+			case strings.HasSuffix(r.URL.Path, ".sha256"), strings.HasSuffix(r.URL.Path, ".sha256sum"):
+				_, _ = w.Write([]byte(checksum)) //nolint:errcheck // test helper
+			default:
+				_, _ = w.Write(content) //nolint:errcheck // test helper
+			}
+		})
+	}
+
+	// runPipeline resolves and downloads the tool, returning the resolved version.
+	runPipeline := func(t *testing.T, tool *Tool) string {
+		t.Helper()
+
+		version, err := tool.RefreshVersion(context.Background())
+		require.NoError(t, err)
+
+		require.NoError(t, tool.download(context.Background(), testToolPath, version))
+
+		data, err := afero.ReadFile(tool.Fs, testToolPath)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+
+		return version
+	}
+
+	t.Run("drives kubectl through a test server", func(t *testing.T) {
+		server, paths := newUpstreamServer(t, "v1.31.2")
+		transport := &countingTransport{base: server.Client().Transport}
+
+		tool := NewKubectl(nil)
+		tool.Fs = afero.NewMemMapFs()
+		tool.Project = &ProjectConfig{}
+		tool.BaseURL = server.URL
+		tool.HTTPClient = &http.Client{Transport: transport}
+
+		assert.Equal(t, "v1.31.2", runPipeline(t, tool))
+
+		binary := fmt.Sprintf("/v1.31.2/bin/%s/%s/kubectl", runtime.GOOS, runtime.GOARCH)
+		assert.ElementsMatch(t, []string{"/stable.txt", binary + ".sha256", binary}, *paths)
+		assert.Equal(t, int32(3), transport.requests.Load())
+	})
+
+	t.Run("drives GitHub-hosted tools through a test server", func(t *testing.T) {
+		clearGitHubTokenEnv(t)
+
+		server, paths := newUpstreamServer(t, "v0.25.0")
+
+		tool := NewKind(nil)
+		tool.Fs = afero.NewMemMapFs()
+		tool.Project = &ProjectConfig{}
+		tool.BaseURL = server.URL + "/download/"
+		tool.GitHubAPIURL = server.URL + "/api"
+
+		assert.Equal(t, "v0.25.0", runPipeline(t, tool))
+
+		binary := fmt.Sprintf("/download/v0.25.0/kind-%s-%s", runtime.GOOS, runtime.GOARCH)
+		assert.ElementsMatch(t, []string{"/api/repos/kubernetes-sigs/kind/releases/latest", binary + ".sha256sum", binary}, *paths)
+	})
+
+	t.Run("are set through the configurations of a registry", func(t *testing.T) {
+		clearGitHubTokenEnv(t)
+
+		server, paths := newUpstreamServer(t, "v0.25.0")
+		transport := &countingTransport{base: server.Client().Transport}
+
+		configs := BuiltinConfigs()
+		for i := range configs {
+			configs[i].BaseURL = server.URL + "/download/"
+			configs[i].GitHubAPIURL = server.URL + "/api"
+			configs[i].HTTPClient = &http.Client{Transport: transport}
+		}
+
+		tool := NewRegistryFromConfigs(configs, nil).Get("kind")
+		require.NotNil(t, tool)
+
+		tool.Fs = afero.NewMemMapFs()
+		tool.Project = &ProjectConfig{}
+
+		assert.Equal(t, "v0.25.0", runPipeline(t, tool))
+
+		binary := fmt.Sprintf("/download/v0.25.0/kind-%s-%s", runtime.GOOS, runtime.GOARCH)
+		assert.ElementsMatch(t, []string{"/api/repos/kubernetes-sigs/kind/releases/latest", binary + ".sha256sum", binary}, *paths)
+		assert.Equal(t, int32(3), transport.requests.Load())
+	})
+
+	t.Run("takes precedence over mirrors", func(t *testing.T) {
+		tool := &Tool{
+			Name:        "testtool",
+			UpstreamURL: "https://example.com/releases",
+			BaseURL:     "http://localhost:8080",
+			Project:     &ProjectConfig{Mirrors: []MirrorRule{{From: "https://example.com/", To: "http://mirror.invalid/"}}},
+		}
+
+		ctx, err := tool.upstreamContext(context.Background())
+		require.NoError(t, err)
+
+		rules, ok := ctx.Value(mirrorRulesKey{}).([]MirrorRule)
+		require.True(t, ok)
+		assert.Equal(t, "http://localhost:8080/v1/testtool", rewriteURL(rules, "https://example.com/releases/v1/testtool"))
+		assert.Equal(t, "http://mirror.invalid/other", rewriteURL(rules, "https://example.com/other"))
+	})
+
+	t.Run("keeps the injected client unchanged", func(t *testing.T) {
+		client := &http.Client{Transport: http.DefaultTransport}
+
+		ctx := withHTTPClient(context.Background(), client)
+		retryClient := getRetryableClient(ctx)
+
+		assert.NotSame(t, client, retryClient.HTTPClient)
+		assert.Same(t, http.DefaultTransport, client.Transport)
+	})
+}
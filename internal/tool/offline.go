@@ -0,0 +1,79 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// OfflineEnvVar is the environment variable that forces offline mode when set to a true value.
+const OfflineEnvVar = "KDEV_OFFLINE"
+
+// errNoCachedVersion is returned when offline mode is active but nothing is cached.
+var errNoCachedVersion = errors.New("no cached version available")
+
+// offlineFromEnv reports whether offline mode is forced through the environment.
+func offlineFromEnv() bool {
+	offline, err := strconv.ParseBool(os.Getenv(OfflineEnvVar))
+
+	return err == nil && offline
+}
+
+// isOffline reports whether upstream version lookups should be skipped.
+func (t *Tool) isOffline() bool {
+	return t.Offline || offlineFromEnv()
+}
+
+// newestCachedVersion returns the newest cached version of the tool in channel
+// that satisfies constraint (any version if constraint is nil).
+func (t *Tool) newestCachedVersion(constraint *semver.Constraints, channel Channel) (string, error) {
+	versions, err := t.CachedVersions()
+	if err != nil {
+		return "", err
+	}
+
+	for _, v := range versions {
+		if channel.Allows(v.Version) && matchesConstraint(v.Version, constraint) {
+			return v.Version, nil
+		}
+	}
+
+	if constraint != nil {
+		return "", fmt.Errorf("%w for %s matching %q", errNoCachedVersion, t.Name, constraint.String())
+	}
+
+	return "", fmt.Errorf("%w for %s", errNoCachedVersion, t.Name)
+}
+
+// offlineVersion resolves the version to use without contacting upstream.
+func (t *Tool) offlineVersion(constraint *semver.Constraints, channel Channel) (string, error) {
+	version, err := t.newestCachedVersion(constraint, channel)
+	if err != nil {
+		return "", fmt.Errorf("offline mode: %w", err)
+	}
+
+	if err := t.writeProgress("Offline mode: using cached %s %s\n", t.Name, version); err != nil {
+		return "", fmt.Errorf("failed to write progress: %w", err)
+	}
+
+	return version, nil
+}
+
+// fallbackVersion resolves to the newest cached version after an upstream
+// lookup failed. The original lookup error is returned if nothing is cached.
+func (t *Tool) fallbackVersion(lookupErr error, constraint *semver.Constraints, channel Channel) (string, error) {
+	version, err := t.newestCachedVersion(constraint, channel)
+	if err != nil {
+		return "", lookupErr
+	}
+
+	if err := t.writeProgress("Warning: failed to look up latest %s version (%v), using cached %s\n",
+		t.Name, lookupErr, version); err != nil {
+		return "", fmt.Errorf("failed to write progress: %w", err)
+	}
+
+	return version, nil
+}
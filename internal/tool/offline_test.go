@@ -0,0 +1,157 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dennisklein/kdev/internal/testutil"
+)
+
+// newOfflineTestTool creates a kubectl tool backed by a memory filesystem with
+// the given versions cached.
+func newOfflineTestTool(t *testing.T, progress *bytes.Buffer, versions ...string) *Tool {
+	t.Helper()
+
+	fs := afero.NewMemMapFs()
+	t.Setenv("HOME", testUser)
+
+	for _, version := range versions {
+		binPath := filepath.Join(testUser, ".kdev", "kdev", "kubectl", version, "kubectl")
+		require.NoError(t, afero.WriteFile(fs, binPath, []byte("cached binary"), 0o755))
+	}
+
+	tool := &Tool{
+		Name:    "kubectl",
+		Fs:      fs,
+		Project: &ProjectConfig{},
+	}
+
+	if progress != nil {
+		tool.ProgressWriter = progress
+	}
+
+	return tool
+}
+
+func TestOfflineFromEnv(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected bool
+	}{
+		{"", false},
+		{"1", true},
+		{"true", true},
+		{"TRUE", true},
+		{"0", false},
+		{"false", false},
+		{"garbage", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("value %q", tt.value), func(t *testing.T) {
+			t.Setenv(OfflineEnvVar, tt.value)
+
+			assert.Equal(t, tt.expected, offlineFromEnv())
+		})
+	}
+}
+
+func TestResolveVersionOffline(t *testing.T) {
+	t.Run("uses newest cached version when forced offline", func(t *testing.T) {
+		var progress bytes.Buffer
+
+		tool := newOfflineTestTool(t, &progress, "v1.28.0", "v1.30.0", "v1.29.0")
+		tool.Offline = true
+		tool.VersionFunc = func(ctx context.Context) (string, error) {
+			t.Fatal("VersionFunc should not be called in offline mode")
+
+			return "", nil
+		}
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.30.0", version)
+		assert.Contains(t, progress.String(), "Offline mode: using cached kubectl v1.30.0")
+	})
+
+	t.Run("honours environment variable", func(t *testing.T) {
+		tool := newOfflineTestTool(t, nil, "v1.29.0")
+		t.Setenv(OfflineEnvVar, "1")
+
+		tool.VersionFunc = func(ctx context.Context) (string, error) {
+			t.Fatal("VersionFunc should not be called in offline mode")
+
+			return "", nil
+		}
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.29.0", version)
+	})
+
+	t.Run("fails when forced offline without cached versions", func(t *testing.T) {
+		tool := newOfflineTestTool(t, nil)
+		tool.Offline = true
+
+		_, err := tool.ResolveVersion(context.Background())
+		require.Error(t, err)
+		assert.ErrorIs(t, err, errNoCachedVersion)
+		assert.Contains(t, err.Error(), "offline mode")
+	})
+
+	t.Run("pinned version takes precedence over offline mode", func(t *testing.T) {
+		tool := newOfflineTestTool(t, nil, "v1.30.0")
+		tool.Offline = true
+		tool.Project = &ProjectConfig{Versions: map[string]string{"kubectl": "v1.28.0"}}
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.28.0", version)
+	})
+
+	t.Run("falls back to cached version when lookup fails", func(t *testing.T) {
+		var progress bytes.Buffer
+
+		tool := newOfflineTestTool(t, &progress, "v1.29.0")
+		tool.VersionFunc = func(ctx context.Context) (string, error) {
+			return "", fmt.Errorf("network unreachable")
+		}
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.29.0", version)
+		assert.Contains(t, progress.String(), "Warning: failed to look up latest kubectl version")
+		assert.Contains(t, progress.String(), "network unreachable")
+	})
+
+	t.Run("returns lookup error when nothing is cached", func(t *testing.T) {
+		tool := newOfflineTestTool(t, nil)
+		tool.VersionFunc = func(ctx context.Context) (string, error) {
+			return "", fmt.Errorf("network unreachable")
+		}
+
+		_, err := tool.ResolveVersion(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "network unreachable")
+	})
+
+	t.Run("handles progress write error on fallback", func(t *testing.T) {
+		tool := newOfflineTestTool(t, nil, "v1.29.0")
+		tool.ProgressWriter = testutil.NewErrorWriter(fmt.Errorf("write error"))
+		tool.VersionFunc = func(ctx context.Context) (string, error) {
+			return "", fmt.Errorf("network unreachable")
+		}
+
+		_, err := tool.ResolveVersion(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to write progress")
+	})
+}
@@ -0,0 +1,68 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+)
+
+// VersionStatus compares the cached, pinned and upstream versions of a tool.
+type VersionStatus struct {
+	// Cached is the newest cached version (empty if nothing is cached).
+	Cached string
+	// Pinned is the version spec from the project configuration (empty if not pinned).
+	Pinned string
+	// Wanted is the version the pin (or lockfile) resolves to, i.e. what would be executed.
+	Wanted string
+	// Latest is the latest upstream release (empty if unknown, e.g. in offline mode).
+	Latest string
+}
+
+// CacheBehind reports whether the wanted version is newer than anything cached.
+// A tool that is not cached at all is not considered behind.
+func (s VersionStatus) CacheBehind() bool {
+	return s.Cached != "" && compareVersions(s.Cached, s.Wanted) < 0
+}
+
+// PinBehind reports whether the wanted version is older than the latest release.
+// A tool whose latest release is unknown is not considered behind.
+func (s VersionStatus) PinBehind() bool {
+	return s.Latest != "" && compareVersions(s.Wanted, s.Latest) < 0
+}
+
+// Outdated reports whether the cache or the pin lags behind.
+func (s VersionStatus) Outdated() bool {
+	return s.CacheBehind() || s.PinBehind()
+}
+
+// Status determines the cached, pinned, wanted and latest versions of the tool.
+// In offline mode, the latest version is not looked up and left empty.
+func (t *Tool) Status(ctx context.Context) (VersionStatus, error) {
+	var status VersionStatus
+
+	versions, err := t.CachedVersions()
+	if err != nil {
+		return status, fmt.Errorf("failed to get cached versions: %w", err)
+	}
+
+	if len(versions) > 0 {
+		status.Cached = versions[0].Version
+	}
+
+	if status.Pinned, err = t.PinnedSpec(); err != nil {
+		return status, err
+	}
+
+	if status.Wanted, err = t.ResolveVersion(ctx); err != nil {
+		return status, fmt.Errorf("failed to get version: %w", err)
+	}
+
+	if t.isOffline() {
+		return status, nil
+	}
+
+	if status.Latest, err = t.LatestVersion(ctx); err != nil {
+		return status, fmt.Errorf("failed to get latest version: %w", err)
+	}
+
+	return status, nil
+}
@@ -0,0 +1,125 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      VersionStatus
+		cacheBehind bool
+		pinBehind   bool
+	}{
+		{
+			name:   "up to date",
+			status: VersionStatus{Cached: "v1.31.0", Wanted: "v1.31.0", Latest: "v1.31.0"},
+		},
+		{
+			name:        "cache behind wanted",
+			status:      VersionStatus{Cached: "v1.30.0", Wanted: "v1.31.0", Latest: "v1.31.0"},
+			cacheBehind: true,
+		},
+		{
+			name:      "pin behind latest",
+			status:    VersionStatus{Cached: "v1.30.0", Pinned: "~1.30", Wanted: "v1.30.0", Latest: "v1.31.0"},
+			pinBehind: true,
+		},
+		{
+			name:   "not cached is not behind",
+			status: VersionStatus{Wanted: "v1.31.0", Latest: "v1.31.0"},
+		},
+		{
+			name:   "unknown latest version is not behind",
+			status: VersionStatus{Cached: "v1.30.0", Wanted: "v1.30.0"},
+		},
+		{
+			name:   "newer cached version is not behind",
+			status: VersionStatus{Cached: "v1.32.0", Wanted: "v1.31.0", Latest: "v1.31.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.cacheBehind, tt.status.CacheBehind())
+			assert.Equal(t, tt.pinBehind, tt.status.PinBehind())
+			assert.Equal(t, tt.cacheBehind || tt.pinBehind, tt.status.Outdated())
+		})
+	}
+}
+
+func TestToolStatus(t *testing.T) {
+	t.Run("collects cached, pinned, wanted and latest versions", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testHome)
+
+		for _, version := range []string{"v0.20.0", "v0.21.0"} {
+			binPath := filepath.Join(testHome, ".kdev", "kdev", "kind", version, "kind")
+			require.NoError(t, afero.WriteFile(fs, binPath, []byte("binary"), 0o755))
+		}
+
+		tool := &Tool{
+			Name:    "kind",
+			Fs:      fs,
+			Project: &ProjectConfig{Versions: map[string]string{"kind": "v0.21.0"}},
+			VersionFunc: func(ctx context.Context) (string, error) {
+				return "v0.22.0", nil
+			},
+		}
+
+		status, err := tool.Status(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, VersionStatus{Cached: "v0.21.0", Pinned: "v0.21.0", Wanted: "v0.21.0", Latest: "v0.22.0"}, status)
+		assert.True(t, status.Outdated())
+	})
+
+	t.Run("leaves the latest version unknown in offline mode", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testHome)
+		t.Setenv(OfflineEnvVar, "true")
+
+		binPath := filepath.Join(testHome, ".kdev", "kdev", "kind", "v0.21.0", "kind")
+		require.NoError(t, afero.WriteFile(fs, binPath, []byte("binary"), 0o755))
+
+		tool := &Tool{
+			Name:    "kind",
+			Fs:      fs,
+			Project: &ProjectConfig{},
+			VersionFunc: func(ctx context.Context) (string, error) {
+				t.Fatal("queried upstream in offline mode")
+
+				return "", nil
+			},
+		}
+
+		status, err := tool.Status(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, VersionStatus{Cached: "v0.21.0", Wanted: "v0.21.0"}, status)
+		assert.False(t, status.Outdated())
+	})
+
+	t.Run("returns error when latest version lookup fails", func(t *testing.T) {
+		t.Setenv("HOME", testHome)
+
+		tool := &Tool{
+			Name:    "kind",
+			Fs:      afero.NewMemMapFs(),
+			Project: &ProjectConfig{Versions: map[string]string{"kind": "v0.21.0"}},
+			VersionFunc: func(ctx context.Context) (string, error) {
+				return "", errors.New("network down")
+			},
+		}
+
+		_, err := tool.Status(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to get latest version")
+	})
+}
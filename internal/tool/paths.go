@@ -28,3 +28,18 @@ func DataDir(fs afero.Fs) (string, error) {
 
 	return filepath.Join(homeDir, ".kdev"), nil
 }
+
+// ConfigDir returns the configuration directory following XDG Base Directory spec.
+// Priority: XDG_CONFIG_HOME > ~/.config.
+func ConfigDir() (string, error) {
+	if xdgConfig := os.Getenv("XDG_CONFIG_HOME"); xdgConfig != "" {
+		return xdgConfig, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homeDir, ".config"), nil
+}
@@ -133,3 +133,22 @@ func TestFSHelper_NewWithNilFs(t *testing.T) {
 		assert.NotNil(t, helper.Fs())
 	})
 }
+
+func TestConfigDir(t *testing.T) {
+	t.Run("uses XDG_CONFIG_HOME when set", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", "/custom/config")
+
+		dir, err := ConfigDir()
+		require.NoError(t, err)
+		assert.Equal(t, "/custom/config", dir)
+	})
+
+	t.Run("falls back to ~/.config", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", "")
+		t.Setenv("HOME", "/home/testuser")
+
+		dir, err := ConfigDir()
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join("/home/testuser", ".config"), dir)
+	})
+}
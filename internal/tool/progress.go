@@ -94,6 +94,29 @@ func (pr *ProgressReader) Finish() {
 	}
 }
 
+// ProgressFunc receives the number of bytes downloaded so far and the total
+// size of a download. The total is 0 if the size is unknown.
+type ProgressFunc func(current, total int64)
+
+// callbackReader wraps an io.Reader and reports progress to a ProgressFunc.
+type callbackReader struct {
+	reader  io.Reader
+	report  ProgressFunc
+	total   int64
+	current int64
+}
+
+// Read implements io.Reader and reports progress.
+func (cr *callbackReader) Read(p []byte) (int, error) {
+	n, err := cr.reader.Read(p)
+	if n > 0 {
+		cr.current += int64(n)
+		cr.report(cr.current, cr.total)
+	}
+
+	return n, err
+}
+
 // ProgressWriter wraps progress messages for non-interactive output.
 type ProgressWriter struct {
 	writer io.Writer
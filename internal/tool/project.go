@@ -0,0 +1,207 @@
+package tool
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ProjectConfigFile is the name of the project-level configuration file.
+	ProjectConfigFile = ".kdev.yaml"
+
+	// defaultLatestVersionTTL is how long a looked up upstream version is reused.
+	defaultLatestVersionTTL = time.Hour
+)
+
+// projectSettings lists the top-level keys of .kdev.yaml that are settings.
+// Any other top-level key names a tool and pins its version.
+var projectSettings = map[string]bool{
+	"versions":   true,
+	"channels":   true,
+	"versionTTL": true,
+	"mirrors":    true,
+	"verify":     true,
+}
+
+// ProjectConfig holds project-level settings read from a .kdev.yaml file.
+// Versions are pinned with top-level tool keys (e.g. "kubectl: v1.30.4") or
+// in the versions map.
+type ProjectConfig struct {
+	// Versions pins tools to a specific version, keyed by tool name.
+	Versions map[string]string `yaml:"versions"`
+	// Channels selects the release channel followed by a tool (e.g. "latest" or "latest-1.31"), keyed by tool name.
+	Channels map[string]string `yaml:"channels"`
+	// VersionTTL controls how long upstream version lookups are cached (e.g. "30m", "0s" to disable).
+	VersionTTL *time.Duration `yaml:"versionTTL"`
+	// Mirrors rewrites upstream download, checksum and version URLs, e.g. for air-gapped networks.
+	Mirrors []MirrorRule `yaml:"mirrors"`
+	// Verify checks cached binaries against their recorded checksum and the host platform before each execution.
+	Verify bool `yaml:"verify"`
+	// Path is the location of the loaded file (empty if none was found).
+	Path string `yaml:"-"`
+}
+
+// UnmarshalYAML decodes the settings of a .kdev.yaml file and collects
+// top-level tool keys into Versions.
+func (c *ProjectConfig) UnmarshalYAML(node *yaml.Node) error {
+	type settings ProjectConfig
+
+	if node.Kind != yaml.MappingNode {
+		return node.Decode((*settings)(c))
+	}
+
+	known := yaml.Node{Kind: yaml.MappingNode, Tag: node.Tag, Line: node.Line, Column: node.Column}
+	pins := make(map[string]*yaml.Node)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		if projectSettings[key.Value] {
+			known.Content = append(known.Content, key, value)
+
+			continue
+		}
+
+		if value.Kind != yaml.ScalarNode {
+			return fmt.Errorf("line %d: unknown setting %q (top-level tool keys take a version)", key.Line, key.Value)
+		}
+
+		pins[key.Value] = value
+	}
+
+	if err := known.Decode((*settings)(c)); err != nil {
+		return err
+	}
+
+	if len(pins) > 0 && c.Versions == nil {
+		c.Versions = make(map[string]string, len(pins))
+	}
+
+	for name, value := range pins {
+		if pinned, ok := c.Versions[name]; ok && pinned != value.Value {
+			return fmt.Errorf("line %d: %s is pinned to both %s and %s", value.Line, name, value.Value, pinned)
+		}
+
+		c.Versions[name] = value.Value
+	}
+
+	return nil
+}
+
+// FindProjectConfig walks up from dir and returns the path of the first
+// .kdev.yaml found, or an empty string if there is none.
+func FindProjectConfig(fs afero.Fs, dir string) string {
+	return findUp(fs, dir, ProjectConfigFile)
+}
+
+// findUp walks up from dir and returns the path of the first file called name,
+// or an empty string if there is none.
+func findUp(fs afero.Fs, dir, name string) string {
+	helper := NewFSHelper(fs)
+
+	for {
+		candidate := filepath.Join(dir, name)
+		if helper.Exists(candidate) {
+			return candidate
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+
+		dir = parent
+	}
+}
+
+// LoadProjectConfig loads the project configuration that applies to dir.
+// It returns an empty configuration if no .kdev.yaml is found.
+func LoadProjectConfig(fs afero.Fs, dir string) (*ProjectConfig, error) {
+	path := FindProjectConfig(fs, dir)
+	if path == "" {
+		return &ProjectConfig{}, nil
+	}
+
+	data, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var cfg ProjectConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for i, rule := range cfg.Mirrors {
+		if rule.From == "" || rule.To == "" {
+			return nil, fmt.Errorf("invalid mirror %d in %s: from and to are required", i+1, path)
+		}
+	}
+
+	cfg.Path = path
+
+	return &cfg, nil
+}
+
+// loadProjectConfigFromWd loads the project configuration for the current working directory.
+func loadProjectConfigFromWd(fs afero.Fs) (*ProjectConfig, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine working directory: %w", err)
+	}
+
+	return LoadProjectConfig(fs, wd)
+}
+
+// PinnedVersion returns the version pinned for the named tool, or an empty string.
+func (c *ProjectConfig) PinnedVersion(name string) string {
+	if c == nil {
+		return ""
+	}
+
+	return c.Versions[name]
+}
+
+// ChannelName returns the release channel configured for the named tool, or an empty string.
+func (c *ProjectConfig) ChannelName(name string) string {
+	if c == nil {
+		return ""
+	}
+
+	return c.Channels[name]
+}
+
+// MirrorRules returns the mirror rules that apply to the named tool. Rules
+// for the tool come before global rules, so that they take precedence.
+func (c *ProjectConfig) MirrorRules(name string) []MirrorRule {
+	if c == nil {
+		return nil
+	}
+
+	var toolRules, globalRules []MirrorRule
+
+	for _, rule := range c.Mirrors {
+		switch rule.Tool {
+		case name:
+			toolRules = append(toolRules, rule)
+		case "":
+			globalRules = append(globalRules, rule)
+		}
+	}
+
+	return append(toolRules, globalRules...)
+}
+
+// LatestVersionTTL returns how long upstream version lookups are cached.
+func (c *ProjectConfig) LatestVersionTTL() time.Duration {
+	if c == nil || c.VersionTTL == nil {
+		return defaultLatestVersionTTL
+	}
+
+	return *c.VersionTTL
+}
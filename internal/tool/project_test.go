@@ -0,0 +1,219 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProjectDir = "/work/project"
+
+func TestFindProjectConfig(t *testing.T) {
+	t.Run("finds config in start directory", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, ProjectConfigFile)
+		require.NoError(t, afero.WriteFile(fs, path, []byte("versions: {}\n"), 0o644))
+
+		assert.Equal(t, path, FindProjectConfig(fs, testProjectDir))
+	})
+
+	t.Run("walks up to parent directories", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, ProjectConfigFile)
+		require.NoError(t, afero.WriteFile(fs, path, []byte("versions: {}\n"), 0o644))
+
+		subDir := filepath.Join(testProjectDir, "deploy", "overlays")
+		require.NoError(t, fs.MkdirAll(subDir, 0o755))
+
+		assert.Equal(t, path, FindProjectConfig(fs, subDir))
+	})
+
+	t.Run("prefers the closest config", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		outer := filepath.Join(testProjectDir, ProjectConfigFile)
+		inner := filepath.Join(testProjectDir, "sub", ProjectConfigFile)
+		require.NoError(t, afero.WriteFile(fs, outer, []byte("versions: {}\n"), 0o644))
+		require.NoError(t, afero.WriteFile(fs, inner, []byte("versions: {}\n"), 0o644))
+
+		assert.Equal(t, inner, FindProjectConfig(fs, filepath.Join(testProjectDir, "sub")))
+	})
+
+	t.Run("returns empty string when not found", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		assert.Empty(t, FindProjectConfig(fs, testProjectDir))
+	})
+
+	t.Run("ignores directories named like the config file", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, fs.MkdirAll(filepath.Join(testProjectDir, ProjectConfigFile), 0o755))
+
+		assert.Empty(t, FindProjectConfig(fs, testProjectDir))
+	})
+}
+
+func TestLoadProjectConfig(t *testing.T) {
+	t.Run("loads pinned versions", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, ProjectConfigFile)
+		content := "versions:\n  kubectl: v1.30.4\n  kind: v0.22.0\n"
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0o644))
+
+		cfg, err := LoadProjectConfig(fs, testProjectDir)
+		require.NoError(t, err)
+		assert.Equal(t, path, cfg.Path)
+		assert.Equal(t, "v1.30.4", cfg.PinnedVersion("kubectl"))
+		assert.Equal(t, "v0.22.0", cfg.PinnedVersion("kind"))
+		assert.Empty(t, cfg.PinnedVersion("cilium"))
+	})
+
+	t.Run("loads versions pinned with top-level tool keys", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, ProjectConfigFile)
+		content := "kubectl: v1.30.4\nkind: v0.22.0\nversions:\n  cilium: v0.16.0\nverify: true\n"
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0o644))
+
+		cfg, err := LoadProjectConfig(fs, testProjectDir)
+		require.NoError(t, err)
+		assert.Equal(t, "v1.30.4", cfg.PinnedVersion("kubectl"))
+		assert.Equal(t, "v0.22.0", cfg.PinnedVersion("kind"))
+		assert.Equal(t, "v0.16.0", cfg.PinnedVersion("cilium"))
+		assert.True(t, cfg.Verify)
+	})
+
+	t.Run("rejects a tool pinned twice", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, ProjectConfigFile)
+		content := "kubectl: v1.30.4\nversions:\n  kubectl: v1.29.0\n"
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0o644))
+
+		_, err := LoadProjectConfig(fs, testProjectDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "kubectl is pinned to both v1.30.4 and v1.29.0")
+	})
+
+	t.Run("rejects unknown settings", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, ProjectConfigFile)
+		require.NoError(t, afero.WriteFile(fs, path, []byte("version:\n  kubectl: v1.30.4\n"), 0o644))
+
+		_, err := LoadProjectConfig(fs, testProjectDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown setting "version"`)
+	})
+
+	t.Run("returns empty config when no file exists", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		cfg, err := LoadProjectConfig(fs, testProjectDir)
+		require.NoError(t, err)
+		require.NotNil(t, cfg)
+		assert.Empty(t, cfg.Path)
+		assert.Empty(t, cfg.PinnedVersion("kubectl"))
+	})
+
+	t.Run("returns error for invalid YAML", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, ProjectConfigFile)
+		require.NoError(t, afero.WriteFile(fs, path, []byte("versions: [unclosed\n"), 0o644))
+
+		_, err := LoadProjectConfig(fs, testProjectDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse")
+	})
+}
+
+func TestProjectConfigLatestVersionTTL(t *testing.T) {
+	t.Run("defaults when unset", func(t *testing.T) {
+		var cfg *ProjectConfig
+
+		assert.Equal(t, defaultLatestVersionTTL, cfg.LatestVersionTTL())
+		assert.Equal(t, defaultLatestVersionTTL, (&ProjectConfig{}).LatestVersionTTL())
+	})
+
+	t.Run("parses duration from YAML", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, ProjectConfigFile)
+		require.NoError(t, afero.WriteFile(fs, path, []byte("versionTTL: 30m\n"), 0o644))
+
+		cfg, err := LoadProjectConfig(fs, testProjectDir)
+		require.NoError(t, err)
+		assert.Equal(t, 30*time.Minute, cfg.LatestVersionTTL())
+	})
+
+	t.Run("allows disabling with zero", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, ProjectConfigFile)
+		require.NoError(t, afero.WriteFile(fs, path, []byte("versionTTL: 0s\n"), 0o644))
+
+		cfg, err := LoadProjectConfig(fs, testProjectDir)
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(0), cfg.LatestVersionTTL())
+	})
+}
+
+func TestProjectConfigPinnedVersion(t *testing.T) {
+	t.Run("handles nil config", func(t *testing.T) {
+		var cfg *ProjectConfig
+
+		assert.Empty(t, cfg.PinnedVersion("kubectl"))
+	})
+}
+
+func TestProjectConfigChannelName(t *testing.T) {
+	t.Run("returns configured channel", func(t *testing.T) {
+		cfg := &ProjectConfig{Channels: map[string]string{"kubectl": "latest-1.31"}}
+
+		assert.Equal(t, "latest-1.31", cfg.ChannelName("kubectl"))
+		assert.Empty(t, cfg.ChannelName("kind"))
+	})
+
+	t.Run("handles nil config", func(t *testing.T) {
+		var cfg *ProjectConfig
+
+		assert.Empty(t, cfg.ChannelName("kubectl"))
+	})
+}
+
+func TestProjectConfigMirrorRules(t *testing.T) {
+	t.Run("orders tool rules before global rules", func(t *testing.T) {
+		global := MirrorRule{From: "https://github.com/", To: "https://mirror.example.com/github/"}
+		kind := MirrorRule{From: "https://github.com/", To: "https://mirror.example.com/kind/", Tool: "kind"}
+		cfg := &ProjectConfig{Mirrors: []MirrorRule{global, kind}}
+
+		assert.Equal(t, []MirrorRule{kind, global}, cfg.MirrorRules("kind"))
+		assert.Equal(t, []MirrorRule{global}, cfg.MirrorRules("cilium"))
+	})
+
+	t.Run("handles nil config", func(t *testing.T) {
+		var cfg *ProjectConfig
+
+		assert.Empty(t, cfg.MirrorRules("kubectl"))
+	})
+
+	t.Run("parses mirrors from YAML", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, ProjectConfigFile)
+		content := "mirrors:\n  - from: https://dl.k8s.io/\n    to: http://files.lab/k8s/\n    tool: kubectl\n"
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0o644))
+
+		cfg, err := LoadProjectConfig(fs, testProjectDir)
+		require.NoError(t, err)
+		assert.Equal(t, []MirrorRule{{From: "https://dl.k8s.io/", To: "http://files.lab/k8s/", Tool: "kubectl"}}, cfg.Mirrors)
+	})
+
+	t.Run("rejects incomplete mirrors", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		path := filepath.Join(testProjectDir, ProjectConfigFile)
+		require.NoError(t, afero.WriteFile(fs, path, []byte("mirrors:\n  - from: https://dl.k8s.io/\n"), 0o644))
+
+		_, err := LoadProjectConfig(fs, testProjectDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid mirror 1")
+	})
+}
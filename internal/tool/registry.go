@@ -1,8 +1,11 @@
 package tool
 
 import (
+	"fmt"
 	"io"
 	"sort"
+
+	"github.com/spf13/afero"
 )
 
 // Registry holds all available tools.
@@ -10,14 +13,81 @@ type Registry struct {
 	tools map[string]*Tool
 }
 
-// NewRegistry creates a registry with all available tools.
-func NewRegistry(progress io.Writer) *Registry {
-	return &Registry{
-		tools: map[string]*Tool{
-			"cilium":  NewCilium(progress),
-			"kind":    NewKind(progress),
-			"kubectl": NewKubectl(progress),
-		},
+// NewRegistry creates a registry with the built-in tools and the user-defined
+// tools declared in the file at ToolsConfigPath. If that file is invalid, the
+// returned registry still holds the built-in tools, so that callers can report
+// the error as a warning and carry on.
+func NewRegistry(progress io.Writer) (*Registry, error) {
+	registry := NewRegistryFromConfigs(BuiltinConfigs(), progress)
+
+	path, err := ToolsConfigPath()
+	if err != nil {
+		return registry, err
+	}
+
+	cfg, err := LoadToolsConfig(afero.NewOsFs(), path)
+	if err != nil {
+		return registry, err
+	}
+
+	if err := registry.addDefinitions(cfg, progress); err != nil {
+		return registry, err
+	}
+
+	return registry, nil
+}
+
+// NewRegistryFromConfigs creates a registry with a tool for each configuration.
+func NewRegistryFromConfigs(configs []Config, progress io.Writer) *Registry {
+	registry := &Registry{tools: make(map[string]*Tool, len(configs))}
+
+	for _, cfg := range configs {
+		registry.tools[cfg.Name] = NewToolFromConfig(cfg, progress)
+	}
+
+	return registry
+}
+
+// addDefinitions registers the user-defined tools of cfg, which must not
+// replace built-in tools. No tool is added if any definition is invalid.
+func (r *Registry) addDefinitions(cfg *ToolsConfig, progress io.Writer) error {
+	tools := make([]*Tool, 0, len(cfg.Tools))
+
+	for _, def := range cfg.Tools {
+		if r.tools[def.Name] != nil {
+			return fmt.Errorf("invalid tool %s in %s: conflicts with a built-in tool", def.Name, cfg.Path)
+		}
+
+		toolCfg, err := def.toolConfig()
+		if err != nil {
+			return fmt.Errorf("invalid tool %s in %s: %w", def.Name, cfg.Path, err)
+		}
+
+		tools = append(tools, NewToolFromConfig(toolCfg, progress))
+	}
+
+	for _, tool := range tools {
+		r.tools[tool.Name] = tool
+	}
+
+	for _, tool := range r.tools {
+		tool.UserConfig = cfg
+	}
+
+	return nil
+}
+
+// SetOffline enables or disables offline mode for all registered tools.
+func (r *Registry) SetOffline(offline bool) {
+	for _, tool := range r.tools {
+		tool.Offline = offline
+	}
+}
+
+// SetProgressWriter sets the writer receiving progress messages of all registered tools.
+func (r *Registry) SetProgressWriter(progress io.Writer) {
+	for _, tool := range r.tools {
+		tool.ProgressWriter = progress
 	}
 }
 
@@ -3,17 +3,31 @@ package tool
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// writeTestToolsConfig points KDEV_TOOLS_CONFIG at a file with content.
+func writeTestToolsConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "tools.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	t.Setenv(ToolsConfigEnvVar, path)
+
+	return path
+}
+
 func TestNewRegistry(t *testing.T) {
 	t.Run("creates registry with all tools", func(t *testing.T) {
 		var buf bytes.Buffer
 
-		registry := NewRegistry(&buf)
+		registry, err := NewRegistry(&buf)
+		require.NoError(t, err)
 
 		require.NotNil(t, registry)
 		require.NotNil(t, registry.tools)
@@ -28,7 +42,8 @@ func TestNewRegistry(t *testing.T) {
 	})
 
 	t.Run("creates registry with nil progress writer", func(t *testing.T) {
-		registry := NewRegistry(nil)
+		registry, err := NewRegistry(nil)
+		require.NoError(t, err)
 
 		require.NotNil(t, registry)
 
@@ -42,9 +57,56 @@ func TestNewRegistry(t *testing.T) {
 	})
 }
 
+func TestNewRegistryUserDefinedTools(t *testing.T) {
+	t.Run("adds user-defined tools", func(t *testing.T) {
+		writeTestToolsConfig(t, `tools:
+  - name: helm
+    github: helm/helm
+    download: https://get.helm.sh/helm-{{.Version}}-{{.OS}}-{{.Arch}}.tar.gz
+    checksum: https://get.helm.sh/helm-{{.Version}}-{{.OS}}-{{.Arch}}.tar.gz.sha256sum
+`)
+
+		var buf bytes.Buffer
+
+		registry, err := NewRegistry(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"cilium", "helm", "kind", "kubectl"}, registry.All())
+
+		helm := registry.Get("helm")
+		require.NotNil(t, helm)
+		assert.Equal(t, "helm", helm.Name)
+		assert.Equal(t, &buf, helm.ProgressWriter)
+		assert.Equal(t, "https://get.helm.sh/helm-v3.15.0-linux-amd64.tar.gz", helm.DownloadURL("v3.15.0", "linux", "amd64"))
+	})
+
+	t.Run("rejects tools replacing built-in tools", func(t *testing.T) {
+		path := writeTestToolsConfig(t, `tools:
+  - name: kubectl
+    versionURL: https://example.com/stable.txt
+    download: https://example.com/{{.Version}}/kubectl
+    checksum: https://example.com/{{.Version}}/kubectl.sha256
+`)
+
+		registry, err := NewRegistry(nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid tool kubectl in "+path+": conflicts with a built-in tool")
+		assert.Equal(t, []string{"cilium", "kind", "kubectl"}, registry.All(), "no user-defined tool is added")
+	})
+
+	t.Run("reports invalid configurations", func(t *testing.T) {
+		writeTestToolsConfig(t, "tools:\n  - name: broken\n")
+
+		registry, err := NewRegistry(nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "github or versionURL is required")
+		assert.Equal(t, []string{"cilium", "kind", "kubectl"}, registry.All(), "built-in tools keep working")
+	})
+}
+
 func TestRegistryGet(t *testing.T) {
 	t.Run("returns tool when found", func(t *testing.T) {
-		registry := NewRegistry(nil)
+		registry, err := NewRegistry(nil)
+		require.NoError(t, err)
 
 		tool := registry.Get("kubectl")
 		require.NotNil(t, tool)
@@ -52,7 +114,8 @@ func TestRegistryGet(t *testing.T) {
 	})
 
 	t.Run("returns nil when not found", func(t *testing.T) {
-		registry := NewRegistry(nil)
+		registry, err := NewRegistry(nil)
+		require.NoError(t, err)
 
 		tool := registry.Get("nonexistent")
 		assert.Nil(t, tool)
@@ -61,7 +124,10 @@ func TestRegistryGet(t *testing.T) {
 
 func TestRegistryAll(t *testing.T) {
 	t.Run("returns all tool names sorted alphabetically", func(t *testing.T) {
-		registry := NewRegistry(nil)
+		t.Setenv(ToolsConfigEnvVar, filepath.Join(t.TempDir(), "tools.yaml"))
+
+		registry, err := NewRegistry(nil)
+		require.NoError(t, err)
 
 		names := registry.All()
 		require.Len(t, names, 3)
@@ -75,7 +141,10 @@ func TestRegistryAll(t *testing.T) {
 
 func TestRegistryAllTools(t *testing.T) {
 	t.Run("returns all tool instances sorted by name", func(t *testing.T) {
-		registry := NewRegistry(nil)
+		t.Setenv(ToolsConfigEnvVar, filepath.Join(t.TempDir(), "tools.yaml"))
+
+		registry, err := NewRegistry(nil)
+		require.NoError(t, err)
 
 		tools := registry.AllTools()
 		require.Len(t, tools, 3)
@@ -86,3 +155,35 @@ func TestRegistryAllTools(t *testing.T) {
 		assert.Equal(t, "kubectl", tools[2].Name)
 	})
 }
+
+func TestRegistrySetOffline(t *testing.T) {
+	t.Run("applies offline mode to all tools", func(t *testing.T) {
+		registry, err := NewRegistry(nil)
+		require.NoError(t, err)
+
+		registry.SetOffline(true)
+
+		for _, tool := range registry.AllTools() {
+			assert.True(t, tool.Offline, "%s should be offline", tool.Name)
+		}
+
+		registry.SetOffline(false)
+
+		for _, tool := range registry.AllTools() {
+			assert.False(t, tool.Offline, "%s should be online", tool.Name)
+		}
+	})
+}
+
+func TestRegistrySetProgressWriter(t *testing.T) {
+	registry, err := NewRegistry(nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	registry.SetProgressWriter(&buf)
+
+	for _, tool := range registry.AllTools() {
+		assert.Equal(t, &buf, tool.ProgressWriter, "%s should write progress to the buffer", tool.Name)
+	}
+}
@@ -0,0 +1,106 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// partialSuffix is appended to the destination path of an incomplete download.
+	partialSuffix = ".partial"
+
+	// partialMetaSuffix is appended to the partial file path for its validator metadata.
+	partialMetaSuffix = ".json"
+)
+
+// partialDownload records where an incomplete download came from and how to
+// check that the remote content has not changed before resuming it.
+type partialDownload struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// newPartialDownload captures the validators of a full (200) download response.
+func newPartialDownload(url string, resp *http.Response) partialDownload {
+	return partialDownload{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+}
+
+// validator returns the value for an If-Range header. Weak ETags cannot be
+// used for range requests, so Last-Modified is used instead.
+func (p partialDownload) validator() string {
+	if p.ETag != "" && !strings.HasPrefix(p.ETag, "W/") {
+		return p.ETag
+	}
+
+	return p.LastModified
+}
+
+// resumeOffset returns how many bytes of url are already stored at partialPath
+// and the metadata to resume them. It returns 0 if there is nothing to resume.
+func resumeOffset(fs afero.Fs, partialPath, url string) (int64, partialDownload) {
+	data, err := afero.ReadFile(fs, partialPath+partialMetaSuffix)
+	if err != nil {
+		return 0, partialDownload{}
+	}
+
+	var meta partialDownload
+	if err := json.Unmarshal(data, &meta); err != nil || meta.URL != url || meta.validator() == "" {
+		return 0, partialDownload{}
+	}
+
+	info, err := fs.Stat(partialPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return 0, partialDownload{}
+	}
+
+	return info.Size(), meta
+}
+
+// writePartialMeta stores the metadata needed to resume the download at partialPath.
+// Nothing is stored if the server provided no validator, as resuming would be unsafe.
+func writePartialMeta(fs afero.Fs, partialPath string, meta partialDownload) error {
+	if meta.validator() == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to encode download metadata: %w", err)
+	}
+
+	return afero.WriteFile(fs, partialPath+partialMetaSuffix, data, 0o644)
+}
+
+// removePartial deletes an incomplete download and its metadata.
+func removePartial(fs afero.Fs, partialPath string) error {
+	if err := fs.Remove(partialPath + partialMetaSuffix); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := fs.Remove(partialPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// completeContentRange reports whether a 416 response states that the content
+// is size bytes long, i.e. that a partial file of that size is complete.
+func completeContentRange(resp *http.Response, size int64) bool {
+	return resp.Header.Get("Content-Range") == fmt.Sprintf("bytes */%d", size)
+}
+
+// validContentRange reports whether a 206 response continues at offset.
+func validContentRange(resp *http.Response, offset int64) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset))
+}
@@ -0,0 +1,255 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newResumeTestServer serves content with Range support. The first request is
+// cut off after half of the content to simulate a dropped connection.
+func newResumeTestServer(t *testing.T, content []byte, etag string, ranges *[]string) *httptest.Server {
+	t.Helper()
+
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*ranges = append(*ranges, r.Header.Get("Range"))
+
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+
+		if requests.Add(1) == 1 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(content[:len(content)/2]) //nolint:errcheck // test helper
+
+			panic(http.ErrAbortHandler)
+		}
+
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestDownloadResume(t *testing.T) {
+	content := bytes.Repeat([]byte("kind binary "), 1000)
+	checksum := fmt.Sprintf("%x", sha256.Sum256(content))
+
+	checksumServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(checksum)) //nolint:errcheck // test helper
+	}))
+	defer checksumServer.Close()
+
+	newTool := func(fs afero.Fs, url string) *Tool {
+		return &Tool{
+			Name: "testtool",
+			Fs:   fs,
+			DownloadURL: func(version, goos, goarch string) string {
+				return url
+			},
+			ChecksumURL: func(version, goos, goarch string) string {
+				return checksumServer.URL
+			},
+		}
+	}
+
+	t.Run("resumes interrupted download with range request", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		var ranges []string
+
+		server := newResumeTestServer(t, content, `"v1"`, &ranges)
+		tool := newTool(fs, server.URL)
+
+		err := tool.download(context.Background(), testToolPath, testVersion)
+		require.Error(t, err)
+
+		partial, err := afero.ReadFile(fs, testToolPath+partialSuffix)
+		require.NoError(t, err)
+		assert.Equal(t, content[:len(content)/2], partial)
+
+		err = tool.download(context.Background(), testToolPath, testVersion)
+		require.NoError(t, err)
+
+		data, err := afero.ReadFile(fs, testToolPath)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+		assert.Equal(t, []string{"", fmt.Sprintf("bytes=%d-", len(content)/2)}, ranges)
+
+		for _, leftover := range []string{testToolPath + partialSuffix, testToolPath + partialSuffix + partialMetaSuffix} {
+			exists, err := afero.Exists(fs, leftover)
+			require.NoError(t, err)
+			assert.False(t, exists, leftover)
+		}
+	})
+
+	t.Run("restarts when content changed", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		var ranges []string
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ranges = append(ranges, r.Header.Get("Range"))
+			w.Header().Set("ETag", `"v2"`)
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+		}))
+		defer server.Close()
+
+		tool := newTool(fs, server.URL)
+
+		// Leave a partial file from an older upstream revision behind.
+		require.NoError(t, afero.WriteFile(fs, testToolPath+partialSuffix, []byte("stale"), 0o644))
+		require.NoError(t, writePartialMeta(fs, testToolPath+partialSuffix, partialDownload{URL: server.URL, ETag: `"v1"`}))
+
+		require.NoError(t, tool.download(context.Background(), testToolPath, testVersion))
+		assert.Equal(t, []string{"bytes=5-"}, ranges)
+
+		data, err := afero.ReadFile(fs, testToolPath)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+	})
+
+	t.Run("verifies a complete partial file", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		var ranges []string
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ranges = append(ranges, r.Header.Get("Range"))
+			w.Header().Set("ETag", `"v1"`)
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+		}))
+		defer server.Close()
+
+		tool := newTool(fs, server.URL)
+
+		// Leave a complete, but unverified partial file behind.
+		require.NoError(t, afero.WriteFile(fs, testToolPath+partialSuffix, content, 0o644))
+		require.NoError(t, writePartialMeta(fs, testToolPath+partialSuffix, partialDownload{URL: server.URL, ETag: `"v1"`}))
+
+		require.NoError(t, tool.download(context.Background(), testToolPath, testVersion))
+		assert.Equal(t, []string{fmt.Sprintf("bytes=%d-", len(content))}, ranges)
+
+		data, err := afero.ReadFile(fs, testToolPath)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+	})
+
+	t.Run("restarts when the partial file is larger than the content", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		var ranges []string
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ranges = append(ranges, r.Header.Get("Range"))
+			w.Header().Set("ETag", `"v1"`)
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+		}))
+		defer server.Close()
+
+		tool := newTool(fs, server.URL)
+
+		oversized := append(bytes.Clone(content), "extra"...)
+		require.NoError(t, afero.WriteFile(fs, testToolPath+partialSuffix, oversized, 0o644))
+		require.NoError(t, writePartialMeta(fs, testToolPath+partialSuffix, partialDownload{URL: server.URL, ETag: `"v1"`}))
+
+		require.NoError(t, tool.download(context.Background(), testToolPath, testVersion))
+		assert.Equal(t, []string{fmt.Sprintf("bytes=%d-", len(oversized)), ""}, ranges)
+
+		data, err := afero.ReadFile(fs, testToolPath)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+	})
+
+	t.Run("discards partial file without validator", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		var ranges []string
+
+		server := newResumeTestServer(t, content, "", &ranges)
+		tool := newTool(fs, server.URL)
+
+		require.Error(t, tool.download(context.Background(), testToolPath, testVersion))
+
+		exists, err := afero.Exists(fs, testToolPath+partialSuffix)
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("discards partial file on checksum mismatch", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+
+		var ranges []string
+
+		server := newResumeTestServer(t, content, `"v1"`, &ranges)
+		tool := newTool(fs, server.URL)
+
+		require.Error(t, tool.download(context.Background(), testToolPath, testVersion))
+
+		// Corrupt the kept partial file so that the resumed content does not verify.
+		require.NoError(t, afero.WriteFile(fs, testToolPath+partialSuffix, bytes.Repeat([]byte("x"), len(content)/2), 0o644))
+
+		err := tool.download(context.Background(), testToolPath, testVersion)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "checksum mismatch")
+
+		exists, err := afero.Exists(fs, testToolPath+partialSuffix)
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
+
+func TestPartialDownloadValidator(t *testing.T) {
+	assert.Equal(t, `"abc"`, partialDownload{ETag: `"abc"`, LastModified: "Mon, 01 Jan 2024 00:00:00 GMT"}.validator())
+	assert.Equal(t, "Mon, 01 Jan 2024 00:00:00 GMT", partialDownload{ETag: `W/"abc"`, LastModified: "Mon, 01 Jan 2024 00:00:00 GMT"}.validator())
+	assert.Empty(t, partialDownload{ETag: `W/"abc"`}.validator())
+}
+
+func TestResumeOffset(t *testing.T) {
+	const url = "https://example.com/kind"
+
+	partialPath := testToolPath + partialSuffix
+
+	t.Run("returns size of partial file with matching metadata", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, partialPath, []byte("12345"), 0o644))
+		require.NoError(t, writePartialMeta(fs, partialPath, partialDownload{URL: url, ETag: `"v1"`}))
+
+		offset, meta := resumeOffset(fs, partialPath, url)
+		assert.Equal(t, int64(5), offset)
+		assert.Equal(t, `"v1"`, meta.ETag)
+	})
+
+	t.Run("ignores partial file of another URL", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, partialPath, []byte("12345"), 0o644))
+		require.NoError(t, writePartialMeta(fs, partialPath, partialDownload{URL: url, ETag: `"v1"`}))
+
+		offset, _ := resumeOffset(fs, partialPath, url+"-other")
+		assert.Zero(t, offset)
+	})
+
+	t.Run("ignores partial file without metadata", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, partialPath, []byte("12345"), 0o644))
+
+		offset, _ := resumeOffset(fs, partialPath, url)
+		assert.Zero(t, offset)
+	})
+}
@@ -0,0 +1,296 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/afero"
+)
+
+// blobStoreDir is the directory below DataDir/kdev that holds cached binaries
+// addressed by their sha256. The leading dot sets it apart from tool directories.
+//
+// Each binary is stored once per content, and every <tool>/<version>/<tool>
+// path is a hard link to its blob. Identical binaries, e.g. of a re-tagged
+// release, thus share storage, while the per-version paths stay regular files
+// for CachedVersions and Exec. The store is only used on the OS filesystem;
+// elsewhere, or where hard links fail, binaries remain plain files.
+const blobStoreDir = ".blobs"
+
+// blobDir returns the directory of the sha256 blobs.
+func blobDir(dataDir string) string {
+	return filepath.Join(dataDir, "kdev", blobStoreDir, "sha256")
+}
+
+// blobPath returns the path of the blob with the given sha256 checksum.
+func blobPath(dataDir, checksum string) string {
+	return filepath.Join(blobDir(dataDir), checksum)
+}
+
+// isBlobStoreDir reports whether name, an entry of DataDir/kdev, is the blob
+// store rather than a tool directory.
+func isBlobStoreDir(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// storeBlob moves the binary at binPath with the given sha256 into the blob
+// store and links it back into place. A binary identical to a stored blob is
+// replaced by a link to that blob. Blobs whose content no longer matches their
+// checksum are replaced rather than shared, and the versions linked to a damaged
+// blob are linked to its replacement.
+func storeBlob(fs afero.Fs, binPath, checksum string) error {
+	if _, ok := fs.(*afero.OsFs); !ok || checksum == "" {
+		return nil
+	}
+
+	dataDir, err := DataDir(fs)
+	if err != nil {
+		return fmt.Errorf("failed to determine data directory: %w", err)
+	}
+
+	blob := blobPath(dataDir, checksum)
+	if err := os.MkdirAll(filepath.Dir(blob), 0o755); err != nil {
+		return fmt.Errorf("failed to create blob store: %w", err)
+	}
+
+	if blobChecksum, _, err := fileSHA256(fs, blob); err == nil && blobChecksum == checksum {
+		return linkBlob(blob, binPath)
+	}
+
+	// Store the binary as a new blob, replacing a missing or damaged one.
+	damaged, damagedErr := os.Stat(blob)
+
+	tmpPath := blob + ".tmp"
+	_ = os.Remove(tmpPath) //nolint:errcheck // leftover of an interrupted run
+
+	if err := os.Link(binPath, tmpPath); err != nil {
+		// Filesystems without hard links keep the plain file.
+		return nil //nolint:nilerr // best effort
+	}
+
+	if err := os.Rename(tmpPath, blob); err != nil {
+		_ = os.Remove(tmpPath) //nolint:errcheck // cleanup on error path
+
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	if damagedErr != nil {
+		return nil
+	}
+
+	return relinkBlob(dataDir, damaged, blob)
+}
+
+// relinkBlob links every cached version that is still linked to the replaced
+// blob, given by its file info, to blob instead.
+func relinkBlob(dataDir string, replaced os.FileInfo, blob string) error {
+	kdevDir := filepath.Join(dataDir, "kdev")
+
+	tools, err := os.ReadDir(kdevDir)
+	if err != nil {
+		return fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	for _, tool := range tools {
+		if !tool.IsDir() || isBlobStoreDir(tool.Name()) {
+			continue
+		}
+
+		versions, err := os.ReadDir(filepath.Join(kdevDir, tool.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read tool directory: %w", err)
+		}
+
+		for _, version := range versions {
+			binPath := filepath.Join(kdevDir, tool.Name(), version.Name(), tool.Name())
+
+			if info, err := os.Stat(binPath); err != nil || !os.SameFile(info, replaced) {
+				continue
+			}
+
+			if err := linkBlob(blob, binPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// linkBlob atomically replaces the file at binPath by a hard link to blob.
+func linkBlob(blob, binPath string) error {
+	tmpPath := binPath + ".link"
+	_ = os.Remove(tmpPath) //nolint:errcheck // leftover of an interrupted run
+
+	if err := os.Link(blob, tmpPath); err != nil {
+		// Keep the plain file, e.g. when the blob was pruned concurrently.
+		return nil //nolint:nilerr // best effort
+	}
+
+	if err := os.Rename(tmpPath, binPath); err != nil {
+		_ = os.Remove(tmpPath) //nolint:errcheck // cleanup on error path
+
+		return fmt.Errorf("failed to link blob: %w", err)
+	}
+
+	return nil
+}
+
+// storedBlob returns the checksum of the blob that the binary at binPath is
+// linked to, or an empty string if it is a plain file.
+func storedBlob(fs afero.Fs, binPath string, metadata *VersionMetadata) string {
+	if _, ok := fs.(*afero.OsFs); !ok || metadata == nil || metadata.SHA256 == "" {
+		return ""
+	}
+
+	dataDir, err := DataDir(fs)
+	if err != nil {
+		return ""
+	}
+
+	binInfo, err := os.Stat(binPath)
+	if err != nil {
+		return ""
+	}
+
+	blobInfo, err := os.Stat(blobPath(dataDir, metadata.SHA256))
+	if err != nil || !os.SameFile(binInfo, blobInfo) {
+		return ""
+	}
+
+	return metadata.SHA256
+}
+
+// DiskUsage returns the disk space used by versions, counting binaries that
+// share a blob once.
+func DiskUsage(versions []CachedVersion) int64 {
+	var size int64
+
+	seen := make(map[string]bool, len(versions))
+
+	for _, v := range versions {
+		if key := v.storageKey(); !seen[key] {
+			seen[key] = true
+			size += v.Size
+		}
+	}
+
+	return size
+}
+
+// ReclaimedSize returns the disk space freed by removing versions from the
+// cache on fs. Like DiskUsage, it counts binaries sharing a blob once, and it
+// does not count blobs that versions of any tool left in the cache still link
+// to. It gives the same answer before and after the versions are removed.
+func ReclaimedSize(fs afero.Fs, removed []CachedVersion) (int64, error) {
+	cached, err := allCachedVersions(fs)
+	if err != nil {
+		return 0, err
+	}
+
+	isRemoved := make(map[string]bool, len(removed))
+	for _, v := range removed {
+		isRemoved[v.Path] = true
+	}
+
+	remaining := make(map[string]bool, len(cached))
+
+	for _, v := range cached {
+		if !isRemoved[v.Path] {
+			remaining[v.storageKey()] = true
+		}
+	}
+
+	freed := make([]CachedVersion, 0, len(removed))
+
+	for _, v := range removed {
+		if !remaining[v.storageKey()] {
+			freed = append(freed, v)
+		}
+	}
+
+	return DiskUsage(freed), nil
+}
+
+// allCachedVersions returns the cached versions of all tools in the cache on fs.
+func allCachedVersions(fs afero.Fs) ([]CachedVersion, error) {
+	dataDir, err := DataDir(fs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data directory: %w", err)
+	}
+
+	entries, err := afero.ReadDir(fs, filepath.Join(dataDir, "kdev"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var versions []CachedVersion
+
+	for _, entry := range entries {
+		if !entry.IsDir() || isBlobStoreDir(entry.Name()) {
+			continue
+		}
+
+		cached, err := (&Tool{Name: entry.Name(), Fs: fs}).CachedVersions()
+		if err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, cached...)
+	}
+
+	return versions, nil
+}
+
+// storageKey identifies the storage of a cached version: its blob, or its path
+// if it is a plain file.
+func (v CachedVersion) storageKey() string {
+	if v.Blob != "" {
+		return "blob:" + v.Blob
+	}
+
+	return v.Path
+}
+
+// pruneBlobs removes blobs that no cached version links to anymore.
+func pruneBlobs(fs afero.Fs) error {
+	if _, ok := fs.(*afero.OsFs); !ok {
+		return nil
+	}
+
+	dataDir, err := DataDir(fs)
+	if err != nil {
+		return fmt.Errorf("failed to determine data directory: %w", err)
+	}
+
+	dir := blobDir(dataDir)
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read blob store: %w", err)
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		// The store's own link is the last one left.
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Nlink <= 1 {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to prune blob: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
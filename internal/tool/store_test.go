@@ -0,0 +1,254 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// downloadStoreTestVersion adds version of tool with content to the cache on
+// the OS filesystem as a download would.
+func downloadStoreTestVersion(t *testing.T, tool *Tool, version, content string) string {
+	t.Helper()
+
+	dataDir, err := DataDir(tool.getFs())
+	require.NoError(t, err)
+
+	binPath := filepath.Join(dataDir, "kdev", tool.Name, version, tool.Name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(binPath), 0o755))
+	require.NoError(t, os.WriteFile(binPath, []byte(content), 0o755))
+	require.NoError(t, recordDownload(tool.getFs(), binPath, VersionMetadata{}))
+
+	return binPath
+}
+
+// storeTestBlobs returns the names of the blobs in the store.
+func storeTestBlobs(t *testing.T) []string {
+	t.Helper()
+
+	dataDir, err := DataDir(afero.NewOsFs())
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(blobDir(dataDir))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	return names
+}
+
+func requireSameFile(t *testing.T, a, b string) {
+	t.Helper()
+
+	aInfo, err := os.Stat(a)
+	require.NoError(t, err)
+
+	bInfo, err := os.Stat(b)
+	require.NoError(t, err)
+
+	require.True(t, os.SameFile(aInfo, bInfo), "%s and %s are not the same file", a, b)
+}
+
+func TestBlobStore(t *testing.T) {
+	t.Run("shares identical binaries", func(t *testing.T) {
+		tool := newCacheLockTestTool(t, nil)
+		first := downloadStoreTestVersion(t, tool, "v1.0.0", "same binary")
+		second := downloadStoreTestVersion(t, tool, "v1.0.1", "same binary")
+
+		requireSameFile(t, first, second)
+		require.Len(t, storeTestBlobs(t), 1)
+
+		versions, err := tool.CachedVersions()
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+
+		checksum, _, err := fileSHA256(tool.getFs(), first)
+		require.NoError(t, err)
+
+		for _, v := range versions {
+			assert.Equal(t, checksum, v.Blob)
+			assert.Equal(t, int64(len("same binary")), v.Size)
+		}
+
+		assert.Equal(t, int64(len("same binary")), DiskUsage(versions))
+	})
+
+	t.Run("stores different binaries separately", func(t *testing.T) {
+		tool := newCacheLockTestTool(t, nil)
+		downloadStoreTestVersion(t, tool, "v1.0.0", "old binary")
+		downloadStoreTestVersion(t, tool, "v2.0.0", "new binary")
+
+		require.Len(t, storeTestBlobs(t), 2)
+
+		versions, err := tool.CachedVersions()
+		require.NoError(t, err)
+		assert.Equal(t, int64(len("old binary")+len("new binary")), DiskUsage(versions))
+	})
+
+	t.Run("keeps the per-version paths executable", func(t *testing.T) {
+		tool := newCacheLockTestTool(t, nil)
+		binPath := downloadStoreTestVersion(t, tool, testVersion, "binary")
+
+		info, err := os.Lstat(binPath)
+		require.NoError(t, err)
+		assert.True(t, info.Mode().IsRegular())
+		assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+		content, err := os.ReadFile(binPath)
+		require.NoError(t, err)
+		assert.Equal(t, "binary", string(content))
+	})
+
+	t.Run("replaces damaged blobs and the versions linked to them", func(t *testing.T) {
+		tool := newCacheLockTestTool(t, nil)
+		first := downloadStoreTestVersion(t, tool, "v1.0.0", "binary")
+
+		// Damaging the blob also damages the first version linked to it.
+		require.NoError(t, os.WriteFile(first, []byte("damaged"), 0o755))
+
+		second := downloadStoreTestVersion(t, tool, "v1.0.1", "binary")
+
+		content, err := os.ReadFile(second)
+		require.NoError(t, err)
+		assert.Equal(t, "binary", string(content))
+
+		dataDir, err := DataDir(tool.getFs())
+		require.NoError(t, err)
+
+		checksum, _, err := fileSHA256(tool.getFs(), second)
+		require.NoError(t, err)
+		requireSameFile(t, second, blobPath(dataDir, checksum))
+		requireSameFile(t, first, second)
+
+		content, err = os.ReadFile(first)
+		require.NoError(t, err)
+		assert.Equal(t, "binary", string(content))
+
+		versions, err := tool.CachedVersions()
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		assert.Equal(t, checksum, versions[0].Blob)
+		assert.Equal(t, checksum, versions[1].Blob)
+		require.Len(t, storeTestBlobs(t), 1)
+	})
+
+	t.Run("prunes blobs of removed versions", func(t *testing.T) {
+		tool := newCacheLockTestTool(t, nil)
+		downloadStoreTestVersion(t, tool, "v1.0.0", "shared binary")
+		downloadStoreTestVersion(t, tool, "v1.0.1", "shared binary")
+		downloadStoreTestVersion(t, tool, "v2.0.0", "other binary")
+		require.Len(t, storeTestBlobs(t), 2)
+
+		require.NoError(t, tool.CleanVersion("v2.0.0"))
+		assert.Len(t, storeTestBlobs(t), 1)
+
+		require.NoError(t, tool.CleanVersion("v1.0.0"))
+		assert.Len(t, storeTestBlobs(t), 1, "blob still linked by v1.0.1")
+
+		require.NoError(t, tool.CleanAll())
+		assert.Empty(t, storeTestBlobs(t))
+	})
+
+	t.Run("is not a tool directory", func(t *testing.T) {
+		tool := newCacheLockTestTool(t, nil)
+		downloadStoreTestVersion(t, tool, testVersion, "binary")
+
+		tools, err := tool.cachedTools()
+		require.NoError(t, err)
+		require.Len(t, tools, 1)
+		assert.Equal(t, "testtool", tools[0].Name)
+	})
+
+	t.Run("keeps plain files on other filesystems", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("XDG_DATA_HOME", testDataDir)
+
+		binPath := cachedTestBinary(testVersion)
+		require.NoError(t, afero.WriteFile(fs, binPath, []byte("binary"), 0o755))
+		require.NoError(t, recordDownload(fs, binPath, VersionMetadata{}))
+
+		exists, err := afero.DirExists(fs, blobDir(testDataDir))
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		versions, err := (&Tool{Name: "testtool", Fs: fs}).CachedVersions()
+		require.NoError(t, err)
+		require.Len(t, versions, 1)
+		assert.Empty(t, versions[0].Blob)
+	})
+}
+
+func TestPlanGCSharedBlobs(t *testing.T) {
+	tool := newCacheLockTestTool(t, nil)
+	downloadStoreTestVersion(t, tool, "v1.0.0", "shared binary")
+	downloadStoreTestVersion(t, tool, "v1.0.1", "shared binary")
+
+	// Both versions together only take the space of one binary.
+	candidates, err := PlanGC([]*Tool{tool}, GCPolicy{MaxSize: int64(len("shared binary"))}, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, candidates)
+
+	candidates, err = PlanGC([]*Tool{tool}, GCPolicy{MaxSize: 1}, time.Now())
+	require.NoError(t, err)
+	assert.Len(t, candidates, 2)
+
+	removed, err := CollectGarbage(candidates)
+	require.NoError(t, err)
+	assert.Len(t, removed, 2)
+	assert.Empty(t, storeTestBlobs(t))
+}
+
+func TestReclaimedSize(t *testing.T) {
+	tool := newCacheLockTestTool(t, nil)
+	other := &Tool{Name: "othertool", Fs: tool.Fs}
+	downloadStoreTestVersion(t, tool, "v1.0.0", "shared binary")
+	downloadStoreTestVersion(t, tool, "v1.0.1", "shared binary")
+	downloadStoreTestVersion(t, tool, "v1.0.2", "plain")
+	downloadStoreTestVersion(t, other, "v2.0.0", "other binary")
+	downloadStoreTestVersion(t, tool, "v1.0.3", "other binary")
+
+	versions, err := tool.CachedVersions()
+	require.NoError(t, err)
+
+	byVersion := make(map[string]CachedVersion)
+	for _, v := range versions {
+		byVersion[v.Version] = v
+	}
+
+	tests := []struct {
+		name     string
+		removed  []string
+		expected int64
+	}{
+		{name: "blob still linked by a kept version", removed: []string{"v1.0.0"}, expected: 0},
+		{name: "all versions linked to a blob", removed: []string{"v1.0.0", "v1.0.1"}, expected: int64(len("shared binary"))},
+		{name: "plain file", removed: []string{"v1.0.2"}, expected: int64(len("plain"))},
+		{name: "blob still linked by another tool", removed: []string{"v1.0.3"}, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removed := make([]CachedVersion, 0, len(tt.removed))
+			for _, version := range tt.removed {
+				removed = append(removed, byVersion[version])
+			}
+
+			size, err := ReclaimedSize(tool.Fs, removed)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, size)
+		})
+	}
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/spf13/afero"
 )
 
@@ -15,65 +19,266 @@ import (
 //
 //nolint:govet // fieldalignment: readability preferred over 8-byte optimization
 type Tool struct {
-	Name           string
-	ProgressWriter io.Writer
-	VersionFunc    func(context.Context) (string, error)
-	DownloadURL    func(version, goos, goarch string) string
-	ChecksumURL    func(version, goos, goarch string) string
-	Fs             afero.Fs // Filesystem abstraction for testing (defaults to OsFs)
-	fsHelper       *FSHelper
+	Name                string
+	ProgressWriter      io.Writer
+	OnProgress          ProgressFunc // Receives download progress instead of drawing a bar to ProgressWriter
+	VersionFunc         func(context.Context) (string, error)
+	ListVersions        func(context.Context, *semver.Constraints) ([]string, error)
+	ChannelVersion      func(context.Context, Channel) (string, error)
+	DownloadURL         func(version, goos, goarch string) string
+	ChecksumURL         func(version, goos, goarch string) string
+	ChecksumFormat      ChecksumFormat                            // Layout of the file at ChecksumURL (defaults to a single checksum)
+	ArchiveFormat       ArchiveFormat                             // Packaging of downloads (defaults to detection from the URL)
+	ArchiveMember       func(version, goos, goarch string) string // Pattern of the binary inside archives (defaults to the tool name)
+	MaxExtractSize      int64                                     // Maximum size of the binary extracted from archives (defaults to 512 MiB)
+	UpstreamURL         string                                    // Common prefix of the upstream download, checksum and version URLs
+	BaseURL             string                                    // Replaces UpstreamURL in all requests, e.g. with an httptest.Server URL
+	GitHubAPIURL        string                                    // Replaces the GitHub API URL in version lookups of GitHub-hosted tools
+	HTTPClient          *http.Client                              // Sends upstream requests, wrapped with retries (defaults to the default transport)
+	Fs                  afero.Fs                                  // Filesystem abstraction for testing (defaults to OsFs)
+	Project             *ProjectConfig                            // Project settings (defaults to the .kdev.yaml found from the working directory)
+	UserConfig          *ToolsConfig                              // User settings (defaults to the tools.yaml at ToolsConfigPath)
+	LockFile            *LockFile                                 // Locked versions and checksums (defaults to the kdev.lock found from the working directory)
+	Offline             bool                                      // Skip upstream lookups and use the newest cached version
+	VerifyBeforeExec    bool                                      // Verify the cached binary before each execution
+	MatchClusterVersion bool                                      // Support the "cluster" version spec, following the API server version
+	RequestedVersion    string                                    // Version spec for this invocation only (defaults to the tool's VersionEnvVar)
+	fsHelper            *FSHelper
 }
 
 // Exec downloads the tool if not cached and executes it with the given arguments.
-// It uses syscall.Exec to replace the current process with the tool.
+// It uses syscall.Exec to replace the current process with the tool. A version
+// spec set in RequestedVersion or the tool's VersionEnvVar overrides project pins
+// for this invocation.
 func (t *Tool) Exec(ctx context.Context, args []string) error {
-	binPath, execArgs, err := t.prepareExec(ctx, args)
+	binPath, execArgs, lock, err := t.prepareExec(ctx, args)
 	if err != nil {
 		return err
 	}
 
-	return syscall.Exec(binPath, execArgs, os.Environ())
+	// The lock stays held until exec closes the lock file, so that no other
+	// kdev process removes the binary in between.
+	err = syscall.Exec(binPath, execArgs, os.Environ())
+	_ = lock.Unlock() //nolint:errcheck // exec error takes precedence
+
+	return err
 }
 
 // prepareExec prepares the binary for execution by ensuring it's downloaded,
-// cached, and executable. Returns the binary path and arguments to execute.
-func (t *Tool) prepareExec(ctx context.Context, args []string) (string, []string, error) {
-	fs := t.getFs()
-	helper := t.getFSHelper()
-
-	dataDir, err := DataDir(fs)
+// cached, executable and, if enabled, verified. Returns the binary path, the
+// arguments to execute and the lock on the cached version, which the caller
+// must release.
+func (t *Tool) prepareExec(ctx context.Context, args []string) (string, []string, *cacheLock, error) {
+	dataDir, err := DataDir(t.getFs())
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to determine data directory: %w", err)
+		return "", nil, nil, fmt.Errorf("failed to determine data directory: %w", err)
 	}
 
-	version, err := t.VersionFunc(ctx)
+	version, err := t.resolveRequestedSpec(ctx, t.requestedVersion())
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to get version: %w", err)
+		return "", nil, nil, fmt.Errorf("failed to get version: %w", err)
 	}
 
 	binPath := filepath.Join(dataDir, "kdev", t.Name, version, t.Name)
 
-	if !helper.Exists(binPath) {
-		if err := t.writeProgress("Downloading %s %s...\n", t.Name, version); err != nil {
-			return "", nil, fmt.Errorf("failed to write progress: %w", err)
+	lock, err := t.lockCachedVersion(ctx, version)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if err := t.ensureExecutable(ctx, binPath, version); err != nil {
+		_ = lock.Unlock() //nolint:errcheck // unlock on error path
+
+		return "", nil, nil, err
+	}
+
+	if err := t.verifyCachedBinary(binPath, version); err != nil {
+		_ = lock.Unlock() //nolint:errcheck // unlock on error path
+
+		return "", nil, nil, err
+	}
+
+	// The last-used time is informational, a read-only cache must not prevent execution.
+	_ = recordUse(t.getFs(), binPath) //nolint:errcheck // best effort
+
+	execArgs := append([]string{t.Name}, args...)
+
+	return binPath, execArgs, lock, nil
+}
+
+// ValidateVersion checks that version can safely be used as the name of a
+// cache directory: it must be a single, non-empty path element.
+func ValidateVersion(version string) error {
+	if version == "" || version == "." || version == ".." ||
+		strings.ContainsAny(version, `/\`) || strings.Contains(version, "..") {
+		return fmt.Errorf("invalid version %q: must not be empty or contain path separators or \"..\"", version)
+	}
+
+	return nil
+}
+
+// VersionEnvVar returns the environment variable selecting the version of the
+// named tool for a single invocation, e.g. KDEV_KUBECTL_VERSION for kubectl.
+func VersionEnvVar(name string) string {
+	return "KDEV_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_VERSION"
+}
+
+// requestedVersion returns the version spec requested for this invocation, or
+// an empty string if none was requested.
+func (t *Tool) requestedVersion() string {
+	if t.RequestedVersion != "" {
+		return t.RequestedVersion
+	}
+
+	return os.Getenv(VersionEnvVar(t.Name))
+}
+
+// ResolveVersion returns the version to use for this tool. A version recorded in
+// the lockfile for the current pin wins. Otherwise a version pinned in the project
+// configuration takes precedence over the upstream VersionFunc; a pinned semver
+// constraint is resolved to the highest matching release. Upstream answers are
+// cached for the configured TTL. In offline mode, or if the upstream lookup fails,
+// the newest matching cached version is used.
+func (t *Tool) ResolveVersion(ctx context.Context) (string, error) {
+	return t.resolveVersion(ctx, true, true)
+}
+
+// RefreshVersion works like ResolveVersion but always queries upstream
+// instead of using a cached answer, and refreshes the version cache.
+func (t *Tool) RefreshVersion(ctx context.Context) (string, error) {
+	return t.resolveVersion(ctx, false, true)
+}
+
+func (t *Tool) resolveVersion(ctx context.Context, useCache, useLock bool) (string, error) {
+	project, err := t.getProject()
+	if err != nil {
+		return "", err
+	}
+
+	spec := project.PinnedVersion(t.Name)
+
+	// A locked "cluster" spec would no longer follow the cluster, so it is resolved anew.
+	if useLock && spec != ClusterVersionSpec {
+		lock, err := t.getLock()
+		if err != nil {
+			return "", err
 		}
 
-		if err := t.download(ctx, binPath, version); err != nil {
-			return "", nil, fmt.Errorf("failed to download: %w", err)
+		channel, err := t.channel()
+		if err != nil {
+			return "", err
 		}
 
-		if err := t.writeProgress("%s %s downloaded successfully\n", t.Name, version); err != nil {
-			return "", nil, fmt.Errorf("failed to write progress: %w", err)
+		if version, ok := lock.LockedVersion(t.Name, spec, channel); ok {
+			if err := ValidateVersion(version); err != nil {
+				return "", fmt.Errorf("invalid %s in %s: %w", t.Name, LockFileName, err)
+			}
+
+			return version, nil
 		}
 	}
 
-	if err := fs.Chmod(binPath, 0o755); err != nil {
-		return "", nil, fmt.Errorf("failed to make executable: %w", err)
+	return t.resolveSpec(ctx, spec, project.LatestVersionTTL(), useCache)
+}
+
+// PinnedSpec returns the version spec pinned for this tool in the project
+// configuration, or an empty string if the tool follows the latest release.
+func (t *Tool) PinnedSpec() (string, error) {
+	project, err := t.getProject()
+	if err != nil {
+		return "", err
 	}
 
-	execArgs := append([]string{t.Name}, args...)
+	return project.PinnedVersion(t.Name), nil
+}
+
+// resolveRequestedSpec resolves an explicitly requested version spec, bypassing
+// project pins and the lockfile. An empty spec falls back to ResolveVersion.
+func (t *Tool) resolveRequestedSpec(ctx context.Context, spec string) (string, error) {
+	if spec == "" {
+		return t.ResolveVersion(ctx)
+	}
+
+	// Exact versions from tool@version or the environment are used as is.
+	if parseConstraint(spec) == nil && spec != ClusterVersionSpec {
+		if err := ValidateVersion(spec); err != nil {
+			return "", err
+		}
+	}
+
+	project, err := t.getProject()
+	if err != nil {
+		return "", err
+	}
+
+	return t.resolveSpec(ctx, spec, project.LatestVersionTTL(), true)
+}
+
+// resolveSpec resolves a version specification. An empty spec refers to the
+// latest upstream release in the tool's channel, an exact version is returned
+// as is, a semver constraint is resolved against the list of upstream releases,
+// and "cluster" matches the API server of the current kubeconfig context.
+func (t *Tool) resolveSpec(ctx context.Context, spec string, ttl time.Duration, useCache bool) (string, error) {
+	version, err := t.resolveSpecVersion(ctx, spec, ttl, useCache)
+	if err != nil {
+		return "", err
+	}
+
+	if err := ValidateVersion(version); err != nil {
+		return "", err
+	}
+
+	return version, nil
+}
+
+// resolveSpecVersion implements resolveSpec without validating the version.
+func (t *Tool) resolveSpecVersion(ctx context.Context, spec string, ttl time.Duration, useCache bool) (string, error) {
+	if spec == ClusterVersionSpec {
+		return t.resolveClusterSpec(ctx, ttl, useCache)
+	}
+
+	constraint := parseConstraint(spec)
+	if spec != "" && constraint == nil {
+		return spec, nil
+	}
+
+	channel, err := t.channel()
+	if err != nil {
+		return "", err
+	}
+
+	if constraint != nil && channel.Prerelease {
+		constraint.IncludePrerelease = true
+	}
+
+	if t.isOffline() {
+		return t.offlineVersion(constraint, channel)
+	}
+
+	query := channel.query(spec)
+
+	if useCache {
+		if version, ok := t.cachedResolvedVersion(query, ttl); ok {
+			return version, nil
+		}
+	}
+
+	var version string
+
+	if constraint == nil {
+		version, err = t.channelVersion(ctx, channel)
+	} else {
+		version, err = t.resolveConstraint(ctx, spec, constraint, channel)
+	}
+
+	if err != nil {
+		return t.fallbackVersion(err, constraint, channel)
+	}
 
-	return binPath, execArgs, nil
+	// Caching is best effort, a read-only data directory must not prevent execution.
+	_ = t.storeResolvedVersion(query, version) //nolint:errcheck // best effort cache
+
+	return version, nil
 }
 
 // getFs returns the filesystem to use, defaulting to OsFs if not set.
@@ -94,6 +299,53 @@ func (t *Tool) getFSHelper() *FSHelper {
 	return t.fsHelper
 }
 
+// getProject returns the project configuration, loading it on first use.
+func (t *Tool) getProject() (*ProjectConfig, error) {
+	if t.Project == nil {
+		project, err := loadProjectConfigFromWd(t.getFs())
+		if err != nil {
+			return nil, err
+		}
+
+		t.Project = project
+	}
+
+	return t.Project, nil
+}
+
+// getUserConfig returns the user configuration, loading it on first use.
+func (t *Tool) getUserConfig() (*ToolsConfig, error) {
+	if t.UserConfig == nil {
+		path, err := ToolsConfigPath()
+		if err != nil {
+			return nil, err
+		}
+
+		user, err := LoadToolsConfig(t.getFs(), path)
+		if err != nil {
+			return nil, err
+		}
+
+		t.UserConfig = user
+	}
+
+	return t.UserConfig, nil
+}
+
+// getLock returns the lockfile, loading it on first use.
+func (t *Tool) getLock() (*LockFile, error) {
+	if t.LockFile == nil {
+		lock, err := loadLockFileFromWd(t.getFs())
+		if err != nil {
+			return nil, err
+		}
+
+		t.LockFile = lock
+	}
+
+	return t.LockFile, nil
+}
+
 // writeProgress writes a progress message if a ProgressWriter is configured.
 func (t *Tool) writeProgress(format string, args ...interface{}) error {
 	if t.ProgressWriter != nil {
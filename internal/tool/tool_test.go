@@ -46,6 +46,150 @@ func TestToolGetFs(t *testing.T) {
 	})
 }
 
+func TestVersionEnvVar(t *testing.T) {
+	assert.Equal(t, "KDEV_KUBECTL_VERSION", VersionEnvVar("kubectl"))
+	assert.Equal(t, "KDEV_MY_TOOL_VERSION", VersionEnvVar("my-tool"))
+}
+
+func TestRequestedVersion(t *testing.T) {
+	t.Run("uses the environment", func(t *testing.T) {
+		t.Setenv(VersionEnvVar("kubectl"), "v1.29.3")
+
+		assert.Equal(t, "v1.29.3", (&Tool{Name: "kubectl"}).requestedVersion())
+	})
+
+	t.Run("prefers the version set on the tool", func(t *testing.T) {
+		t.Setenv(VersionEnvVar("kubectl"), "v1.29.3")
+
+		assert.Equal(t, "v1.30.0", (&Tool{Name: "kubectl", RequestedVersion: "v1.30.0"}).requestedVersion())
+	})
+}
+
+func TestValidateVersion(t *testing.T) {
+	for _, version := range []string{"v1.30.0", "1.30.0-rc.1", "v0.23.0+build.1"} {
+		assert.NoError(t, ValidateVersion(version), version)
+	}
+
+	for _, version := range []string{"", ".", "..", "../../../tmp/x", "v1/../../x", `v1\x`, "v1.30/x"} {
+		assert.Error(t, ValidateVersion(version), version)
+	}
+}
+
+func TestResolveVersionRejectsPathTraversal(t *testing.T) {
+	t.Run("rejects requested versions", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("XDG_DATA_HOME", "/data")
+		t.Setenv(VersionEnvVar("kubectl"), "../../../tmp/x")
+
+		tool := &Tool{Name: "kubectl", Fs: fs, Project: &ProjectConfig{}}
+
+		_, _, _, err := tool.prepareExec(context.Background(), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid version "../../../tmp/x"`)
+
+		exists, err := afero.Exists(fs, "/tmp")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("rejects requested versions set on the tool", func(t *testing.T) {
+		tool := &Tool{Name: "kubectl", Fs: afero.NewMemMapFs(), Project: &ProjectConfig{}, RequestedVersion: "../x"}
+
+		_, _, _, err := tool.prepareExec(context.Background(), nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid version "../x"`)
+	})
+
+	t.Run("rejects pinned versions", func(t *testing.T) {
+		tool := &Tool{
+			Name:     "kubectl",
+			Fs:       afero.NewMemMapFs(),
+			Project:  &ProjectConfig{Versions: map[string]string{"kubectl": "../x"}},
+			LockFile: &LockFile{},
+		}
+
+		_, err := tool.ResolveVersion(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid version")
+	})
+
+	t.Run("rejects cleaning outside the cache", func(t *testing.T) {
+		tool := &Tool{Name: "kubectl", Fs: afero.NewMemMapFs()}
+
+		require.Error(t, tool.CleanVersion("../other"))
+	})
+}
+
+func TestResolveVersion(t *testing.T) {
+	t.Run("prefers project pin over VersionFunc", func(t *testing.T) {
+		tool := &Tool{
+			Name:    "kubectl",
+			Project: &ProjectConfig{Versions: map[string]string{"kubectl": "v1.29.3"}},
+			VersionFunc: func(ctx context.Context) (string, error) {
+				t.Fatal("VersionFunc should not be called for pinned tools")
+
+				return "", nil
+			},
+		}
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.29.3", version)
+	})
+
+	t.Run("falls back to VersionFunc when not pinned", func(t *testing.T) {
+		tool := &Tool{
+			Name:    "kubectl",
+			Project: &ProjectConfig{Versions: map[string]string{"kind": "v0.22.0"}},
+			VersionFunc: func(ctx context.Context) (string, error) {
+				return kubectlTestVersion, nil
+			},
+		}
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, kubectlTestVersion, version)
+	})
+
+	t.Run("loads project config from working directory", func(t *testing.T) {
+		dir := t.TempDir()
+		content := []byte("versions:\n  kubectl: v1.28.0\n")
+		require.NoError(t, afero.WriteFile(afero.NewOsFs(), filepath.Join(dir, ProjectConfigFile), content, 0o644))
+
+		subDir := filepath.Join(dir, "sub")
+		require.NoError(t, afero.NewOsFs().MkdirAll(subDir, 0o755))
+		t.Chdir(subDir)
+
+		tool := &Tool{
+			Name: "kubectl",
+			VersionFunc: func(ctx context.Context) (string, error) {
+				return kubectlTestVersion, nil
+			},
+		}
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.28.0", version)
+	})
+
+	t.Run("returns error for invalid project config", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, afero.WriteFile(afero.NewOsFs(), filepath.Join(dir, ProjectConfigFile), []byte("versions: [\n"), 0o644))
+		t.Chdir(dir)
+
+		tool := &Tool{
+			Name: "kubectl",
+			VersionFunc: func(ctx context.Context) (string, error) {
+				return kubectlTestVersion, nil
+			},
+		}
+
+		_, err := tool.ResolveVersion(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse")
+	})
+}
+
 // TestToolExecPreparation tests everything up to the syscall.Exec call.
 // We cannot test syscall.Exec itself as it replaces the current process.
 func TestToolExecPreparation(t *testing.T) {
@@ -293,8 +437,9 @@ func TestPrepareExec(t *testing.T) { //nolint:maintidx // test function complexi
 			},
 		}
 
-		resultPath, resultArgs, err := tool.prepareExec(context.Background(), []string{"get", "pods"})
+		resultPath, resultArgs, lock, err := tool.prepareExec(context.Background(), []string{"get", "pods"})
 		require.NoError(t, err)
+		require.NoError(t, lock.Unlock())
 		assert.Equal(t, binPath, resultPath)
 		assert.Equal(t, []string{"kubectl", "get", "pods"}, resultArgs)
 
@@ -344,8 +489,9 @@ func TestPrepareExec(t *testing.T) { //nolint:maintidx // test function complexi
 		dataDir := filepath.Join(home, ".kdev")
 		expectedPath := filepath.Join(dataDir, "kdev", "kubectl", "v1.30.0", "kubectl")
 
-		resultPath, resultArgs, err := tool.prepareExec(context.Background(), []string{"version"})
+		resultPath, resultArgs, lock, err := tool.prepareExec(context.Background(), []string{"version"})
 		require.NoError(t, err)
+		require.NoError(t, lock.Unlock())
 		assert.Equal(t, expectedPath, resultPath)
 		assert.Equal(t, []string{"kubectl", "version"}, resultArgs)
 
@@ -360,6 +506,66 @@ func TestPrepareExec(t *testing.T) { //nolint:maintidx // test function complexi
 		assert.Contains(t, progress, "kubectl v1.30.0 downloaded successfully")
 	})
 
+	t.Run("uses pinned version from project config", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		home := testUser
+		t.Setenv("HOME", home)
+
+		dataDir := filepath.Join(home, ".kdev")
+		binPath := filepath.Join(dataDir, "kdev", "kubectl", "v1.29.3", "kubectl")
+
+		err := fs.MkdirAll(filepath.Dir(binPath), 0o755)
+		require.NoError(t, err)
+
+		err = afero.WriteFile(fs, binPath, []byte("pinned binary"), 0o755)
+		require.NoError(t, err)
+
+		tool := &Tool{
+			Name:    "kubectl",
+			Fs:      fs,
+			Project: &ProjectConfig{Versions: map[string]string{"kubectl": "v1.29.3"}},
+			VersionFunc: func(ctx context.Context) (string, error) {
+				return kubectlTestVersion, nil
+			},
+		}
+
+		resultPath, _, lock, err := tool.prepareExec(context.Background(), []string{"version"})
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock())
+		assert.Equal(t, binPath, resultPath)
+	})
+
+	t.Run("version environment variable overrides pin and lock", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		home := testUser
+		t.Setenv("HOME", home)
+		t.Setenv("KDEV_KUBECTL_VERSION", "v1.28.7")
+
+		dataDir := filepath.Join(home, ".kdev")
+		binPath := filepath.Join(dataDir, "kdev", "kubectl", "v1.28.7", "kubectl")
+
+		err := fs.MkdirAll(filepath.Dir(binPath), 0o755)
+		require.NoError(t, err)
+
+		err = afero.WriteFile(fs, binPath, []byte("override binary"), 0o755)
+		require.NoError(t, err)
+
+		tool := &Tool{
+			Name:     "kubectl",
+			Fs:       fs,
+			Project:  &ProjectConfig{Versions: map[string]string{"kubectl": "v1.29.3"}},
+			LockFile: &LockFile{Tools: map[string]LockedTool{"kubectl": {Spec: "v1.29.3", Version: "v1.29.3"}}},
+			VersionFunc: func(ctx context.Context) (string, error) {
+				return kubectlTestVersion, nil
+			},
+		}
+
+		resultPath, _, lock, err := tool.prepareExec(context.Background(), []string{"version"})
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock())
+		assert.Equal(t, binPath, resultPath)
+	})
+
 	t.Run("handles empty arguments", func(t *testing.T) {
 		fs := afero.NewMemMapFs()
 		home := testUser
@@ -383,8 +589,9 @@ func TestPrepareExec(t *testing.T) { //nolint:maintidx // test function complexi
 			},
 		}
 
-		resultPath, resultArgs, err := tool.prepareExec(context.Background(), []string{})
+		resultPath, resultArgs, lock, err := tool.prepareExec(context.Background(), []string{})
 		require.NoError(t, err)
+		require.NoError(t, lock.Unlock())
 		assert.Equal(t, binPath, resultPath)
 		assert.Equal(t, []string{"kind"}, resultArgs)
 	})
@@ -402,8 +609,9 @@ func TestPrepareExec(t *testing.T) { //nolint:maintidx // test function complexi
 			},
 		}
 
-		_, _, err := tool.prepareExec(context.Background(), []string{"version"})
+		_, _, lock, err := tool.prepareExec(context.Background(), []string{"version"})
 		require.Error(t, err)
+		assert.Nil(t, lock)
 		assert.Contains(t, err.Error(), "failed to determine data directory")
 	})
 
@@ -420,8 +628,9 @@ func TestPrepareExec(t *testing.T) { //nolint:maintidx // test function complexi
 			},
 		}
 
-		_, _, err := tool.prepareExec(context.Background(), []string{"version"})
+		_, _, lock, err := tool.prepareExec(context.Background(), []string{"version"})
 		require.Error(t, err)
+		assert.Nil(t, lock)
 		assert.Contains(t, err.Error(), "failed to get version")
 	})
 
@@ -450,8 +659,9 @@ func TestPrepareExec(t *testing.T) { //nolint:maintidx // test function complexi
 			},
 		}
 
-		_, _, err := tool.prepareExec(context.Background(), []string{"version"})
+		_, _, lock, err := tool.prepareExec(context.Background(), []string{"version"})
 		require.Error(t, err)
+		assert.Nil(t, lock)
 		assert.Contains(t, err.Error(), "failed to download")
 	})
 
@@ -481,8 +691,9 @@ func TestPrepareExec(t *testing.T) { //nolint:maintidx // test function complexi
 			},
 		}
 
-		_, _, err = tool.prepareExec(context.Background(), []string{"version"})
+		_, _, lock, err := tool.prepareExec(context.Background(), []string{"version"})
 		require.Error(t, err)
+		assert.Nil(t, lock)
 		assert.Contains(t, err.Error(), "failed to make executable")
 	})
 
@@ -509,8 +720,9 @@ func TestPrepareExec(t *testing.T) { //nolint:maintidx // test function complexi
 			},
 		}
 
-		_, _, err := tool.prepareExec(context.Background(), []string{"version"})
+		_, _, lock, err := tool.prepareExec(context.Background(), []string{"version"})
 		require.Error(t, err)
+		assert.Nil(t, lock)
 		assert.Contains(t, err.Error(), "failed to write progress")
 	})
 
@@ -552,8 +764,9 @@ func TestPrepareExec(t *testing.T) { //nolint:maintidx // test function complexi
 			},
 		}
 
-		_, _, err := tool.prepareExec(context.Background(), []string{"version"})
+		_, _, lock, err := tool.prepareExec(context.Background(), []string{"version"})
 		require.Error(t, err)
+		assert.Nil(t, lock)
 		assert.Contains(t, err.Error(), "failed to write progress")
 	})
 }
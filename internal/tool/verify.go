@@ -0,0 +1,373 @@
+package tool
+
+import (
+	"context"
+	"debug/elf"
+	"debug/macho"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+
+	"github.com/spf13/afero"
+)
+
+// VerifyEnvVar is the environment variable that enables verifying cached
+// binaries before each execution when set to a true value.
+const VerifyEnvVar = "KDEV_VERIFY"
+
+// shebang starts scripts, whose executable header is not checked.
+const shebang = "#!"
+
+// repairSuffix is appended to the path of a cached binary while Repair downloads it again.
+const repairSuffix = ".repair"
+
+var (
+	// errCorruptBinary is returned for cached binaries that fail verification.
+	errCorruptBinary = errors.New("corrupt binary")
+
+	// errUpstreamOffline is reported instead of an upstream checksum in offline mode.
+	errUpstreamOffline = errors.New("not fetched in offline mode")
+)
+
+// elfMachines maps GOARCH values to the ELF machine of their executables.
+var elfMachines = map[string]elf.Machine{
+	"386":     elf.EM_386,
+	"amd64":   elf.EM_X86_64,
+	"arm":     elf.EM_ARM,
+	"arm64":   elf.EM_AARCH64,
+	"loong64": elf.EM_LOONGARCH,
+	"ppc64le": elf.EM_PPC64,
+	"riscv64": elf.EM_RISCV,
+	"s390x":   elf.EM_S390,
+}
+
+// machoCPUs maps GOARCH values to the Mach-O CPU type of their executables.
+var machoCPUs = map[string]macho.Cpu{
+	"amd64": macho.CpuAmd64,
+	"arm64": macho.CpuArm64,
+}
+
+// VerifyResult is the outcome of verifying a cached version.
+type VerifyResult struct {
+	Version string
+	Path    string
+	// Checksummed is set if the binary was compared against a checksum.
+	// Versions cached before metadata was recorded only get their header checked.
+	Checksummed bool
+	// Trusted names where the compared checksum came from if not from the cache
+	// itself (LockFileName or "upstream"). Only such checksums detect tampering:
+	// the checksum recorded in the cache was computed from the cached binary.
+	Trusted string
+	// UpstreamErr describes why the upstream checksum could not be fetched. The
+	// binary is then compared with the recorded checksum instead.
+	UpstreamErr error
+	// Err describes why the binary failed verification, nil if it is intact.
+	Err error
+}
+
+// OK reports whether the cached binary passed verification.
+func (r VerifyResult) OK() bool {
+	return r.Err == nil
+}
+
+// Verify checks all cached versions of the tool: their checksum must match the
+// one in kdev.lock or, if upstream is set, the upstream checksum, and otherwise
+// the checksum recorded at download time. Their executable header must match
+// the host OS and architecture. An upstream checksum that cannot be fetched is
+// reported in VerifyResult.UpstreamErr, not as a failed verification.
+func (t *Tool) Verify(ctx context.Context, upstream bool) ([]VerifyResult, error) {
+	versions, err := t.CachedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]VerifyResult, 0, len(versions))
+
+	for _, v := range versions {
+		result := VerifyResult{Version: v.Version, Path: v.Path}
+
+		checksum, err := t.lockedChecksum(v.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case checksum != "":
+			result.Trusted = LockFileName
+		case upstream:
+			checksum, result.UpstreamErr = t.upstreamBinaryChecksum(ctx, v.Version)
+			if checksum != "" {
+				result.Trusted = "upstream"
+			}
+		}
+
+		result.Checksummed, result.Err = verifyBinary(t.getFs(), v.Path, checksum)
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// lockedChecksum returns the checksum of the binary of version for the host
+// in kdev.lock, or an empty string if there is none. Checksums of archives do
+// not apply to the extracted binary, so none is returned for tools distributed
+// as archives.
+func (t *Tool) lockedChecksum(version string) (string, error) {
+	lock, err := t.getLock()
+	if err != nil {
+		return "", err
+	}
+
+	artifact, ok := lock.Artifact(t.Name, version, runtime.GOOS, runtime.GOARCH)
+	if !ok || t.archiveFormat(artifact.URL) != ArchivePlain {
+		return "", nil
+	}
+
+	return artifact.Checksum(), nil
+}
+
+// upstreamBinaryChecksum fetches the upstream checksum of the binary of
+// version for the host. Like lockedChecksum, it returns an empty string for
+// tools distributed as archives, and also for tools without checksums.
+func (t *Tool) upstreamBinaryChecksum(ctx context.Context, version string) (string, error) {
+	if t.DownloadURL == nil || t.ChecksumURL == nil ||
+		t.archiveFormat(t.DownloadURL(version, runtime.GOOS, runtime.GOARCH)) != ArchivePlain {
+		return "", nil
+	}
+
+	if t.isOffline() {
+		return "", errUpstreamOffline
+	}
+
+	ctx, err := t.upstreamContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	checksum, err := t.upstreamChecksum(ctx, version, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksum: %w", err)
+	}
+
+	return checksum, nil
+}
+
+// Repair downloads a cached version, e.g. one that failed verification, again.
+// The cached binary is only replaced once the download succeeded.
+func (t *Tool) Repair(ctx context.Context, version string) error {
+	fs := t.getFs()
+
+	dataDir, err := DataDir(fs)
+	if err != nil {
+		return fmt.Errorf("failed to determine data directory: %w", err)
+	}
+
+	binPath := filepath.Join(dataDir, "kdev", t.Name, version, t.Name)
+
+	lock, err := t.lockCachedVersion(ctx, version)
+	if err != nil {
+		return err
+	}
+
+	defer lock.Unlock() //nolint:errcheck // nothing to recover after the download
+
+	if err := t.writeProgress("Downloading %s %s...\n", t.Name, version); err != nil {
+		return fmt.Errorf("failed to write progress: %w", err)
+	}
+
+	repairPath := binPath + repairSuffix
+
+	sourceURL, err := t.fetchArtifact(ctx, repairPath, version, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+
+	if err := fs.Chmod(repairPath, 0o755); err != nil {
+		return fmt.Errorf("failed to make executable: %w", err)
+	}
+
+	// Replacing the path rather than writing to it leaves other versions linked
+	// to the same damaged blob alone; recording the download relinks them.
+	if err := fs.Rename(repairPath, binPath); err != nil {
+		_ = fs.Remove(repairPath) //nolint:errcheck // cleanup on error path
+
+		return fmt.Errorf("failed to replace binary: %w", err)
+	}
+
+	if err := recordDownload(fs, binPath, VersionMetadata{SourceURL: sourceURL}); err != nil {
+		return err
+	}
+
+	if err := t.writeProgress("%s %s downloaded successfully\n", t.Name, version); err != nil {
+		return fmt.Errorf("failed to write progress: %w", err)
+	}
+
+	return nil
+}
+
+// verifyFromEnv reports whether verification before execution is enabled
+// through the environment.
+func verifyFromEnv() bool {
+	verify, err := strconv.ParseBool(os.Getenv(VerifyEnvVar))
+
+	return err == nil && verify
+}
+
+// verifyBeforeExec reports whether cached binaries are verified before they
+// are executed.
+func (t *Tool) verifyBeforeExec() (bool, error) {
+	if t.VerifyBeforeExec || verifyFromEnv() {
+		return true, nil
+	}
+
+	project, err := t.getProject()
+	if err != nil {
+		return false, err
+	}
+
+	return project.Verify, nil
+}
+
+// verifyCachedBinary verifies the cached binary of version at binPath if
+// verification before execution is enabled.
+func (t *Tool) verifyCachedBinary(binPath, version string) error {
+	verify, err := t.verifyBeforeExec()
+	if err != nil || !verify {
+		return err
+	}
+
+	checksum, err := t.lockedChecksum(version)
+	if err != nil {
+		return err
+	}
+
+	if _, err := verifyBinary(t.getFs(), binPath, checksum); err != nil {
+		return fmt.Errorf("cached %s %s failed verification (run 'kdev tools verify --repair %s' to download it again): %w", t.Name, version, t.Name, err)
+	}
+
+	return nil
+}
+
+// verifyBinary checks the binary at binPath against the trusted checksum if
+// set, and otherwise against the checksum recorded in its metadata, if any. It
+// also checks that it is an executable for the host. It reports whether a
+// checksum was compared.
+func verifyBinary(fs afero.Fs, binPath, trusted string) (bool, error) {
+	metadata := readMetadata(fs, binPath)
+
+	switch {
+	case trusted != "":
+		checksum, _, err := fileChecksum(fs, binPath, newChecksumHash(trusted))
+		if err != nil {
+			return true, err
+		}
+
+		if checksum != trusted {
+			return true, fmt.Errorf("%w: checksum %s does not match the expected %s", errCorruptBinary, checksum, trusted)
+		}
+	case metadata != nil && metadata.SHA256 != "":
+		checksum, _, err := fileSHA256(fs, binPath)
+		if err != nil {
+			return true, err
+		}
+
+		if checksum != metadata.SHA256 {
+			return true, fmt.Errorf("%w: sha256 %s does not match the recorded %s", errCorruptBinary, checksum, metadata.SHA256)
+		}
+	default:
+		return false, checkBinaryExecutable(fs, binPath)
+	}
+
+	return true, checkBinaryExecutable(fs, binPath)
+}
+
+// checkBinaryExecutable checks that the file at binPath is an executable for the host.
+func checkBinaryExecutable(fs afero.Fs, binPath string) error {
+	file, err := fs.Open(binPath)
+	if err != nil {
+		return fmt.Errorf("failed to open binary: %w", err)
+	}
+
+	defer file.Close() //nolint:errcheck // read-only file
+
+	return checkExecutable(file, runtime.GOOS, runtime.GOARCH)
+}
+
+// checkExecutable checks that r holds an executable for goos/goarch: a Mach-O
+// file on darwin and an ELF file elsewhere. Architectures without a known
+// machine type are not checked, and neither are scripts, which user-defined
+// tools may be.
+func checkExecutable(r io.ReaderAt, goos, goarch string) error {
+	if isScript(r) {
+		return nil
+	}
+
+	if goos == "darwin" {
+		return checkMachO(r, goarch)
+	}
+
+	return checkELF(r, goarch)
+}
+
+// isScript reports whether r starts with a shebang line.
+func isScript(r io.ReaderAt) bool {
+	magic := make([]byte, len(shebang))
+	if _, err := r.ReadAt(magic, 0); err != nil {
+		return false
+	}
+
+	return string(magic) == shebang
+}
+
+// checkELF checks that r is an ELF executable for goarch.
+func checkELF(r io.ReaderAt, goarch string) error {
+	file, err := elf.NewFile(r)
+	if err != nil {
+		return fmt.Errorf("%w: not an ELF executable: %w", errCorruptBinary, err)
+	}
+
+	if file.Type != elf.ET_EXEC && file.Type != elf.ET_DYN {
+		return fmt.Errorf("%w: ELF file of type %s is not executable", errCorruptBinary, file.Type)
+	}
+
+	if want, ok := elfMachines[goarch]; ok && file.Machine != want {
+		return fmt.Errorf("%w: built for %s, expected %s for %s", errCorruptBinary, file.Machine, want, goarch)
+	}
+
+	return nil
+}
+
+// checkMachO checks that r is a Mach-O executable, or a universal binary
+// containing one, for goarch.
+func checkMachO(r io.ReaderAt, goarch string) error {
+	want, known := machoCPUs[goarch]
+
+	if fat, err := macho.NewFatFile(r); err == nil {
+		for _, arch := range fat.Arches {
+			if !known || arch.Cpu == want {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%w: universal binary has no %s executable", errCorruptBinary, goarch)
+	}
+
+	file, err := macho.NewFile(r)
+	if err != nil {
+		return fmt.Errorf("%w: not a Mach-O executable: %w", errCorruptBinary, err)
+	}
+
+	if file.Type != macho.TypeExec {
+		return fmt.Errorf("%w: Mach-O file of type %s is not executable", errCorruptBinary, file.Type)
+	}
+
+	if known && file.Cpu != want {
+		return fmt.Errorf("%w: built for %s, expected %s for %s", errCorruptBinary, file.Cpu, want, goarch)
+	}
+
+	return nil
+}
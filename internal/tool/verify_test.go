@@ -0,0 +1,450 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"debug/elf"
+	"debug/macho"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testELF returns a minimal 64-bit little-endian ELF header of the given type and machine.
+func testELF(typ elf.Type, machine elf.Machine) []byte {
+	header := elf.Header64{
+		Type:    uint16(typ),
+		Machine: uint16(machine),
+		Version: uint32(elf.EV_CURRENT),
+		Ehsize:  uint16(binary.Size(elf.Header64{})),
+	}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	header.Ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	header.Ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, header) //nolint:errcheck // writes to a buffer cannot fail
+
+	return buf.Bytes()
+}
+
+// testMachO returns a minimal 64-bit Mach-O header of the given type and CPU.
+func testMachO(typ macho.Type, cpu macho.Cpu) []byte {
+	header := macho.FileHeader{Magic: macho.Magic64, Cpu: cpu, Type: typ}
+
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, header) //nolint:errcheck // writes to a buffer cannot fail
+	buf.Write(make([]byte, 4))                          // reserved field of 64-bit headers
+
+	return buf.Bytes()
+}
+
+// hostTestBinary returns a minimal executable header for the host platform.
+func hostTestBinary() []byte {
+	if runtime.GOOS == "darwin" {
+		return testMachO(macho.TypeExec, machoCPUs[runtime.GOARCH])
+	}
+
+	return testELF(elf.ET_EXEC, elfMachines[runtime.GOARCH])
+}
+
+func TestCheckExecutable(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		goos    string
+		goarch  string
+		wantErr string
+	}{
+		{name: "ELF executable", content: testELF(elf.ET_EXEC, elf.EM_X86_64), goos: "linux", goarch: "amd64"},
+		{name: "position independent ELF executable", content: testELF(elf.ET_DYN, elf.EM_AARCH64), goos: "linux", goarch: "arm64"},
+		{name: "ELF for another architecture", content: testELF(elf.ET_EXEC, elf.EM_AARCH64), goos: "linux", goarch: "amd64", wantErr: "built for EM_AARCH64, expected EM_X86_64 for amd64"},
+		{name: "ELF object file", content: testELF(elf.ET_REL, elf.EM_X86_64), goos: "linux", goarch: "amd64", wantErr: "ELF file of type ET_REL is not executable"},
+		{name: "ELF for unknown architecture", content: testELF(elf.ET_EXEC, elf.EM_MIPS), goos: "linux", goarch: "mips"},
+		{name: "truncated file", content: []byte("\x7fELF"), goos: "linux", goarch: "amd64", wantErr: "not an ELF executable"},
+		{name: "Mach-O on linux", content: testMachO(macho.TypeExec, macho.CpuAmd64), goos: "linux", goarch: "amd64", wantErr: "not an ELF executable"},
+		{name: "Mach-O executable", content: testMachO(macho.TypeExec, macho.CpuArm64), goos: "darwin", goarch: "arm64"},
+		{name: "Mach-O for another architecture", content: testMachO(macho.TypeExec, macho.CpuAmd64), goos: "darwin", goarch: "arm64", wantErr: "built for CpuAmd64, expected CpuArm64 for arm64"},
+		{name: "Mach-O library", content: testMachO(macho.TypeDylib, macho.CpuArm64), goos: "darwin", goarch: "arm64", wantErr: "is not executable"},
+		{name: "ELF on darwin", content: testELF(elf.ET_EXEC, elf.EM_AARCH64), goos: "darwin", goarch: "arm64", wantErr: "not a Mach-O executable"},
+		{name: "script", content: []byte("#!/bin/sh\necho hello\n"), goos: "linux", goarch: "amd64"},
+		{name: "script on darwin", content: []byte("#!/usr/bin/env bash\n"), goos: "darwin", goarch: "arm64"},
+		{name: "empty file", content: nil, goos: "linux", goarch: "amd64", wantErr: "not an ELF executable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkExecutable(bytes.NewReader(tt.content), tt.goos, tt.goarch)
+			if tt.wantErr != "" {
+				require.ErrorIs(t, err, errCorruptBinary)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+
+	content := hostTestBinary()
+	fs := afero.NewMemMapFs()
+
+	for _, version := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		require.NoError(t, afero.WriteFile(fs, cachedTestBinary(version), content, 0o755))
+	}
+
+	require.NoError(t, recordDownload(fs, cachedTestBinary("v1.0.0"), VersionMetadata{}))
+	require.NoError(t, recordDownload(fs, cachedTestBinary("v1.1.0"), VersionMetadata{}))
+	require.NoError(t, afero.WriteFile(fs, cachedTestBinary("v1.1.0"), append(content, "tampered"...), 0o755))
+
+	tool := &Tool{Name: "testtool", Fs: fs, LockFile: &LockFile{}}
+
+	results, err := tool.Verify(context.Background(), false)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	byVersion := make(map[string]VerifyResult)
+	for _, result := range results {
+		byVersion[result.Version] = result
+	}
+
+	t.Run("accepts intact binaries", func(t *testing.T) {
+		result := byVersion["v1.0.0"]
+		assert.True(t, result.OK())
+		assert.True(t, result.Checksummed)
+		assert.Equal(t, cachedTestBinary("v1.0.0"), result.Path)
+	})
+
+	t.Run("detects modified binaries", func(t *testing.T) {
+		result := byVersion["v1.1.0"]
+		assert.False(t, result.OK())
+		require.ErrorIs(t, result.Err, errCorruptBinary)
+		assert.Contains(t, result.Err.Error(), "does not match the recorded")
+	})
+
+	t.Run("checks the header of binaries without metadata", func(t *testing.T) {
+		result := byVersion["v1.2.0"]
+		assert.True(t, result.OK())
+		assert.False(t, result.Checksummed)
+
+		require.NoError(t, afero.WriteFile(fs, cachedTestBinary("v1.2.0"), []byte("not an executable"), 0o755))
+
+		checksummed, err := verifyBinary(fs, cachedTestBinary("v1.2.0"), "")
+		assert.False(t, checksummed)
+		require.ErrorIs(t, err, errCorruptBinary)
+	})
+}
+
+// testLockFile returns a lockfile with the checksum of content for testtool testVersion on the host.
+func testLockFile(content []byte) *LockFile {
+	lock := &LockFile{}
+	lock.Set("testtool", LockedTool{
+		Version: testVersion,
+		Platforms: map[string]LockedArtifact{
+			Platform(runtime.GOOS, runtime.GOARCH): {URL: "https://example.com/testtool", SHA256: fmt.Sprintf("%x", sha256.Sum256(content))},
+		},
+	})
+
+	return lock
+}
+
+func TestVerifyTrustedChecksum(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+
+	content := hostTestBinary()
+	tampered := append(hostTestBinary(), "tampered"...)
+
+	// newTool caches a binary whose recorded checksum was computed from it, as
+	// if it had been replaced together with its metadata.
+	newTool := func(cached []byte) *Tool {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, cachedTestBinary(testVersion), cached, 0o755))
+		require.NoError(t, recordDownload(fs, cachedTestBinary(testVersion), VersionMetadata{}))
+
+		return &Tool{Name: "testtool", Fs: fs, LockFile: &LockFile{}}
+	}
+
+	t.Run("detects tampering with the kdev.lock checksum", func(t *testing.T) {
+		tool := newTool(tampered)
+		tool.LockFile = testLockFile(content)
+
+		results, err := tool.Verify(context.Background(), false)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, LockFileName, results[0].Trusted)
+		require.ErrorIs(t, results[0].Err, errCorruptBinary)
+		assert.Contains(t, results[0].Err.Error(), "does not match the expected")
+	})
+
+	t.Run("accepts binaries matching the kdev.lock checksum", func(t *testing.T) {
+		tool := newTool(content)
+		tool.LockFile = testLockFile(content)
+
+		results, err := tool.Verify(context.Background(), false)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.True(t, results[0].OK())
+		assert.True(t, results[0].Checksummed)
+		assert.Equal(t, LockFileName, results[0].Trusted)
+	})
+
+	t.Run("detects tampering with the upstream checksum", func(t *testing.T) {
+		requested := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested = true
+			_, _ = fmt.Fprintf(w, "%x", sha256.Sum256(content)) //nolint:errcheck // test helper
+		}))
+		defer server.Close()
+
+		tool := newTool(tampered)
+		tool.DownloadURL = func(version, goos, goarch string) string {
+			return server.URL + "/testtool"
+		}
+		tool.ChecksumURL = func(version, goos, goarch string) string {
+			return server.URL + "/testtool.sha256"
+		}
+
+		results, err := tool.Verify(context.Background(), false)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.True(t, results[0].OK(), "the recorded checksum only detects corruption")
+		assert.False(t, requested)
+
+		results, err = tool.Verify(context.Background(), true)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "upstream", results[0].Trusted)
+		require.ErrorIs(t, results[0].Err, errCorruptBinary)
+	})
+
+	t.Run("falls back to the recorded checksum if the upstream one is unavailable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		tool := newTool(content)
+		tool.DownloadURL = func(version, goos, goarch string) string {
+			return server.URL + "/testtool"
+		}
+		tool.ChecksumURL = func(version, goos, goarch string) string {
+			return server.URL + "/testtool.sha256"
+		}
+
+		results, err := tool.Verify(context.Background(), true)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.True(t, results[0].OK())
+		assert.True(t, results[0].Checksummed)
+		assert.Empty(t, results[0].Trusted)
+		require.Error(t, results[0].UpstreamErr)
+		assert.Contains(t, results[0].UpstreamErr.Error(), "failed to fetch checksum")
+
+		tool.Offline = true
+
+		results, err = tool.Verify(context.Background(), true)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.True(t, results[0].OK())
+		require.ErrorIs(t, results[0].UpstreamErr, errUpstreamOffline)
+	})
+}
+
+func TestPrepareExecVerifies(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+	t.Setenv(VerifyEnvVar, "")
+
+	newTool := func(content []byte, project *ProjectConfig) *Tool {
+		fs := afero.NewMemMapFs()
+		binPath := cachedTestBinary(testVersion)
+		require.NoError(t, afero.WriteFile(fs, binPath, hostTestBinary(), 0o755))
+		require.NoError(t, recordDownload(fs, binPath, VersionMetadata{}))
+		require.NoError(t, afero.WriteFile(fs, binPath, content, 0o755))
+
+		project.Versions = map[string]string{"testtool": testVersion}
+
+		return &Tool{Name: "testtool", Fs: fs, Project: project, LockFile: &LockFile{}}
+	}
+
+	t.Run("executes modified binaries unless enabled", func(t *testing.T) {
+		_, _, lock, err := newTool([]byte("tampered"), &ProjectConfig{}).prepareExec(context.Background(), nil)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock())
+	})
+
+	t.Run("rejects modified binaries", func(t *testing.T) {
+		tool := newTool([]byte("tampered"), &ProjectConfig{})
+		tool.VerifyBeforeExec = true
+
+		_, _, _, err := tool.prepareExec(context.Background(), nil)
+		require.ErrorIs(t, err, errCorruptBinary)
+		assert.Contains(t, err.Error(), "cached testtool v1.0.0 failed verification")
+		assert.Contains(t, err.Error(), "kdev tools verify --repair testtool")
+	})
+
+	t.Run("rejects binaries not matching kdev.lock", func(t *testing.T) {
+		tampered := append(hostTestBinary(), "tampered"...)
+
+		tool := newTool(tampered, &ProjectConfig{})
+		require.NoError(t, recordDownload(tool.Fs, cachedTestBinary(testVersion), VersionMetadata{}))
+		tool.LockFile = testLockFile(hostTestBinary())
+		tool.VerifyBeforeExec = true
+
+		_, _, _, err := tool.prepareExec(context.Background(), nil)
+		require.ErrorIs(t, err, errCorruptBinary)
+		assert.Contains(t, err.Error(), "does not match the expected")
+	})
+
+	t.Run("enabled through the project configuration", func(t *testing.T) {
+		_, _, _, err := newTool([]byte("tampered"), &ProjectConfig{Verify: true}).prepareExec(context.Background(), nil)
+		require.ErrorIs(t, err, errCorruptBinary)
+	})
+
+	t.Run("enabled through the environment", func(t *testing.T) {
+		t.Setenv(VerifyEnvVar, "true")
+
+		_, _, _, err := newTool([]byte("tampered"), &ProjectConfig{}).prepareExec(context.Background(), nil)
+		require.ErrorIs(t, err, errCorruptBinary)
+
+		_, _, lock, err := newTool(hostTestBinary(), &ProjectConfig{}).prepareExec(context.Background(), nil)
+		require.NoError(t, err)
+		require.NoError(t, lock.Unlock())
+	})
+}
+
+func TestVerifyUserDefinedScript(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+
+	script := []byte("#!/bin/sh\necho hello\n")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			_, _ = fmt.Fprintf(w, "%x", sha256.Sum256(script)) //nolint:errcheck // test helper
+
+			return
+		}
+
+		_, _ = w.Write(script) //nolint:errcheck // test helper
+	}))
+	defer server.Close()
+
+	cfg, err := ToolDefinition{
+		Name:       "myscript",
+		VersionURL: server.URL + "/stable.txt",
+		Download:   server.URL + "/{{.Version}}/myscript",
+		Checksum:   server.URL + "/{{.Version}}/myscript.sha256",
+	}.toolConfig()
+	require.NoError(t, err)
+
+	tool := NewToolFromConfig(cfg, nil)
+	tool.Fs = afero.NewMemMapFs()
+	tool.Project = &ProjectConfig{Versions: map[string]string{"myscript": testVersion}}
+	tool.LockFile = &LockFile{}
+	tool.VerifyBeforeExec = true
+
+	_, err = tool.Install(context.Background(), testVersion)
+	require.NoError(t, err)
+
+	results, err := tool.Verify(context.Background(), false)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err)
+	assert.True(t, results[0].Checksummed)
+
+	_, _, lock, err := tool.prepareExec(context.Background(), nil)
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+}
+
+func TestRepair(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+
+	content := hostTestBinary()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/testtool.sha256" {
+			_, _ = fmt.Fprintf(w, "%x", sha256.Sum256(content)) //nolint:errcheck // test helper
+
+			return
+		}
+
+		_, _ = w.Write(content) //nolint:errcheck // test helper
+	}))
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	binPath := cachedTestBinary(testVersion)
+	require.NoError(t, afero.WriteFile(fs, binPath, []byte("tampered"), 0o755))
+
+	tool := &Tool{
+		Name: "testtool",
+		Fs:   fs,
+		DownloadURL: func(version, goos, goarch string) string {
+			return server.URL + "/testtool"
+		},
+		ChecksumURL: func(version, goos, goarch string) string {
+			return server.URL + "/testtool.sha256"
+		},
+		Project:  &ProjectConfig{},
+		LockFile: &LockFile{},
+	}
+
+	require.NoError(t, tool.Repair(context.Background(), testVersion))
+
+	data, err := afero.ReadFile(fs, binPath)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	exists, err := afero.Exists(fs, binPath+repairSuffix)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	results, err := tool.Verify(context.Background(), false)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].OK())
+	assert.True(t, results[0].Checksummed)
+}
+
+func TestRepairKeepsBinaryOnFailedDownload(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", testDataDir)
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	binPath := cachedTestBinary(testVersion)
+	require.NoError(t, afero.WriteFile(fs, binPath, hostTestBinary(), 0o755))
+
+	tool := &Tool{
+		Name: "testtool",
+		Fs:   fs,
+		DownloadURL: func(version, goos, goarch string) string {
+			return server.URL + "/testtool"
+		},
+		ChecksumURL: func(version, goos, goarch string) string {
+			return server.URL + "/testtool.sha256"
+		},
+		Project:  &ProjectConfig{},
+		LockFile: &LockFile{},
+	}
+
+	require.Error(t, tool.Repair(context.Background(), testVersion))
+
+	data, err := afero.ReadFile(fs, binPath)
+	require.NoError(t, err)
+	assert.Equal(t, hostTestBinary(), data)
+}
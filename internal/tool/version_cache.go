@@ -0,0 +1,137 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// resolvedVersionsFile is the name of the file storing upstream version lookups.
+	resolvedVersionsFile = "versions.json"
+
+	// latestQuery is the cache key for lookups of the latest upstream version.
+	latestQuery = "latest"
+)
+
+// resolvedVersionRecord is the persisted result of an upstream version lookup.
+type resolvedVersionRecord struct {
+	Version   string    `json:"version"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// resolvedVersionsPath returns the path of the version cache file for this tool.
+func (t *Tool) resolvedVersionsPath() (string, error) {
+	dataDir, err := DataDir(t.getFs())
+	if err != nil {
+		return "", fmt.Errorf("failed to determine data directory: %w", err)
+	}
+
+	return filepath.Join(dataDir, "kdev", t.Name, resolvedVersionsFile), nil
+}
+
+// readResolvedVersions loads all cached lookups keyed by query.
+// A missing or unreadable cache yields an empty map.
+func (t *Tool) readResolvedVersions() map[string]resolvedVersionRecord {
+	records := map[string]resolvedVersionRecord{}
+
+	path, err := t.resolvedVersionsPath()
+	if err != nil {
+		return records
+	}
+
+	data, err := afero.ReadFile(t.getFs(), path)
+	if err != nil {
+		return records
+	}
+
+	if err := json.Unmarshal(data, &records); err != nil {
+		return map[string]resolvedVersionRecord{}
+	}
+
+	return records
+}
+
+// cachedResolvedVersion returns the cached answer for query if it is younger than ttl.
+// An empty query refers to the latest upstream version.
+func (t *Tool) cachedResolvedVersion(query string, ttl time.Duration) (string, bool) {
+	if ttl <= 0 {
+		return "", false
+	}
+
+	record, ok := t.readResolvedVersions()[cacheKey(query)]
+	if !ok || record.Version == "" {
+		return "", false
+	}
+
+	if time.Since(record.CheckedAt) > ttl {
+		return "", false
+	}
+
+	return record.Version, true
+}
+
+// storeResolvedVersion persists the result of an upstream lookup for query.
+// The cache is replaced through a temporary file, so that concurrent lookups
+// never read a partial cache.
+func (t *Tool) storeResolvedVersion(query, version string) error {
+	fs := t.getFs()
+
+	path, err := t.resolvedVersionsPath()
+	if err != nil {
+		return err
+	}
+
+	records := t.readResolvedVersions()
+	records[cacheKey(query)] = resolvedVersionRecord{
+		Version:   version,
+		CheckedAt: time.Now().UTC(),
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode version cache: %w", err)
+	}
+
+	if err := fs.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp, err := afero.TempFile(fs, filepath.Dir(path), resolvedVersionsFile+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create version cache: %w", err)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()           //nolint:errcheck // close on error path
+		_ = fs.Remove(tmp.Name()) //nolint:errcheck // cleanup on error path
+
+		return fmt.Errorf("failed to write version cache: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = fs.Remove(tmp.Name()) //nolint:errcheck // cleanup on error path
+
+		return fmt.Errorf("failed to write version cache: %w", err)
+	}
+
+	if err := fs.Rename(tmp.Name(), path); err != nil {
+		_ = fs.Remove(tmp.Name()) //nolint:errcheck // cleanup on error path
+
+		return fmt.Errorf("failed to write version cache: %w", err)
+	}
+
+	return nil
+}
+
+// cacheKey maps a version query to its key in the version cache.
+func cacheKey(query string) string {
+	if query == "" {
+		return latestQuery
+	}
+
+	return query
+}
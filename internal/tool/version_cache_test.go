@@ -0,0 +1,208 @@
+//nolint:testpackage // internal functions require same package
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeResolvedVersionRecord stores a version cache record for query checked at the given time.
+func writeResolvedVersionRecord(t *testing.T, fs afero.Fs, toolName, query, version string, checkedAt time.Time) {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]resolvedVersionRecord{
+		cacheKey(query): {Version: version, CheckedAt: checkedAt},
+	})
+	require.NoError(t, err)
+
+	path := filepath.Join(testUser, ".kdev", "kdev", toolName, resolvedVersionsFile)
+	require.NoError(t, afero.WriteFile(fs, path, data, 0o644))
+}
+
+func TestResolvedVersionCache(t *testing.T) {
+	t.Run("stores and reads back version", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+
+		tool := &Tool{Name: "kubectl", Fs: fs}
+
+		require.NoError(t, tool.storeResolvedVersion("", "v1.30.0"))
+
+		version, ok := tool.cachedResolvedVersion("", time.Hour)
+		assert.True(t, ok)
+		assert.Equal(t, "v1.30.0", version)
+
+		entries, err := afero.ReadDir(fs, filepath.Join(testUser, ".kdev", "kdev", "kubectl"))
+		require.NoError(t, err)
+		require.Len(t, entries, 1, "no temporary file is left behind")
+		assert.Equal(t, resolvedVersionsFile, entries[0].Name())
+	})
+
+	t.Run("ignores expired record", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+
+		writeResolvedVersionRecord(t, fs, "kubectl", "", "v1.30.0", time.Now().Add(-2*time.Hour))
+
+		tool := &Tool{Name: "kubectl", Fs: fs}
+
+		_, ok := tool.cachedResolvedVersion("", time.Hour)
+		assert.False(t, ok)
+	})
+
+	t.Run("zero TTL disables cache", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+
+		writeResolvedVersionRecord(t, fs, "kubectl", "", "v1.30.0", time.Now())
+
+		tool := &Tool{Name: "kubectl", Fs: fs}
+
+		_, ok := tool.cachedResolvedVersion("", 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("ignores corrupted record", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+
+		path := filepath.Join(testUser, ".kdev", "kdev", "kubectl", resolvedVersionsFile)
+		require.NoError(t, afero.WriteFile(fs, path, []byte("{not json"), 0o644))
+
+		tool := &Tool{Name: "kubectl", Fs: fs}
+
+		_, ok := tool.cachedResolvedVersion("", time.Hour)
+		assert.False(t, ok)
+	})
+
+	t.Run("keeps separate entries per query", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+
+		tool := &Tool{Name: "kubectl", Fs: fs}
+
+		require.NoError(t, tool.storeResolvedVersion("", "v1.31.0"))
+		require.NoError(t, tool.storeResolvedVersion("~1.30", "v1.30.5"))
+
+		latest, ok := tool.cachedResolvedVersion("", time.Hour)
+		assert.True(t, ok)
+		assert.Equal(t, "v1.31.0", latest)
+
+		constrained, ok := tool.cachedResolvedVersion("~1.30", time.Hour)
+		assert.True(t, ok)
+		assert.Equal(t, "v1.30.5", constrained)
+	})
+
+	t.Run("is not listed as cached version", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+
+		tool := &Tool{Name: "kubectl", Fs: fs}
+		require.NoError(t, tool.storeResolvedVersion("", "v1.30.0"))
+
+		versions, err := tool.CachedVersions()
+		require.NoError(t, err)
+		assert.Empty(t, versions)
+	})
+}
+
+func TestResolveVersionCache(t *testing.T) {
+	t.Run("uses fresh cached answer", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+
+		writeResolvedVersionRecord(t, fs, "kubectl", "", "v1.29.0", time.Now())
+
+		tool := &Tool{
+			Name:    "kubectl",
+			Fs:      fs,
+			Project: &ProjectConfig{},
+			VersionFunc: func(ctx context.Context) (string, error) {
+				t.Fatal("VersionFunc should not be called with a fresh cache")
+
+				return "", nil
+			},
+		}
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "v1.29.0", version)
+	})
+
+	t.Run("stores looked up version", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+
+		calls := 0
+		tool := &Tool{
+			Name:    "kubectl",
+			Fs:      fs,
+			Project: &ProjectConfig{},
+			VersionFunc: func(ctx context.Context) (string, error) {
+				calls++
+
+				return kubectlTestVersion, nil
+			},
+		}
+
+		for range 3 {
+			version, err := tool.ResolveVersion(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, kubectlTestVersion, version)
+		}
+
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("honours TTL from project config", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+
+		writeResolvedVersionRecord(t, fs, "kubectl", "", "v1.29.0", time.Now().Add(-10*time.Minute))
+
+		ttl := 5 * time.Minute
+		tool := &Tool{
+			Name:    "kubectl",
+			Fs:      fs,
+			Project: &ProjectConfig{VersionTTL: &ttl},
+			VersionFunc: func(ctx context.Context) (string, error) {
+				return kubectlTestVersion, nil
+			},
+		}
+
+		version, err := tool.ResolveVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, kubectlTestVersion, version)
+	})
+
+	t.Run("refresh bypasses and updates cache", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		t.Setenv("HOME", testUser)
+
+		writeResolvedVersionRecord(t, fs, "kubectl", "", "v1.29.0", time.Now())
+
+		tool := &Tool{
+			Name:    "kubectl",
+			Fs:      fs,
+			Project: &ProjectConfig{},
+			VersionFunc: func(ctx context.Context) (string, error) {
+				return kubectlTestVersion, nil
+			},
+		}
+
+		version, err := tool.RefreshVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, kubectlTestVersion, version)
+
+		cached, ok := tool.cachedResolvedVersion("", time.Hour)
+		assert.True(t, ok)
+		assert.Equal(t, kubectlTestVersion, cached)
+	})
+}
@@ -1,6 +1,30 @@
 package util
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// byteUnits maps the lower-case unit suffixes accepted by ParseBytes to their size.
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kib": 1 << 10,
+	"kb":  1e3,
+	"m":   1 << 20,
+	"mib": 1 << 20,
+	"mb":  1e6,
+	"g":   1 << 30,
+	"gib": 1 << 30,
+	"gb":  1e9,
+	"t":   1 << 40,
+	"tib": 1 << 40,
+	"tb":  1e12,
+}
 
 // FormatBytes formats a byte count into a human-readable string with appropriate units.
 func FormatBytes(bytes int64) string {
@@ -23,3 +47,56 @@ func FormatBytes(bytes int64) string {
 
 	return fmt.Sprintf("%.1f %s", float64(bytes)/float64(div), units[exp])
 }
+
+// ParseBytes parses a human-readable size such as "500MiB", "1.5 GiB" or
+// "100MB" into a byte count. Units are case-insensitive; single-letter units
+// (K, M, G, T) are binary like KiB, MiB, GiB and TiB, KB, MB, GB and TB are
+// decimal. A number without unit is a count of bytes.
+func ParseBytes(s string) (int64, error) {
+	trimmed := strings.TrimSpace(s)
+	number := strings.TrimRightFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	unit := strings.ToLower(strings.TrimSpace(trimmed[len(number):]))
+
+	multiplier, ok := byteUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", s, unit)
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	bytes := value * multiplier
+	if bytes >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q: too large", s)
+	}
+
+	return int64(bytes), nil
+}
+
+// ParseDuration parses a duration like time.ParseDuration, but additionally
+// accepts whole days and weeks such as "30d" or "2w".
+func ParseDuration(s string) (time.Duration, error) {
+	trimmed := strings.TrimSpace(s)
+
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(trimmed, suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(trimmed)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	return d, nil
+}
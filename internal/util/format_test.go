@@ -2,6 +2,7 @@ package util_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/dennisklein/kdev/internal/util"
 )
@@ -54,3 +55,71 @@ func TestFormatBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestParseBytes(t *testing.T) {
+	//nolint:govet // fieldalignment: test readability over optimization
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{input: "512", want: 512},
+		{input: "512B", want: 512},
+		{input: "500MiB", want: 500 * 1024 * 1024},
+		{input: "500M", want: 500 * 1024 * 1024},
+		{input: "1.5 GiB", want: 1536 * 1024 * 1024},
+		{input: "100MB", want: 100_000_000},
+		{input: "2kib", want: 2048},
+		{input: "1TB", want: 1_000_000_000_000},
+		{input: "", wantErr: true},
+		{input: "MiB", wantErr: true},
+		{input: "10 parsecs", wantErr: true},
+		{input: "1.2.3GiB", wantErr: true},
+		{input: "99999999999TiB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := util.ParseBytes(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBytes(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseBytes(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	//nolint:govet // fieldalignment: test readability over optimization
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "30d", want: 30 * 24 * time.Hour},
+		{input: "2w", want: 14 * 24 * time.Hour},
+		{input: "36h", want: 36 * time.Hour},
+		{input: "90m", want: 90 * time.Minute},
+		{input: "0d", want: 0},
+		{input: "1.5d", wantErr: true},
+		{input: "-1d", wantErr: true},
+		{input: "d", wantErr: true},
+		{input: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := util.ParseDuration(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}